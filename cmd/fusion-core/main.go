@@ -5,14 +5,22 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/bazsalanszky/fusioncore/internal/config"
 	"github.com/bazsalanszky/fusioncore/internal/games"
 	"github.com/bazsalanszky/fusioncore/internal/gui"
+	"github.com/bazsalanszky/fusioncore/internal/importer"
 	"github.com/bazsalanszky/fusioncore/internal/instance"
+	"github.com/bazsalanszky/fusioncore/internal/launch"
 	"github.com/bazsalanszky/fusioncore/internal/mod"
+	"github.com/bazsalanszky/fusioncore/internal/modlist"
+	"github.com/bazsalanszky/fusioncore/internal/nexus"
 	fos "github.com/bazsalanszky/fusioncore/internal/os"
+	"github.com/bazsalanszky/fusioncore/internal/prefix"
+	"github.com/bazsalanszky/fusioncore/internal/steam"
 	"github.com/bazsalanszky/fusioncore/internal/vfs"
 )
 
@@ -37,10 +45,71 @@ func main() {
 	apiKeyFlag := loginCmd.String("apikey", "", "Your Nexus Mods API key")
 
 	activateCmd := flag.NewFlagSet("activate", flag.ExitOnError)
-	activateModName := activateCmd.String("mod", "", "The name of the mod to activate")
+	activateModName := activateCmd.String("mod", "", "The ID or name of the mod to activate")
 
 	deactivateCmd := flag.NewFlagSet("deactivate", flag.ExitOnError)
-	deactivateModName := deactivateCmd.String("mod", "", "The name of the mod to deactivate")
+	deactivateModName := deactivateCmd.String("mod", "", "The ID or name of the mod to deactivate")
+
+	renameCmd := flag.NewFlagSet("rename", flag.ExitOnError)
+	renameModName := renameCmd.String("mod", "", "The ID or name of the mod to rename")
+	renameNewName := renameCmd.String("name", "", "The new name of the mod")
+
+	bindShortcutCmd := flag.NewFlagSet("bind-shortcut", flag.ExitOnError)
+	bindShortcutAppID := bindShortcutCmd.String("appid", "", "The app ID of the non-Steam shortcut (see 'shortcuts')")
+	bindShortcutGame := bindShortcutCmd.String("game", "", "The game to bind the shortcut to (defaults to the current game)")
+
+	launchCmd := flag.NewFlagSet("launch", flag.ExitOnError)
+	launchGame := launchCmd.String("game", "", "The game to deploy and launch (defaults to the current game)")
+
+	createShortcutCmd := flag.NewFlagSet("create-shortcut", flag.ExitOnError)
+	createShortcutGame := createShortcutCmd.String("game", "", "The game to create the shortcut for (defaults to the current game)")
+	createShortcutLoader := createShortcutCmd.Bool("loader", false, "Launch the script extender loader instead of 'fusion-core launch'")
+	createShortcutProton := createShortcutCmd.String("proton", steam.DefaultCompatTool, "The Proton version used for the loader")
+	createShortcutUser := createShortcutCmd.String("user", "", "The Steam user ID to add the shortcut for (defaults to the most recent user)")
+
+	registryCmd := flag.NewFlagSet("registry", flag.ExitOnError)
+	registryGame := registryCmd.String("game", "", "The game whose prefix to inspect (defaults to the current game)")
+
+	ensureRegistryCmd := flag.NewFlagSet("ensure-registry", flag.ExitOnError)
+	ensureRegistryGame := ensureRegistryCmd.String("game", "", "The game to register in its prefix (defaults to the current game)")
+
+	refreshMetadataCmd := flag.NewFlagSet("refresh-metadata", flag.ExitOnError)
+	refreshMetadataMod := refreshMetadataCmd.String("mod", "", "The ID or name of the mod to refresh (defaults to all mods of the current game)")
+
+	verifyCmd := flag.NewFlagSet("verify", flag.ExitOnError)
+	verifyMod := verifyCmd.String("mod", "", "The ID or name of the mod to verify (defaults to all mods of the current game)")
+	verifyRecord := verifyCmd.Bool("record", false, "Accept the current files as installed and record a new manifest")
+
+	importExistingCmd := flag.NewFlagSet("import-existing", flag.ExitOnError)
+	importExistingApply := importExistingCmd.Bool("apply", false, "Move the files into new mods instead of only listing them")
+
+	importMO2Cmd := flag.NewFlagSet("import-mo2", flag.ExitOnError)
+	importMO2Instance := importMO2Cmd.String("instance", "", "The Mod Organizer 2 instance folder, the one holding ModOrganizer.ini")
+	importMO2Profile := importMO2Cmd.String("profile", "", "The profile to take the mod and plugin order from (defaults to the one selected in MO2)")
+	importMO2Apply := importMO2Cmd.Bool("apply", false, "Move the mods into Fusion Core instead of only listing them")
+
+	importVortexCmd := flag.NewFlagSet("import-vortex", flag.ExitOnError)
+	importVortexStaging := importVortexCmd.String("staging", "", "The Vortex staging folder (defaults to the one in the deployment)")
+	importVortexApply := importVortexCmd.Bool("apply", false, "Undo the Vortex deployment and move the mods into Fusion Core instead of only listing them (Nexus file IDs are looked up with the API key, without one only mod IDs are kept)")
+
+	exportCmd := flag.NewFlagSet("export", flag.ExitOnError)
+	exportOut := exportCmd.String("out", "modlist.json", "The file to write the modlist of the current game to")
+
+	importCmd := flag.NewFlagSet("import", flag.ExitOnError)
+	importFile := importCmd.String("file", "", "The modlist file to reproduce")
+	importApply := importCmd.Bool("apply", false, "Download missing mods and apply the mod and plugin order instead of only listing the changes")
+
+	collectionCmd := flag.NewFlagSet("install-collection", flag.ExitOnError)
+	collectionURL := collectionCmd.String("url", "", "The nxm link of the collection revision, nxm://<game>/collections/<slug>/revisions/<n>")
+	collectionOptional := collectionCmd.String("optional", "", "Comma separated names of the optional mods to install")
+	collectionAllOptional := collectionCmd.Bool("all-optional", false, "Install every optional mod")
+	collectionApply := collectionCmd.Bool("apply", false, "Install the collection instead of only listing its mods")
+
+	conflictsCmd := flag.NewFlagSet("conflicts", flag.ExitOnError)
+	conflictsMod := conflictsCmd.String("mod", "", "Only list the conflicts of this mod, given by ID or name")
+
+	gameInfoCmd := flag.NewFlagSet("game-info", flag.ExitOnError)
+	gameInfoGame := gameInfoCmd.String("game", "", "The game to inspect (defaults to the current game)")
 
 	registerHandler := flag.Bool("register-handler", false, "Register the application as a protocol handler for nxm URLs")
 
@@ -52,8 +121,11 @@ func main() {
 				fmt.Println("Please provide your API key with the --apikey flag.")
 				return
 			}
-			cfg := &config.Config{APIKey: *apiKeyFlag, CurrentGame: "fallout76"}
-			if err := config.SaveConfig(cfg); err != nil {
+			_, err := config.UpdateConfig(func(cfg *config.Config) error {
+				cfg.APIKey = *apiKeyFlag
+				return nil
+			})
+			if err != nil {
 				log.Fatalf("Failed to save config: %v", err)
 			}
 			fmt.Println("API key saved successfully.")
@@ -61,7 +133,7 @@ func main() {
 		case "activate":
 			activateCmd.Parse(os.Args[2:])
 			if *activateModName == "" {
-				fmt.Println("Please provide the name of the mod to activate with the --mod flag.")
+				fmt.Println("Please provide the ID or name of the mod to activate with the --mod flag.")
 				return
 			}
 			if err := vfs.Activate(*activateModName); err != nil {
@@ -72,7 +144,7 @@ func main() {
 		case "deactivate":
 			deactivateCmd.Parse(os.Args[2:])
 			if *deactivateModName == "" {
-				fmt.Println("Please provide the name of the mod to deactivate with the --mod flag.")
+				fmt.Println("Please provide the ID or name of the mod to deactivate with the --mod flag.")
 				return
 			}
 			if err := vfs.Deactivate(*deactivateModName); err != nil {
@@ -80,6 +152,106 @@ func main() {
 			}
 			fmt.Printf("Mod %s deactivated successfully.\n", *deactivateModName)
 			return
+		case "rename":
+			renameCmd.Parse(os.Args[2:])
+			if *renameModName == "" || *renameNewName == "" {
+				fmt.Println("Please provide the mod with the --mod flag and its new name with the --name flag.")
+				return
+			}
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				log.Fatalf("Failed to load config: %v", err)
+			}
+			m, err := mod.Rename(cfg.CurrentGame, *renameModName, *renameNewName)
+			if err != nil {
+				log.Fatalf("Failed to rename mod: %v", err)
+			}
+			fmt.Printf("Mod %s renamed to %s.\n", m.ID, m.Name)
+			return
+		case "refresh-metadata":
+			refreshMetadataCmd.Parse(os.Args[2:])
+			if err := refreshMetadata(*refreshMetadataMod); err != nil {
+				log.Fatalf("Failed to refresh metadata: %v", err)
+			}
+			return
+		case "verify":
+			verifyCmd.Parse(os.Args[2:])
+			if err := verifyMods(*verifyMod, *verifyRecord); err != nil {
+				log.Fatalf("Failed to verify mods: %v", err)
+			}
+			return
+		case "import-existing":
+			importExistingCmd.Parse(os.Args[2:])
+			if err := importExisting(*importExistingApply); err != nil {
+				log.Fatalf("Failed to import existing files: %v", err)
+			}
+			return
+		case "import-mo2":
+			importMO2Cmd.Parse(os.Args[2:])
+			if *importMO2Instance == "" {
+				fmt.Println("Please provide the Mod Organizer 2 instance folder with the --instance flag.")
+				return
+			}
+			if err := importMO2(*importMO2Instance, *importMO2Profile, *importMO2Apply); err != nil {
+				log.Fatalf("Failed to import from Mod Organizer 2: %v", err)
+			}
+			return
+		case "import-vortex":
+			importVortexCmd.Parse(os.Args[2:])
+			if err := importVortex(*importVortexStaging, *importVortexApply); err != nil {
+				log.Fatalf("Failed to import from Vortex: %v", err)
+			}
+			return
+		case "export":
+			exportCmd.Parse(os.Args[2:])
+			game, err := gameOrCurrent("")
+			if err != nil {
+				log.Fatalf("Failed to get game: %v", err)
+			}
+			list, err := modlist.Export(game)
+			if err != nil {
+				log.Fatalf("Failed to export mods: %v", err)
+			}
+			if err := modlist.Save(*exportOut, list); err != nil {
+				log.Fatalf("Failed to export mods: %v", err)
+			}
+			fmt.Printf("Exported %d mods of %s to %s\n", len(list.Mods), game.Name, *exportOut)
+			return
+		case "import":
+			importCmd.Parse(os.Args[2:])
+			if *importFile == "" {
+				fmt.Println("Please provide the modlist file with the --file flag.")
+				return
+			}
+			if err := importModlist(*importFile, *importApply); err != nil {
+				log.Fatalf("Failed to import modlist: %v", err)
+			}
+			return
+		case "install-collection":
+			collectionCmd.Parse(os.Args[2:])
+			if *collectionURL == "" {
+				fmt.Println("Please provide the nxm link of the collection with the --url flag.")
+				return
+			}
+			optional := make(map[string]bool)
+			for _, name := range strings.Split(*collectionOptional, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					optional[strings.ToLower(name)] = true
+				}
+			}
+			withOptional := func(cm *nexus.CollectionMod) bool {
+				return *collectionAllOptional || optional[strings.ToLower(cm.Name)]
+			}
+			if err := installCollection(*collectionURL, withOptional, *collectionApply); err != nil {
+				log.Fatalf("Failed to install collection: %v", err)
+			}
+			return
+		case "conflicts":
+			conflictsCmd.Parse(os.Args[2:])
+			if err := printConflicts(*conflictsMod); err != nil {
+				log.Fatalf("Failed to find conflicts: %v", err)
+			}
+			return
 		case "list":
 			cfg, err := config.LoadConfig()
 			if err != nil {
@@ -103,13 +275,142 @@ func main() {
 				if m.Active {
 					status = "active"
 				}
-				fmt.Printf("- %s (%s)\n", m.Name, status)
+				fmt.Printf("- %s [%s] (%s)\n", m.Name, m.ID, status)
 			}
 			return
 		case "games":
 			fmt.Println("Supported games:")
 			for _, game := range games.GetSupportedGames() {
 				fmt.Printf("- %s (ID: %s)\n", game.Name, game.ID)
+				inst, err := game.FindInstallation("")
+				if err != nil || inst.Manifest == nil {
+					continue
+				}
+				fmt.Printf("    %s (build %s, %s)\n", inst.GameDir, inst.Manifest.BuildID, inst.Manifest.StateDescription())
+			}
+			if dir, err := games.GetDefinitionsDir(); err == nil {
+				fmt.Printf("Add or override games with .toml or .json files in %s\n", dir)
+			}
+			return
+		case "steam-roots":
+			fmt.Println("Steam root candidates:")
+			for _, c := range steam.FindRootCandidates() {
+				marker := " "
+				if c.Selected {
+					marker = "*"
+				}
+				fmt.Printf("%s %s [%s]: %s\n", marker, c.Path, c.Source, c.Reason)
+			}
+			libraries, err := steam.FindAllLibraries()
+			if err != nil {
+				return
+			}
+			fmt.Println("Steam libraries:")
+			for _, lib := range libraries {
+				fmt.Printf("- %s (%d apps)\n", lib.Path, len(lib.Apps))
+			}
+			return
+		case "shortcuts":
+			shortcuts, err := steam.FindAllShortcuts()
+			if err != nil {
+				log.Fatalf("Failed to read non-Steam shortcuts: %v", err)
+			}
+			if len(shortcuts) == 0 {
+				fmt.Println("No non-Steam shortcuts found.")
+				return
+			}
+			fmt.Println("Non-Steam shortcuts:")
+			for _, s := range shortcuts {
+				fmt.Printf("- %s (app ID: %s)\n    %s\n", s.AppName, s.CompatdataID(), s.Exe)
+				for _, game := range games.GetSupportedGames() {
+					if game.MatchesShortcut(s) {
+						fmt.Printf("    Matches %s (ID: %s)\n", game.Name, game.ID)
+					}
+				}
+			}
+			return
+		case "bind-shortcut":
+			bindShortcutCmd.Parse(os.Args[2:])
+			if *bindShortcutAppID == "" {
+				fmt.Println("Please provide the app ID of the shortcut with the --appid flag.")
+				return
+			}
+			if err := bindShortcut(*bindShortcutAppID, *bindShortcutGame); err != nil {
+				log.Fatalf("Failed to bind shortcut: %v", err)
+			}
+			return
+		case "launch":
+			launchCmd.Parse(os.Args[2:])
+			game, err := gameOrCurrent(*launchGame)
+			if err != nil {
+				log.Fatalf("Failed to get game: %v", err)
+			}
+			if err := launch.Run(game); err != nil {
+				log.Fatalf("Failed to launch %s: %v", game.Name, err)
+			}
+			return
+		case "create-shortcut":
+			createShortcutCmd.Parse(os.Args[2:])
+			game, err := gameOrCurrent(*createShortcutGame)
+			if err != nil {
+				log.Fatalf("Failed to get game: %v", err)
+			}
+			shortcut, err := launch.CreateShortcut(game, launch.ShortcutOptions{
+				UseLoader:  *createShortcutLoader,
+				CompatTool: *createShortcutProton,
+				UserID:     *createShortcutUser,
+			})
+			if err != nil {
+				log.Fatalf("Failed to create shortcut: %v", err)
+			}
+			fmt.Printf("Added %s to Steam (app ID: %s). Start Steam to see it in your library.\n", shortcut.AppName, shortcut.CompatdataID())
+			return
+		case "registry":
+			registryCmd.Parse(os.Args[2:])
+			game, err := gameOrCurrent(*registryGame)
+			if err != nil {
+				log.Fatalf("Failed to get game: %v", err)
+			}
+			if err := printRegistry(game); err != nil {
+				log.Fatalf("Failed to read the registry: %v", err)
+			}
+			return
+		case "game-info":
+			gameInfoCmd.Parse(os.Args[2:])
+			game, err := gameOrCurrent(*gameInfoGame)
+			if err != nil {
+				log.Fatalf("Failed to get game: %v", err)
+			}
+			if err := printGameInfo(game); err != nil {
+				log.Fatalf("Failed to find %s: %v", game.Name, err)
+			}
+			return
+		case "ensure-registry":
+			ensureRegistryCmd.Parse(os.Args[2:])
+			game, err := gameOrCurrent(*ensureRegistryGame)
+			if err != nil {
+				log.Fatalf("Failed to get game: %v", err)
+			}
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				log.Fatalf("Failed to load config: %v", err)
+			}
+			prefixPath, err := game.FindCompatdataWithCustomPath(cfg.CompatdataPaths[game.ID])
+			if err != nil {
+				log.Fatalf("Failed to find the prefix of %s: %v", game.Name, err)
+			}
+			gameDir, err := game.FindGameDirWithCustomPath(cfg.GamePaths[game.ID])
+			if err != nil {
+				log.Fatalf("Failed to find %s: %v", game.Name, err)
+			}
+			changed, err := prefix.EnsureBethesdaKeys(prefixPath, game.RegistryName, gameDir)
+			if err != nil {
+				log.Fatalf("Failed to update the registry: %v", err)
+			}
+			if changed {
+				fmt.Printf("Registered %s in %s\n", game.Name, prefix.SystemRegPath(prefixPath))
+			} else {
+				fmt.Printf("%s is already registered in its prefix.\n", game.Name)
 			}
 			return
 		case "switch-game":
@@ -123,12 +424,11 @@ func main() {
 				fmt.Printf("Invalid game ID: %s\n", gameID)
 				return
 			}
-			cfg, err := config.LoadConfig()
+			_, err = config.UpdateConfig(func(cfg *config.Config) error {
+				cfg.CurrentGame = gameID
+				return nil
+			})
 			if err != nil {
-				log.Fatalf("Failed to load config: %v", err)
-			}
-			cfg.CurrentGame = gameID
-			if err := config.SaveConfig(cfg); err != nil {
 				log.Fatalf("Failed to save config: %v", err)
 			}
 			game, _ := games.GetGameByID(gameID)
@@ -146,3 +446,572 @@ func main() {
 		return
 	}
 }
+
+// gameOrCurrent returns the game with the given ID, or the current game if id is empty.
+func gameOrCurrent(id string) (*games.Game, error) {
+	if id == "" {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return nil, err
+		}
+		id = cfg.CurrentGame
+	}
+	return games.GetGameByID(id)
+}
+
+// printRegistry prints the registry entries of a game's prefix that matter
+// for modding: the Bethesda install location, DLL overrides and runtimes.
+func printRegistry(game *games.Game) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	prefixPath, err := game.FindCompatdataWithCustomPath(cfg.CompatdataPaths[game.ID])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Prefix: %s\n", prefix.Root(prefixPath))
+	if path, ok := prefix.BethesdaInstalledPath(prefixPath, game.RegistryName); ok {
+		fmt.Printf("Installed Path: %s\n", path)
+	} else {
+		fmt.Println("Installed Path: not set (run 'ensure-registry' to add it)")
+	}
+
+	overrides, err := prefix.DllOverrides(prefixPath)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Println("DLL overrides:")
+	for _, name := range names {
+		fmt.Printf("- %s: %s\n", name, overrides[name])
+	}
+
+	programs, err := prefix.InstalledPrograms(prefixPath)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Installed programs:")
+	for _, p := range programs {
+		if p.Version != "" {
+			fmt.Printf("- %s (%s)\n", p.Name, p.Version)
+		} else {
+			fmt.Printf("- %s\n", p.Name)
+		}
+	}
+	return nil
+}
+
+// refreshMetadata fetches the Nexus Mods metadata of one or all mods of the
+// current game. Mods that fail are reported and skipped.
+func refreshMetadata(idOrName string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	game, err := games.GetGameByID(cfg.CurrentGame)
+	if err != nil {
+		return err
+	}
+	mods, err := mod.LoadMods(game.ID)
+	if err != nil {
+		return err
+	}
+
+	targets := mods
+	if idOrName != "" {
+		m, err := mod.Find(mods, idOrName)
+		if err != nil {
+			return err
+		}
+		targets = []*mod.Mod{m}
+	}
+
+	apiKey := os.Getenv("NEXUS_API_KEY")
+	if apiKey == "" {
+		apiKey = cfg.APIKey
+	}
+
+	// The requests are slow, so the mod list is only locked to store the results
+	fetched := make(map[string]*mod.Metadata)
+	for _, m := range targets {
+		if !m.IsFromNexus() {
+			fmt.Printf("Skipping %s, it wasn't downloaded from Nexus Mods\n", m.Name)
+			continue
+		}
+		metadata, err := nexus.FetchMetadata(game.NexusName, m.ModID, m.FileID, apiKey)
+		if err != nil {
+			fmt.Printf("Failed to refresh %s: %v\n", m.Name, err)
+			continue
+		}
+		fetched[m.ID] = metadata
+		fmt.Printf("Refreshed %s (version %s by %s)\n", m.Name, metadata.Version, metadata.Author)
+		if metadata.Category == "" && metadata.CategoryID != 0 {
+			fmt.Printf("    The name of category %d couldn't be fetched\n", metadata.CategoryID)
+		}
+	}
+
+	if len(fetched) == 0 {
+		return nil
+	}
+	_, err = mod.UpdateMods(game.ID, func(mods []*mod.Mod) ([]*mod.Mod, error) {
+		for _, m := range mods {
+			if metadata, ok := fetched[m.ID]; ok {
+				m.Metadata = metadata
+			}
+		}
+		return mods, nil
+	})
+	return err
+}
+
+// verifyMods reports the files of one or all mods of the current game that
+// were modified, deleted or added since the mod was installed. With record,
+// the current files are accepted and saved as the new manifest instead.
+func verifyMods(idOrName string, record bool) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	mods, err := mod.LoadMods(cfg.CurrentGame)
+	if err != nil {
+		return err
+	}
+	if idOrName != "" {
+		m, err := mod.Find(mods, idOrName)
+		if err != nil {
+			return err
+		}
+		mods = []*mod.Mod{m}
+	}
+
+	for _, m := range mods {
+		if record {
+			if err := mod.RecordManifest(cfg.CurrentGame, m); err != nil {
+				return err
+			}
+			fmt.Printf("Recorded the files of %s\n", m.Name)
+			continue
+		}
+
+		manifest, err := mod.LoadManifest(cfg.CurrentGame, m)
+		if err != nil {
+			if os.IsNotExist(err) {
+				fmt.Printf("%s: no manifest, run 'verify --record --mod %s' to create one\n", m.Name, m.ID)
+				continue
+			}
+			return err
+		}
+		drift, err := manifest.Verify(m.Path)
+		if err != nil {
+			return err
+		}
+		if drift.IsClean() {
+			fmt.Printf("%s: unchanged\n", m.Name)
+			continue
+		}
+		drift.Print(m.Name)
+	}
+	return nil
+}
+
+// importExisting lists the files in the current game's data directory that
+// Fusion Core doesn't manage. With apply, they are moved into new mods.
+func importExisting(apply bool) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	game, err := games.GetGameByID(cfg.CurrentGame)
+	if err != nil {
+		return err
+	}
+	dataDir, err := game.FindDataDirWithCustomPath(cfg.GamePaths[game.ID])
+	if err != nil {
+		return err
+	}
+
+	found, kept, err := vfs.ScanUnmanaged(game, dataDir)
+	if err != nil {
+		return err
+	}
+	if len(kept) > 0 {
+		fmt.Printf("%s only gets archives deployed, these files stay where they are:\n", game.Name)
+		for _, file := range kept {
+			fmt.Printf("    %s\n", file)
+		}
+	}
+	if len(found) == 0 {
+		fmt.Printf("No unmanaged archives or files to adopt in %s.\n", dataDir)
+		return nil
+	}
+	for _, group := range found {
+		fmt.Printf("%s:\n", group.Name)
+		for _, file := range group.Files {
+			fmt.Printf("    %s\n", file)
+		}
+	}
+	if !apply {
+		fmt.Println("Run 'import-existing --apply' to move these files into new mods.")
+		return nil
+	}
+
+	adopted, err := vfs.AdoptUnmanaged(game, dataDir, found)
+	if err != nil {
+		// The groups adopted before the failure are out of Data already
+		if len(adopted) > 0 {
+			if err := vfs.DeployImported(adopted); err != nil {
+				fmt.Printf("Failed to deploy the adopted files: %v\n", err)
+			}
+		}
+		return err
+	}
+	return vfs.DeployImported(adopted)
+}
+
+// importMO2 lists the mods of a Mod Organizer 2 instance. With apply, they
+// are moved into the current game's mods and deployed.
+func importMO2(instanceDir, profile string, apply bool) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	game, err := games.GetGameByID(cfg.CurrentGame)
+	if err != nil {
+		return err
+	}
+
+	instance, err := importer.ReadMO2(instanceDir, game, profile)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Mods of profile %s, the lowest priority first:\n", instance.Profile)
+	for _, m := range instance.Mods {
+		status := "inactive"
+		if m.Active {
+			status = "active"
+		}
+		if m.ModID != "" {
+			fmt.Printf("- %s (%s, Nexus mod %s)\n", m.Name, status, m.ModID)
+		} else {
+			fmt.Printf("- %s (%s)\n", m.Name, status)
+		}
+	}
+	fmt.Printf("%d plugins in the load order\n", len(instance.Plugins))
+	if !apply {
+		fmt.Printf("Run 'import-mo2 --apply' to move these mods into %s. MO2 can't use them afterwards.\n", game.Name)
+		return nil
+	}
+
+	imported, err := instance.Import(game)
+	if err != nil {
+		return err
+	}
+	return vfs.DeployImported(imported)
+}
+
+// importVortex lists the mods Vortex deployed to the current game. With
+// apply, the Vortex links are removed and the mods are moved into the
+// game's mods and deployed. The Nexus file IDs are looked up if there is
+// an API key, otherwise only the mod IDs are recovered.
+func importVortex(stagingDir string, apply bool) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	game, err := games.GetGameByID(cfg.CurrentGame)
+	if err != nil {
+		return err
+	}
+	dataDir, err := game.FindDataDirWithCustomPath(cfg.GamePaths[game.ID])
+	if err != nil {
+		return err
+	}
+
+	deployment, err := importer.ReadVortex(game, dataDir, stagingDir)
+	if err != nil {
+		return err
+	}
+	apiKey := os.Getenv("NEXUS_API_KEY")
+	if apiKey == "" {
+		apiKey = cfg.APIKey
+	}
+	if apiKey != "" {
+		deployment.ResolveFileIDs(game, apiKey)
+	} else {
+		fmt.Println("No Nexus Mods API key, only the mod IDs of the mods are recovered, not their file IDs.")
+	}
+	fmt.Printf("Mods in %s, the lowest priority first:\n", deployment.StagingDir)
+	for _, m := range deployment.Mods {
+		status := "inactive"
+		if m.Active {
+			status = "active"
+		}
+		switch {
+		case m.FileID != "":
+			fmt.Printf("- %s (%s, Nexus mod %s, file %s)\n", m.Name, status, m.ModID, m.FileID)
+		case m.ModID != "":
+			fmt.Printf("- %s (%s, Nexus mod %s)\n", m.Name, status, m.ModID)
+		default:
+			fmt.Printf("- %s (%s)\n", m.Name, status)
+		}
+	}
+	if !apply {
+		fmt.Printf("Run 'import-vortex --apply' to move these mods into %s. Vortex can't use them afterwards.\n", game.Name)
+		return nil
+	}
+
+	imported, err := deployment.Import(game)
+	if err != nil {
+		return err
+	}
+	return vfs.DeployImported(imported)
+}
+
+// importModlist lists what it takes to reproduce a modlist for the current
+// game. With apply, missing mods are downloaded and the order is applied.
+func importModlist(path string, apply bool) error {
+	list, err := modlist.Load(path)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	game, err := games.GetGameByID(cfg.CurrentGame)
+	if err != nil {
+		return err
+	}
+	if list.Game != game.ID {
+		return fmt.Errorf("the modlist is for %s, switch to it with 'switch-game %s' first", list.Game, list.Game)
+	}
+	installed, err := mod.LoadMods(game.ID)
+	if err != nil {
+		return err
+	}
+	matched := list.Match(installed)
+	for i, e := range list.Mods {
+		switch {
+		case matched[i] != nil:
+			fmt.Printf("- %s: installed\n", e.Name)
+		case e.CanDownload():
+			fmt.Printf("- %s: download mod %s, file %s\n", e.Name, e.ModID, e.FileID)
+		case e.IsFromNexus():
+			fmt.Printf("- %s: missing, no Nexus file ID to download mod %s\n", e.Name, e.ModID)
+		default:
+			fmt.Printf("- %s: missing, not from Nexus Mods\n", e.Name)
+		}
+	}
+	if !apply {
+		list.CheckGameVersion(game)
+		if _, err := list.CheckInstalled(matched); err != nil {
+			return err
+		}
+		fmt.Println("Run 'import --apply' to download the missing mods and apply the mod and plugin order.")
+		return nil
+	}
+
+	apiKey := os.Getenv("NEXUS_API_KEY")
+	if apiKey == "" {
+		apiKey = cfg.APIKey
+	}
+	return list.Apply(game, apiKey)
+}
+
+// installCollection lists the mods of a collection revision for the current
+// game. With apply, they are installed along with its plugin order.
+func installCollection(nxmURL string, withOptional func(*nexus.CollectionMod) bool, apply bool) error {
+	info, err := nexus.ParseNxmURL(nxmURL)
+	if err != nil {
+		return err
+	}
+	if !info.IsCollection() {
+		return fmt.Errorf("%s is not a collection link", nxmURL)
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	game, err := games.GetGameByID(cfg.CurrentGame)
+	if err != nil {
+		return err
+	}
+	apiKey := os.Getenv("NEXUS_API_KEY")
+	if apiKey == "" {
+		apiKey = cfg.APIKey
+	}
+
+	collection, err := nexus.GetCollection(info, apiKey)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s, revision %d:\n", collection.Name, collection.Revision)
+	for i := range collection.Mods {
+		cm := &collection.Mods[i]
+		switch {
+		case cm.Source != "nexus":
+			fmt.Printf("- %s: install by hand from %s\n", cm.Name, cm.URL)
+		case cm.Optional && !withOptional(cm):
+			fmt.Printf("- %s %s: optional, skipped\n", cm.Name, cm.Version)
+		default:
+			fmt.Printf("- %s %s\n", cm.Name, cm.Version)
+		}
+	}
+	if !apply {
+		fmt.Println("Run 'install-collection --apply' to install the collection.")
+		return nil
+	}
+	return modlist.InstallCollection(collection, game, apiKey, withOptional)
+}
+
+// printConflicts lists the files several active mods of the current game
+// provide and which mod wins each, optionally only those of one mod.
+func printConflicts(idOrName string) error {
+	game, err := gameOrCurrent("")
+	if err != nil {
+		return err
+	}
+	mods, err := mod.LoadMods(game.ID)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	dataDir, err := game.FindDataDirWithCustomPath(cfg.GamePaths[game.ID])
+	if err != nil {
+		return err
+	}
+	conflicts, err := vfs.FindConflicts(mods, game, dataDir)
+	if err != nil {
+		return err
+	}
+	if idOrName != "" {
+		m, err := mod.Find(mods, idOrName)
+		if err != nil {
+			return err
+		}
+		var filtered []vfs.Conflict
+		for _, c := range conflicts {
+			if c.Involves(m) {
+				filtered = append(filtered, c)
+			}
+		}
+		conflicts = filtered
+	}
+
+	if len(conflicts) == 0 {
+		fmt.Println("No conflicts between the active mods or with files in Data.")
+		return nil
+	}
+	for _, c := range conflicts {
+		fmt.Printf("%s: %s wins over %s\n", filepath.ToSlash(c.Path), c.Winner.Name, strings.Join(c.LoserNames(), ", "))
+	}
+	fmt.Printf("%d conflicting files. Move a mod later in the mod order to make it win, files in Data are backed up while mods replace them.\n", len(conflicts))
+	return nil
+}
+
+// printGameInfo prints where a game is installed and which build it is, so
+// version-specific mods can be checked before installing them.
+func printGameInfo(game *games.Game) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	inst, err := game.FindInstallation(cfg.GamePaths[game.ID])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Game: %s (ID: %s)\n", game.Name, game.ID)
+	fmt.Printf("Directory: %s\n", inst.GameDir)
+	switch {
+	case inst.Launcher != "":
+		fmt.Printf("Source: %s\n", inst.Launcher)
+	case inst.Shortcut != nil:
+		fmt.Printf("Source: non-Steam shortcut %s\n", inst.Shortcut.AppName)
+	case inst.Library != "":
+		fmt.Printf("Source: Steam library %s\n", inst.Library)
+	default:
+		fmt.Println("Source: custom path")
+	}
+
+	exe := game.VersionExecutablePath(inst.GameDir)
+	fmt.Printf("Executable: %s\n", exe)
+	if version, err := game.ExecutableVersion(inst.GameDir); err != nil {
+		fmt.Printf("Version: unknown (%v)\n", err)
+	} else {
+		fmt.Printf("Version: %s\n", version)
+		if edition := game.Edition(version); edition != "" {
+			fmt.Printf("Edition: %s\n", edition)
+		}
+	}
+
+	if inst.Manifest != nil {
+		fmt.Printf("Steam build: %s (%s)\n", inst.Manifest.BuildID, inst.Manifest.StateDescription())
+	}
+	return nil
+}
+
+// bindShortcut points a game's compatdata path (and game path, if unset) at a
+// non-Steam shortcut, so INI and plugins.txt writes target its prefix.
+func bindShortcut(appID, gameID string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if gameID == "" {
+		gameID = cfg.CurrentGame
+	}
+	game, err := games.GetGameByID(gameID)
+	if err != nil {
+		return err
+	}
+
+	shortcuts, err := steam.FindAllShortcuts()
+	if err != nil {
+		return err
+	}
+	var shortcut *steam.Shortcut
+	for i := range shortcuts {
+		if shortcuts[i].CompatdataID() == appID {
+			shortcut = &shortcuts[i]
+			break
+		}
+	}
+	if shortcut == nil {
+		return fmt.Errorf("no non-Steam shortcut with app ID %s", appID)
+	}
+
+	libraries, err := steam.FindAllLibraries()
+	if err != nil {
+		return err
+	}
+	compatdataPath, ok := steam.FindCompatdata(libraries, appID)
+	if !ok {
+		return fmt.Errorf("%s has no Proton prefix yet. Launch it from Steam once with Proton enabled", shortcut.AppName)
+	}
+
+	_, err = config.UpdateConfig(func(cfg *config.Config) error {
+		cfg.CompatdataPaths[game.ID] = compatdataPath
+		if _, ok := cfg.GamePaths[game.ID]; !ok && shortcut.StartDir != "" {
+			if _, err := os.Stat(filepath.Join(shortcut.StartDir, game.DataSubDir)); err == nil {
+				cfg.GamePaths[game.ID] = shortcut.StartDir
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Bound %s to %s (%s)\n", game.Name, shortcut.AppName, compatdataPath)
+	return nil
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"strings"
+)
+
+// ArchiveList is the ordered list of archives stored in an INI key like
+// sResourceArchive2List. Names are unique and compared case-insensitively,
+// like the game does.
+type ArchiveList []string
+
+// ParseArchiveList parses a comma separated INI value, dropping empty and
+// duplicate entries.
+func ParseArchiveList(value string) ArchiveList {
+	var list ArchiveList
+	for _, name := range strings.Split(value, ",") {
+		list = list.Add(strings.TrimSpace(name))
+	}
+	return list
+}
+
+// String formats the list the way the games write it.
+func (l ArchiveList) String() string {
+	return strings.Join(l, ", ")
+}
+
+// Index returns the position of an archive in the list, or -1.
+func (l ArchiveList) Index(name string) int {
+	for i, existing := range l {
+		if strings.EqualFold(existing, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Contains reports whether the list has an archive.
+func (l ArchiveList) Contains(name string) bool {
+	return l.Index(name) >= 0
+}
+
+// Add appends an archive if it is not in the list yet.
+func (l ArchiveList) Add(name string) ArchiveList {
+	if name == "" || l.Contains(name) {
+		return l
+	}
+	return append(l, name)
+}
+
+// Remove returns the list without an archive.
+func (l ArchiveList) Remove(name string) ArchiveList {
+	i := l.Index(name)
+	if i < 0 {
+		return l
+	}
+	removed := make(ArchiveList, 0, len(l)-1)
+	removed = append(removed, l[:i]...)
+	return append(removed, l[i+1:]...)
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bazsalanszky/fusioncore/internal/games"
+	"gopkg.in/ini.v1"
+)
+
+func TestArchiveList(t *testing.T) {
+	list := ParseArchiveList(" Mod.ba2,, mod.BA2 ,Other - Main.ba2, ")
+	if !reflect.DeepEqual(list, ArchiveList{"Mod.ba2", "Other - Main.ba2"}) {
+		t.Fatalf("unexpected list %q", list)
+	}
+
+	// Substrings of other entries are separate archives
+	list = list.Add("Main.ba2")
+	list = list.Remove("Other - Main.ba2")
+	if list.String() != "Mod.ba2, Main.ba2" {
+		t.Errorf("unexpected list %q", list.String())
+	}
+	if list.Remove("missing.ba2").String() != "Mod.ba2, Main.ba2" {
+		t.Errorf("removing a missing archive changed the list")
+	}
+}
+
+func TestGameArchiveList(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test-prefix-archives")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := os.MkdirAll(filepath.Join(tmpDir, "pfx", "drive_c", "users", "steamuser"), 0755); err != nil {
+		t.Fatalf("Failed to create prefix: %v", err)
+	}
+
+	game, err := games.GetGameByID("falloutnv")
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+
+	// A key missing from the INI starts with the vanilla archives
+	if err := AddArchiveToGameIni(tmpDir, game, "MyMod.bsa"); err != nil {
+		t.Fatalf("Failed to add archive: %v", err)
+	}
+	list, err := ReadGameArchiveList(tmpDir, game)
+	if err != nil {
+		t.Fatalf("Failed to read archive list: %v", err)
+	}
+	expected := append(ArchiveList(game.VanillaArchives), "MyMod.bsa")
+	if !reflect.DeepEqual(list, expected) {
+		t.Errorf("expected %q, got %q", expected, list)
+	}
+
+	// Vanilla archives are never removed
+	if err := RemoveArchiveFromGameIni(tmpDir, game, "Fallout - Textures.bsa"); err != nil {
+		t.Fatalf("Failed to remove archive: %v", err)
+	}
+	if err := RemoveArchiveFromGameIni(tmpDir, game, "MyMod.bsa"); err != nil {
+		t.Fatalf("Failed to remove archive: %v", err)
+	}
+	list, _ = ReadGameArchiveList(tmpDir, game)
+	if !reflect.DeepEqual(list, ArchiveList(game.VanillaArchives)) {
+		t.Errorf("expected only the vanilla archives, got %q", list)
+	}
+
+	// Setting the list keeps the vanilla archives first and other keys untouched
+	iniPath, err := GetCustomIniPath(tmpDir, game)
+	if err != nil {
+		t.Fatalf("Failed to get ini path: %v", err)
+	}
+	cfg, err := ini.Load(iniPath)
+	if err != nil {
+		t.Fatalf("Failed to load ini: %v", err)
+	}
+	cfg.Section("Archive").Key("bInvalidateOlderFiles").SetValue("1")
+	if err := cfg.SaveTo(iniPath); err != nil {
+		t.Fatalf("Failed to save ini: %v", err)
+	}
+
+	if err := SetGameArchiveList(tmpDir, game, []string{"B.bsa", "A.bsa", "Fallout - Misc.bsa"}); err != nil {
+		t.Fatalf("Failed to set archive list: %v", err)
+	}
+	list, _ = ReadGameArchiveList(tmpDir, game)
+	expected = append(ArchiveList(game.VanillaArchives), "B.bsa", "A.bsa")
+	if !reflect.DeepEqual(list, expected) {
+		t.Errorf("expected %q, got %q", expected, list)
+	}
+	cfg, _ = ini.Load(iniPath)
+	if cfg.Section("Archive").Key("bInvalidateOlderFiles").String() != "1" {
+		t.Errorf("expected other keys to be preserved")
+	}
+}
+
+func TestNumberedArchiveKeys(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test-prefix-starfield")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := os.MkdirAll(filepath.Join(tmpDir, "pfx", "drive_c", "users", "steamuser"), 0755); err != nil {
+		t.Fatalf("Failed to create prefix: %v", err)
+	}
+
+	game, err := games.GetGameByID("starfield")
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+
+	for _, archive := range []string{"A - Main.ba2", "B - Main.ba2", "C - Main.ba2"} {
+		if err := AddArchiveToGameIni(tmpDir, game, archive); err != nil {
+			t.Fatalf("Failed to add %s: %v", archive, err)
+		}
+	}
+	if err := RemoveArchiveFromGameIni(tmpDir, game, "B - Main.ba2"); err != nil {
+		t.Fatalf("Failed to remove archive: %v", err)
+	}
+	if err := EnsureRequiredIni(tmpDir, game); err != nil {
+		t.Fatalf("Failed to write required settings: %v", err)
+	}
+
+	iniPath, err := GetCustomIniPath(tmpDir, game)
+	if err != nil {
+		t.Fatalf("Failed to get ini path: %v", err)
+	}
+	if filepath.Base(iniPath) != "StarfieldCustom.ini" {
+		t.Errorf("unexpected ini path %s", iniPath)
+	}
+	cfg, err := ini.Load(iniPath)
+	if err != nil {
+		t.Fatalf("Failed to load ini: %v", err)
+	}
+	// Starfield reads the sTestFile keys from [General]
+	general := cfg.Section("General")
+	if general.Key("sTestFile1").String() != "A - Main.ba2" || general.Key("sTestFile2").String() != "C - Main.ba2" {
+		t.Errorf("unexpected archive keys %v", general.KeysHash())
+	}
+	if general.HasKey("sTestFile3") {
+		t.Errorf("expected the third key to be removed")
+	}
+	section := cfg.Section("Archive")
+	if section.HasKey("sTestFile1") {
+		t.Errorf("expected no archive keys in [Archive], got %v", section.KeysHash())
+	}
+	if section.Key("bInvalidateOlderFiles").String() != "1" || !section.HasKey("sResourceDataDirsFinal") {
+		t.Errorf("expected the loose file settings, got %v", section.KeysHash())
+	}
+
+	// There are only ten sTestFile keys
+	var archives []string
+	for i := 0; i < 11; i++ {
+		archives = append(archives, fmt.Sprintf("Mod%d.ba2", i))
+	}
+	if err := SetGameArchiveList(tmpDir, game, archives); err == nil {
+		t.Errorf("expected an error for more archives than keys")
+	}
+}
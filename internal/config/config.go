@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/bazsalanszky/fusioncore/internal/store"
 )
 
 // Config holds the application's configuration.
 type Config struct {
+	Version         int               `json:"version"` // Schema version, see configMigrations
 	APIKey          string            `json:"api_key"`
 	CurrentGame     string            `json:"current_game"`
 	GamePaths       map[string]string `json:"game_paths,omitempty"`       // Maps game ID to custom game directory path
@@ -24,6 +27,12 @@ func GetConfigPath() (string, error) {
 	return filepath.Join(configDir, "fusion-core", "config.json"), nil
 }
 
+// configMigrations upgrade config files written by older releases, see store.Migrate.
+var configMigrations = []store.Migration{
+	// Version 0 had no version field, the layout is unchanged
+	func(data []byte) ([]byte, error) { return data, nil },
+}
+
 // LoadConfig loads the application's configuration from the config file.
 func LoadConfig() (*Config, error) {
 	configPath, err := GetConfigPath()
@@ -31,10 +40,11 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
-	file, err := os.Open(configPath)
+	data, err := os.ReadFile(configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return &Config{
+				Version:         len(configMigrations),
 				CurrentGame:     "fallout76",
 				GamePaths:       make(map[string]string),
 				CompatdataPaths: make(map[string]string),
@@ -42,11 +52,14 @@ func LoadConfig() (*Config, error) {
 		}
 		return nil, fmt.Errorf("failed to open config file: %w", err)
 	}
-	defer file.Close()
 
+	data, err = store.Migrate(data, configMigrations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", configPath, err)
+	}
 	var config Config
-	if err := json.NewDecoder(file).Decode(&config); err != nil {
-		return nil, fmt.Errorf("failed to decode config file: %w", err)
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to decode config file, backups are kept in %s: %w", store.BackupDir(configPath), err)
 	}
 
 	// Set default game if not set
@@ -67,29 +80,58 @@ func LoadConfig() (*Config, error) {
 	return &config, nil
 }
 
-// SaveConfig saves the application's configuration to the config file.
+// SaveConfig saves the application's configuration to the config file,
+// replacing the stored one. Use UpdateConfig to change a config that may
+// have been changed by another process since it was loaded.
 func SaveConfig(config *Config) error {
 	configPath, err := GetConfigPath()
 	if err != nil {
 		return err
 	}
 
-	// Create the directory if it doesn't exist.
-	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
+	unlock, err := store.Lock(configPath)
+	if err != nil {
+		return err
 	}
+	defer unlock()
+
+	return writeConfig(configPath, config)
+}
 
-	file, err := os.Create(configPath)
+// UpdateConfig loads the configuration, changes it with update and saves it
+// while holding the lock of the config file. It returns the saved config.
+func UpdateConfig(update func(config *Config) error) (*Config, error) {
+	configPath, err := GetConfigPath()
 	if err != nil {
-		return fmt.Errorf("failed to create config file: %w", err)
+		return nil, err
 	}
-	defer file.Close()
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(config); err != nil {
-		return fmt.Errorf("failed to encode config file: %w", err)
+	unlock, err := store.Lock(configPath)
+	if err != nil {
+		return nil, err
 	}
+	defer unlock()
 
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if err := update(config); err != nil {
+		return nil, err
+	}
+	if err := writeConfig(configPath, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// writeConfig saves the config file. The previous file is kept as a backup
+// and the file is replaced atomically. It is only readable by the user, as
+// it holds the API key.
+func writeConfig(configPath string, config *Config) error {
+	config.Version = len(configMigrations)
+	if err := store.SaveJSON(configPath, config, 0600); err != nil {
+		return fmt.Errorf("failed to save config file: %w", err)
+	}
 	return nil
 }
@@ -7,17 +7,42 @@ import (
 	"strings"
 
 	"github.com/bazsalanszky/fusioncore/internal/games"
+	"github.com/bazsalanszky/fusioncore/internal/prefix"
 	"gopkg.in/ini.v1"
 )
 
-// GetCustomIniPath returns the path to the custom ini file for any game.
-func GetCustomIniPath(prefixPath, configFile string) string {
-	return filepath.Join(prefixPath, "pfx", "drive_c", "users", "steamuser", "Documents", "My Games", "Fallout 76", configFile)
+// GetMyGamesDir returns the game's folder under Documents/My Games.
+// prefixPath may be a Proton compatdata directory or a plain Wine prefix.
+func GetMyGamesDir(prefixPath string, game *games.Game) string {
+	return game.MyGamesPath(prefixPath)
+}
+
+// GetCustomIniPath returns the path to the custom ini file of a game. It is
+// in My Games unless the game keeps it in its install directory.
+func GetCustomIniPath(prefixPath string, game *games.Game) (string, error) {
+	if game.ConfigDir == "" {
+		return filepath.Join(GetMyGamesDir(prefixPath, game), game.ConfigFile), nil
+	}
+	gameDir, err := findGameDir(game)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(gameDir, game.ConfigDir, game.ConfigFile), nil
+}
+
+// findGameDir returns the install directory of a game, honouring a custom
+// game path from the config.
+func findGameDir(game *games.Game) (string, error) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return "", err
+	}
+	return game.FindGameDirWithCustomPath(cfg.GamePaths[game.ID])
 }
 
 // GetFallout76CustomIniPath returns the path to the Fallout76Custom.ini file.
 func GetFallout76CustomIniPath(prefixPath string) string {
-	return GetCustomIniPath(prefixPath, "Fallout76Custom.ini")
+	return filepath.Join(prefix.DocumentsDir(prefixPath), "My Games", "Fallout 76", "Fallout76Custom.ini")
 }
 
 // GetFallout76CustomIniPathWithPrefix finds the prefix and returns the path to the Fallout76Custom.ini file.
@@ -44,116 +69,280 @@ func GetFallout76CustomIniPathWithPrefix() (string, error) {
 	return GetFallout76CustomIniPath(prefixPath), nil
 }
 
-// AddArchiveToCustomIni adds a new archive to the sResourceArchive2List in Fallout76Custom.ini.
-func AddArchiveToCustomIni(prefixPath, archiveName string) error {
-	iniPath := GetFallout76CustomIniPath(prefixPath)
-	cfg, err := ini.Load(iniPath)
+// fallout76ArchiveKey is the INI key Fallout 76 reads extra archives from.
+const fallout76ArchiveKey = "sResourceArchive2List"
+
+// archiveIni is the INI setting holding an archive list, with the entries
+// the game needs to keep loading its own archives.
+type archiveIni struct {
+	path    string
+	section string
+	key     string
+	vanilla ArchiveList
+	limit   int // Number of keys when the list is stored as key1 to keyN, 0 for a single key
+}
+
+func fallout76ArchiveIni(prefixPath string) archiveIni {
+	return archiveIni{path: GetFallout76CustomIniPath(prefixPath), section: "Archive", key: fallout76ArchiveKey}
+}
+
+func gameArchiveIni(prefixPath string, game *games.Game) (archiveIni, error) {
+	iniPath, err := GetCustomIniPath(prefixPath, game)
 	if err != nil {
-		if os.IsNotExist(err) {
-			cfg = ini.Empty()
-		} else {
-			return fmt.Errorf("failed to load Fallout76Custom.ini: %w", err)
-		}
+		return archiveIni{}, err
 	}
+	a := archiveIni{
+		path:    iniPath,
+		section: game.ArchiveSection,
+		key:     game.ArchiveKey,
+		vanilla: ArchiveList(game.VanillaArchives),
+	}
+	if a.section == "" {
+		a.section = "Archive"
+	}
+	if game.ArchiveKeyFormat == games.ArchiveKeyFormatNumbered {
+		a.limit = game.ArchiveKeyLimit
+	}
+	return a, nil
+}
 
-	section := cfg.Section("Archive")
-	key := section.Key("sResourceArchive2List")
-	currentValue := key.String()
+// keys returns the names of the INI keys the list is stored in.
+func (a archiveIni) keys() []string {
+	if a.limit == 0 {
+		return []string{a.key}
+	}
+	keys := make([]string, a.limit)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%s%d", a.key, i+1)
+	}
+	return keys
+}
 
-	if currentValue == "" {
-		key.SetValue(archiveName)
-	} else {
-		// Avoid adding duplicate entries
-		if !strings.Contains(currentValue, archiveName) {
-			key.SetValue(fmt.Sprintf("%s, %s", currentValue, archiveName))
+// load reads the INI file and the current archive list. A list missing from
+// the file starts out with the vanilla archives, because setting it in the
+// custom INI replaces the game's default value.
+func (a archiveIni) load() (*ini.File, ArchiveList, error) {
+	cfg, err := ini.Load(a.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, nil, fmt.Errorf("failed to load %s: %w", filepath.Base(a.path), err)
 		}
+		cfg = ini.Empty()
 	}
 
-	return cfg.SaveTo(iniPath)
-
+	section := cfg.Section(a.section)
+	found := false
+	var list ArchiveList
+	for _, key := range a.keys() {
+		if section.HasKey(key) {
+			found = true
+			list = append(list, ParseArchiveList(section.Key(key).String())...)
+		}
+	}
+	if !found {
+		return cfg, append(ArchiveList(nil), a.vanilla...), nil
+	}
+	return cfg, ParseArchiveList(list.String()), nil
 }
 
-// RemoveArchiveFromCustomIni removes an archive from the sResourceArchive2List in Fallout76Custom.ini.
-
-func RemoveArchiveFromCustomIni(prefixPath, archiveName string) error {
-
-	iniPath := GetFallout76CustomIniPath(prefixPath)
+func (a archiveIni) save(cfg *ini.File, list ArchiveList) error {
+	section := cfg.Section(a.section)
+	if a.limit == 0 {
+		section.Key(a.key).SetValue(list.String())
+	} else {
+		if len(list) > a.limit {
+			return fmt.Errorf("%s can only list %d archives in %s", filepath.Base(a.path), a.limit, a.key)
+		}
+		for i, key := range a.keys() {
+			if i < len(list) {
+				section.Key(key).SetValue(list[i])
+			} else {
+				section.DeleteKey(key)
+			}
+		}
+	}
 
-	cfg, err := ini.Load(iniPath)
+	// The My Games folder only exists once the game has been started
+	if err := os.MkdirAll(filepath.Dir(a.path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(a.path), err)
+	}
+	return cfg.SaveTo(a.path)
+}
 
+func (a archiveIni) add(archiveName string) error {
+	cfg, list, err := a.load()
 	if err != nil {
-
-		return fmt.Errorf("failed to load Fallout76Custom.ini: %w", err)
-
+		return err
 	}
+	if list.Contains(archiveName) && cfg.Section(a.section).HasKey(a.keys()[0]) {
+		return nil
+	}
+	return a.save(cfg, list.Add(archiveName))
+}
 
-	section := cfg.Section("Archive")
-
-	key := section.Key("sResourceArchive2List")
-
-	currentValue := key.String()
-
-	if strings.Contains(currentValue, archiveName) {
+func (a archiveIni) remove(archiveName string) error {
+	// Never unregister an archive the game itself needs
+	if a.vanilla.Contains(archiveName) {
+		return nil
+	}
+	cfg, list, err := a.load()
+	if err != nil {
+		return err
+	}
+	if !list.Contains(archiveName) {
+		return nil
+	}
+	return a.save(cfg, list.Remove(archiveName))
+}
 
-		newValue := strings.ReplaceAll(currentValue, ", "+archiveName, "")
+// set replaces the archive list with the vanilla archives followed by archives.
+func (a archiveIni) set(archives []string) error {
+	cfg, _, err := a.load()
+	if err != nil {
+		return err
+	}
+	list := append(ArchiveList(nil), a.vanilla...)
+	for _, archive := range archives {
+		list = list.Add(archive)
+	}
+	return a.save(cfg, list)
+}
 
-		newValue = strings.ReplaceAll(newValue, archiveName+", ", "")
+// AddArchiveToCustomIni adds a new archive to the sResourceArchive2List in Fallout76Custom.ini.
+func AddArchiveToCustomIni(prefixPath, archiveName string) error {
+	return fallout76ArchiveIni(prefixPath).add(archiveName)
+}
 
-		newValue = strings.ReplaceAll(newValue, archiveName, "")
+// AddArchiveToGameIni adds a new archive to the archive list of the game's custom ini.
+func AddArchiveToGameIni(prefixPath string, game *games.Game, archiveName string) error {
+	a, err := gameArchiveIni(prefixPath, game)
+	if err != nil {
+		return err
+	}
+	return a.add(archiveName)
+}
 
-		key.SetValue(newValue)
+// RemoveArchiveFromCustomIni removes an archive from the sResourceArchive2List in Fallout76Custom.ini.
+func RemoveArchiveFromCustomIni(prefixPath, archiveName string) error {
+	return fallout76ArchiveIni(prefixPath).remove(archiveName)
+}
 
+// RemoveArchiveFromGameIni removes an archive from the archive list of the
+// game's custom ini. Vanilla archives are kept.
+func RemoveArchiveFromGameIni(prefixPath string, game *games.Game, archiveName string) error {
+	a, err := gameArchiveIni(prefixPath, game)
+	if err != nil {
+		return err
 	}
+	return a.remove(archiveName)
+}
 
-	return cfg.SaveTo(iniPath)
-
+// ReadGameArchiveList returns the archive list of the game's custom ini.
+func ReadGameArchiveList(prefixPath string, game *games.Game) (ArchiveList, error) {
+	a, err := gameArchiveIni(prefixPath, game)
+	if err != nil {
+		return nil, err
+	}
+	_, list, err := a.load()
+	return list, err
 }
 
-// AddArchiveToCustomIniWithPrefix finds the prefix and adds a new archive to the sResourceArchive2List.
-func AddArchiveToCustomIniWithPrefix(archiveName string) error {
-	cfg, err := LoadConfig()
+// SetGameArchiveList replaces the archive list of the game's custom ini with
+// the vanilla archives followed by archives, in order.
+func SetGameArchiveList(prefixPath string, game *games.Game, archives []string) error {
+	a, err := gameArchiveIni(prefixPath, game)
 	if err != nil {
 		return err
 	}
-	game, err := games.GetGameByID(cfg.CurrentGame)
+	return a.set(archives)
+}
+
+// EnsureRequiredIni writes the settings a game needs for mods to load, such
+// as enabling loose files, to its custom ini. Other settings are kept.
+func EnsureRequiredIni(prefixPath string, game *games.Game) error {
+	if len(game.RequiredIni) == 0 {
+		return nil
+	}
+	iniPath, err := GetCustomIniPath(prefixPath, game)
 	if err != nil {
 		return err
 	}
+	cfg, err := ini.Load(iniPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to load %s: %w", game.ConfigFile, err)
+		}
+		cfg = ini.Empty()
+	}
 
-	// Get custom compatdata path if configured
-	customPath := ""
-	if cfg.CompatdataPaths != nil {
-		customPath = cfg.CompatdataPaths[cfg.CurrentGame]
+	changed := false
+	for setting, value := range game.RequiredIni {
+		sectionName, keyName, _ := strings.Cut(setting, ".")
+		section := cfg.Section(sectionName)
+		if section.HasKey(keyName) && section.Key(keyName).String() == value {
+			continue
+		}
+		section.Key(keyName).SetValue(value)
+		changed = true
+	}
+	if !changed {
+		return nil
 	}
 
-	prefixPath, err := game.FindCompatdataWithCustomPath(customPath)
+	if err := os.MkdirAll(filepath.Dir(iniPath), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(iniPath), err)
+	}
+	return cfg.SaveTo(iniPath)
+}
+
+// AddArchiveToCustomIniWithPrefix finds the prefix of the current game and adds a new archive to its custom ini.
+func AddArchiveToCustomIniWithPrefix(archiveName string) error {
+	game, prefixPath, err := currentGamePrefix()
 	if err != nil {
 		return err
 	}
-	return AddArchiveToCustomIni(prefixPath, archiveName)
+	return AddArchiveToGameIni(prefixPath, game, archiveName)
 }
 
-// RemoveArchiveFromCustomIniWithPrefix finds the prefix and removes an archive from the sResourceArchive2List.
-
+// RemoveArchiveFromCustomIniWithPrefix finds the prefix of the current game and removes an archive from its custom ini.
 func RemoveArchiveFromCustomIniWithPrefix(archiveName string) error {
-	cfg, err := LoadConfig()
+	game, prefixPath, err := currentGamePrefix()
 	if err != nil {
 		return err
 	}
+	return RemoveArchiveFromGameIni(prefixPath, game, archiveName)
+}
+
+// currentGamePrefix returns the current game and its prefix.
+func currentGamePrefix() (*games.Game, string, error) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, "", err
+	}
 	game, err := games.GetGameByID(cfg.CurrentGame)
 	if err != nil {
-		return err
+		return nil, "", err
+	}
+	prefixPath, err := FindGamePrefix(game)
+	if err != nil {
+		return nil, "", err
+	}
+	return game, prefixPath, nil
+}
+
+// FindGamePrefix returns the prefix of a game, honouring a custom compatdata
+// path from the config.
+func FindGamePrefix(game *games.Game) (string, error) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return "", err
 	}
 
 	// Get custom compatdata path if configured
 	customPath := ""
 	if cfg.CompatdataPaths != nil {
-		customPath = cfg.CompatdataPaths[cfg.CurrentGame]
+		customPath = cfg.CompatdataPaths[game.ID]
 	}
 
-	prefixPath, err := game.FindCompatdataWithCustomPath(customPath)
-	if err != nil {
-		return err
-	}
-	return RemoveArchiveFromCustomIni(prefixPath, archiveName)
+	return game.FindCompatdataWithCustomPath(customPath)
 }
@@ -5,18 +5,46 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/bazsalanszky/fusioncore/internal/games"
 	"github.com/bazsalanszky/fusioncore/internal/prefix"
 )
 
-// GetPluginsTxtPath returns the path to the plugins.txt file.
+// GetPluginsTxtPath returns the path to the Fallout 76 plugins.txt file.
+// prefixPath may be a Proton compatdata directory or a plain Wine prefix.
 func GetPluginsTxtPath(prefixPath string) string {
-	return filepath.Join(prefixPath, "pfx", "drive_c", "users", "steamuser", "AppData", "Local", "Fallout76", "plugins.txt")
+	return filepath.Join(prefix.LocalAppDataDir(prefixPath), "Fallout76", "plugins.txt")
+}
+
+// GetGamePluginsTxtPath returns the path to the plugins file of a game. It is
+// in AppData/Local unless the game keeps it in its install directory.
+func GetGamePluginsTxtPath(prefixPath string, game *games.Game) (string, error) {
+	if game.PluginsDir == "" {
+		return filepath.Join(prefix.LocalAppDataDir(prefixPath), game.AppDataDir, game.PluginsFile), nil
+	}
+	gameDir, err := findGameDir(game)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(gameDir, game.PluginsDir, game.PluginsFile), nil
 }
 
 // ReadPlugins reads the list of plugins from plugins.txt.
 func ReadPlugins(prefixPath string) ([]string, error) {
-	pluginsPath := GetPluginsTxtPath(prefixPath)
+	return readPluginsFile(GetPluginsTxtPath(prefixPath))
+}
+
+// ReadGamePlugins reads the list of plugins of a game.
+func ReadGamePlugins(prefixPath string, game *games.Game) ([]string, error) {
+	pluginsPath, err := GetGamePluginsTxtPath(prefixPath, game)
+	if err != nil {
+		return nil, err
+	}
+	return readPluginsFile(pluginsPath)
+}
+
+func readPluginsFile(pluginsPath string) ([]string, error) {
 	file, err := os.Open(pluginsPath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -45,7 +73,23 @@ func ReadPlugins(prefixPath string) ([]string, error) {
 
 // WritePlugins writes the list of plugins to plugins.txt.
 func WritePlugins(prefixPath string, plugins []string) error {
-	pluginsPath := GetPluginsTxtPath(prefixPath)
+	return writePluginsFile(GetPluginsTxtPath(prefixPath), plugins)
+}
+
+// WriteGamePlugins writes the list of plugins of a game.
+func WriteGamePlugins(prefixPath string, game *games.Game, plugins []string) error {
+	pluginsPath, err := GetGamePluginsTxtPath(prefixPath, game)
+	if err != nil {
+		return err
+	}
+	return writePluginsFile(pluginsPath, plugins)
+}
+
+func writePluginsFile(pluginsPath string, plugins []string) error {
+	// AppData/Local/<game> only exists once the game has been started
+	if err := os.MkdirAll(filepath.Dir(pluginsPath), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(pluginsPath), err)
+	}
 	file, err := os.Create(pluginsPath)
 	if err != nil {
 		return fmt.Errorf("failed to create plugins.txt: %w", err)
@@ -65,27 +109,76 @@ func WritePlugins(prefixPath string, plugins []string) error {
 
 // AddPlugin adds a new plugin to plugins.txt.
 func AddPlugin(prefixPath, pluginName string) error {
-	plugins, err := ReadPlugins(prefixPath)
+	return addPluginToFile(GetPluginsTxtPath(prefixPath), pluginName, games.PluginsFormatPlain)
+}
+
+// AddGamePlugin adds a new, active plugin to the plugins file of a game.
+func AddGamePlugin(prefixPath string, game *games.Game, pluginName string) error {
+	pluginsPath, err := GetGamePluginsTxtPath(prefixPath, game)
+	if err != nil {
+		return err
+	}
+	return addPluginToFile(pluginsPath, pluginName, game.PluginsFormat)
+}
+
+func addPluginToFile(pluginsPath, pluginName, format string) error {
+	plugins, err := readPluginsFile(pluginsPath)
 	if err != nil {
 		return err
 	}
 
 	// Avoid adding duplicate plugins
 	for _, p := range plugins {
-		if p == pluginName {
+		if strings.TrimPrefix(p, "*") == pluginName {
 			return nil // Plugin already exists
 		}
 	}
 
+	// Games using the asterisk format only load plugins marked with '*'
+	if format == games.PluginsFormatAsterisk {
+		pluginName = "*" + pluginName
+	}
 	plugins = append(plugins, pluginName)
-	return WritePlugins(prefixPath, plugins)
+	return writePluginsFile(pluginsPath, plugins)
 }
 
-// AddPluginWithPrefix finds the prefix and adds a new plugin to plugins.txt.
+// AddPluginWithPrefix finds the prefix of the current game and adds a new plugin to its plugins file.
 func AddPluginWithPrefix(pluginName string) error {
-    prefixPath, err := prefix.FindFallout76Prefix()
-    if err != nil {
-        return err
-    }
-    return AddPlugin(prefixPath, pluginName)
+	game, prefixPath, err := currentGamePrefix()
+	if err != nil {
+		return err
+	}
+	return AddGamePlugin(prefixPath, game, pluginName)
+}
+
+// RemoveGamePlugin removes a plugin from the plugins file of a game.
+func RemoveGamePlugin(prefixPath string, game *games.Game, pluginName string) error {
+	pluginsPath, err := GetGamePluginsTxtPath(prefixPath, game)
+	if err != nil {
+		return err
+	}
+	plugins, err := readPluginsFile(pluginsPath)
+	if err != nil {
+		return err
+	}
+
+	var kept []string
+	for _, p := range plugins {
+		if !strings.EqualFold(strings.TrimPrefix(p, "*"), pluginName) {
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) == len(plugins) {
+		return nil
+	}
+	return writePluginsFile(pluginsPath, kept)
+}
+
+// RemovePluginWithPrefix finds the prefix of the current game and removes a plugin from its plugins file.
+func RemovePluginWithPrefix(pluginName string) error {
+	game, prefixPath, err := currentGamePrefix()
+	if err != nil {
+		return err
+	}
+	return RemoveGamePlugin(prefixPath, game, pluginName)
 }
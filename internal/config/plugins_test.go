@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"reflect"
 	"testing"
+
+	"github.com/bazsalanszky/fusioncore/internal/games"
 )
 
 func TestPlugins(t *testing.T) {
@@ -92,3 +94,36 @@ func TestPlugins(t *testing.T) {
 		t.Errorf("Test 5 failed: expected %v, got %v", expectedPlugins, plugins)
 	}
 }
+
+func TestGamePluginsPath(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test-prefix-game-plugins")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := os.MkdirAll(filepath.Join(tmpDir, "pfx", "drive_c", "users", "steamuser"), 0755); err != nil {
+		t.Fatalf("Failed to create prefix: %v", err)
+	}
+
+	game, err := games.GetGameByID("fallout4")
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+
+	// The AppData folder doesn't exist until the game is started once
+	if err := AddGamePlugin(tmpDir, game, "TestMod.esp"); err != nil {
+		t.Fatalf("Failed to add plugin: %v", err)
+	}
+
+	userDir := filepath.Join(tmpDir, "pfx", "drive_c", "users", "steamuser")
+	expected := filepath.Join(userDir, "AppData", "Local", "Fallout4", "plugins.txt")
+	if _, err := os.Stat(expected); err != nil {
+		t.Errorf("expected plugins file at %s: %v", expected, err)
+	}
+
+	expectedIni := filepath.Join(userDir, "Documents", "My Games", "Fallout4", "Fallout4Custom.ini")
+	if path, err := GetCustomIniPath(tmpDir, game); err != nil || path != expectedIni {
+		t.Errorf("expected ini path %q, got %q (%v)", expectedIni, path, err)
+	}
+}
@@ -0,0 +1,301 @@
+// Package fomod installs mods packaged with a FOMOD installer using choices
+// made elsewhere, like the ones stored in a Nexus collection.
+package fomod
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/bazsalanszky/fusioncore/internal/mod"
+)
+
+// Choice is a plugin selected in a group of an install step.
+type Choice struct {
+	Step   string
+	Group  string
+	Plugin string
+}
+
+type moduleConfig struct {
+	Required    fileList `xml:"requiredInstallFiles"`
+	Steps       []step   `xml:"installSteps>installStep"`
+	Conditional []struct {
+		Dependencies dependencies `xml:"dependencies"`
+		Files        fileList     `xml:"files"`
+	} `xml:"conditionalFileInstalls>patterns>pattern"`
+}
+
+type step struct {
+	Name   string  `xml:"name,attr"`
+	Groups []group `xml:"optionalFileGroups>group"`
+}
+
+type group struct {
+	Name    string   `xml:"name,attr"`
+	Plugins []plugin `xml:"plugins>plugin"`
+}
+
+type plugin struct {
+	Name  string   `xml:"name,attr"`
+	Files fileList `xml:"files"`
+	Flags []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"conditionFlags>flag"`
+}
+
+type fileList struct {
+	Files   []fileEntry `xml:"file"`
+	Folders []fileEntry `xml:"folder"`
+}
+
+type fileEntry struct {
+	Source      string  `xml:"source,attr"`
+	Destination *string `xml:"destination,attr"` // Files default to their source path, folders to the root
+	Priority    int     `xml:"priority,attr"`
+	folder      bool
+}
+
+type dependencies struct {
+	Operator string `xml:"operator,attr"` // And if empty
+	Flags    []struct {
+		Flag  string `xml:"flag,attr"`
+		Value string `xml:"value,attr"`
+	} `xml:"flagDependency"`
+	Nested []dependencies `xml:"dependencies"`
+}
+
+// met reports whether the flag dependencies are satisfied. Dependencies on
+// files and game versions aren't checked.
+func (d *dependencies) met(flags map[string]string) bool {
+	var results []bool
+	for _, f := range d.Flags {
+		results = append(results, flags[f.Flag] == f.Value)
+	}
+	for i := range d.Nested {
+		results = append(results, d.Nested[i].met(flags))
+	}
+	if strings.EqualFold(d.Operator, "Or") {
+		for _, r := range results {
+			if r {
+				return true
+			}
+		}
+		return len(results) == 0
+	}
+	for _, r := range results {
+		if !r {
+			return false
+		}
+	}
+	return true
+}
+
+// FindConfig returns the folder holding the fomod folder of an extracted
+// mod, empty if the mod has no installer. Archives often wrap everything in
+// a top level folder, so that is searched too.
+func FindConfig(dir string) string {
+	candidates := []string{dir}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) == 1 && entries[0].IsDir() {
+		candidates = append(candidates, filepath.Join(dir, entries[0].Name()))
+	}
+	for _, base := range candidates {
+		if _, err := resolve(base, "fomod/ModuleConfig.xml"); err == nil {
+			return base
+		}
+	}
+	return ""
+}
+
+// Install copies the files of the installer in srcDir, as found by
+// FindConfig, to destDir: the required files, the files of the chosen
+// plugins and the conditional files whose flags the choices set. Files with
+// a higher priority win.
+func Install(srcDir, destDir string, choices []Choice) error {
+	configPath, err := resolve(srcDir, "fomod/ModuleConfig.xml")
+	if err != nil {
+		return fmt.Errorf("failed to find the FOMOD installer: %w", err)
+	}
+	config, err := readConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	chosen := make(map[string]bool)
+	for _, c := range choices {
+		chosen[strings.ToLower(c.Step+"/"+c.Group+"/"+c.Plugin)] = true
+	}
+
+	entries := config.Required.entries()
+	flags := make(map[string]string)
+	found := 0
+	for _, s := range config.Steps {
+		for _, g := range s.Groups {
+			for _, p := range g.Plugins {
+				if !chosen[strings.ToLower(s.Name+"/"+g.Name+"/"+p.Name)] {
+					continue
+				}
+				found++
+				entries = append(entries, p.Files.entries()...)
+				for _, f := range p.Flags {
+					flags[f.Name] = f.Value
+				}
+			}
+		}
+	}
+	if found < len(chosen) {
+		fmt.Printf("Only %d of the %d choices match the installer in %s\n", found, len(chosen), srcDir)
+	}
+	for _, c := range config.Conditional {
+		if c.Dependencies.met(flags) {
+			entries = append(entries, c.Files.entries()...)
+		}
+	}
+
+	// Later entries win on equal priority
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Priority < entries[j].Priority })
+	for _, e := range entries {
+		if err := e.install(srcDir, destDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *fileList) entries() []fileEntry {
+	var entries []fileEntry
+	for _, f := range l.Files {
+		entries = append(entries, f)
+	}
+	for _, f := range l.Folders {
+		f.folder = true
+		entries = append(entries, f)
+	}
+	return entries
+}
+
+// install copies an entry of the installer, with the source resolved case
+// insensitively as installers are written for Windows.
+func (e *fileEntry) install(srcDir, destDir string) error {
+	src, err := resolve(srcDir, e.Source)
+	if err != nil {
+		return fmt.Errorf("failed to find %s in the installer: %w", e.Source, err)
+	}
+	dest := ""
+	switch {
+	case e.Destination != nil:
+		dest = *e.Destination
+	case !e.folder:
+		dest = e.Source
+	}
+	dest, err = destination(destDir, dest)
+	if err != nil {
+		return err
+	}
+
+	if !e.folder {
+		return copyFile(src, dest)
+	}
+	return filepath.WalkDir(src, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		return copyFile(path, filepath.Join(dest, rel))
+	})
+}
+
+// destination joins a destination of the installer onto destDir, rejecting
+// ones that would write outside of it as installers come from downloads.
+func destination(destDir, dest string) (string, error) {
+	slashed := strings.ReplaceAll(dest, `\`, "/")
+	if strings.HasPrefix(slashed, "/") || filepath.IsAbs(slashed) || filepath.VolumeName(slashed) != "" ||
+		len(slashed) >= 2 && slashed[1] == ':' {
+		return "", fmt.Errorf("installer destination %s is absolute", dest)
+	}
+	for _, part := range strings.Split(slashed, "/") {
+		if part == ".." {
+			return "", fmt.Errorf("installer destination %s leaves the mod folder", dest)
+		}
+	}
+	path := filepath.Join(destDir, filepath.FromSlash(slashed))
+	rel, err := filepath.Rel(destDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("installer destination %s leaves the mod folder", dest)
+	}
+	return path, nil
+}
+
+// resolve finds a path with backslashes under dir, ignoring case.
+func resolve(dir, rel string) (string, error) {
+	path := dir
+	for _, part := range strings.Split(strings.ReplaceAll(rel, `\`, "/"), "/") {
+		if part == "" || part == "." {
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return "", err
+		}
+		next := ""
+		for _, entry := range entries {
+			if strings.EqualFold(entry.Name(), part) {
+				next = filepath.Join(path, entry.Name())
+				break
+			}
+		}
+		if next == "" {
+			return "", fmt.Errorf("%s not found in %s: %w", part, path, os.ErrNotExist)
+		}
+		path = next
+	}
+	return path, nil
+}
+
+// readConfig reads ModuleConfig.xml, which is often UTF-16 encoded.
+func readConfig(path string) (*moduleConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(data) >= 2 && (data[0] == 0xff && data[1] == 0xfe || data[0] == 0xfe && data[1] == 0xff) {
+		bigEndian := data[0] == 0xfe
+		units := make([]uint16, 0, len(data)/2)
+		for i := 2; i+1 < len(data); i += 2 {
+			if bigEndian {
+				units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+			} else {
+				units = append(units, uint16(data[i+1])<<8|uint16(data[i]))
+			}
+		}
+		data = []byte(string(utf16.Decode(units)))
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	var config moduleConfig
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	// The content is UTF-8 by now, whatever the declaration says
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) { return input, nil }
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &config, nil
+}
+
+func copyFile(src, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	os.Remove(dest) // Replace files of entries with a lower priority
+	return mod.CopyFile(src, dest)
+}
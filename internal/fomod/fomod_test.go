@@ -0,0 +1,143 @@
+package fomod
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+)
+
+const testConfig = `<?xml version="1.0" encoding="UTF-16"?>
+<config>
+	<moduleName>Test Mod</moduleName>
+	<requiredInstallFiles>
+		<folder source="00 Core" destination="" />
+	</requiredInstallFiles>
+	<installSteps order="Explicit">
+		<installStep name="Main">
+			<optionalFileGroups order="Explicit">
+				<group name="Version" type="SelectExactlyOne">
+					<plugins order="Explicit">
+						<plugin name="SE">
+							<files><file source="10 SE\Plugin.dll" destination="SKSE\Plugins\Plugin.dll" /></files>
+							<conditionFlags><flag name="edition">se</flag></conditionFlags>
+						</plugin>
+						<plugin name="AE">
+							<files><file source="20 AE\Plugin.dll" destination="SKSE\Plugins\Plugin.dll" /></files>
+							<conditionFlags><flag name="edition">ae</flag></conditionFlags>
+						</plugin>
+					</plugins>
+				</group>
+			</optionalFileGroups>
+		</installStep>
+	</installSteps>
+	<conditionalFileInstalls>
+		<patterns>
+			<pattern>
+				<dependencies operator="And"><flagDependency flag="edition" value="se" /></dependencies>
+				<files><folder source="30 SE Extras" destination="" priority="1" /></files>
+			</pattern>
+			<pattern>
+				<dependencies operator="And"><flagDependency flag="edition" value="ae" /></dependencies>
+				<files><folder source="40 AE Extras" destination="" /></files>
+			</pattern>
+		</patterns>
+	</conditionalFileInstalls>
+</config>`
+
+func TestInstall(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-fomod")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Archives usually wrap the installer in a folder
+	srcDir := filepath.Join(tmpDir, "src", "Test Mod")
+	files := map[string]string{
+		"00 Core/Test.esp":                    "core",
+		"00 Core/interface/settings.ini":      "default",
+		"10 SE/Plugin.dll":                    "se",
+		"20 AE/Plugin.dll":                    "ae",
+		"30 SE Extras/interface/settings.ini": "se settings",
+		"40 AE Extras/ae.txt":                 "ae",
+	}
+	for name, content := range files {
+		path := filepath.Join(srcDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	// Installers are often UTF-16 with a lowercase fomod folder
+	data := []byte{0xff, 0xfe}
+	for _, u := range utf16.Encode([]rune(testConfig)) {
+		data = append(data, byte(u), byte(u>>8))
+	}
+	if err := os.MkdirAll(filepath.Join(srcDir, "fomod"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "fomod", "moduleconfig.xml"), data, 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	base := FindConfig(filepath.Join(tmpDir, "src"))
+	if base != srcDir {
+		t.Fatalf("expected the installer in %s, got %q", srcDir, base)
+	}
+
+	destDir := filepath.Join(tmpDir, "dest")
+	if err := Install(base, destDir, []Choice{{Step: "Main", Group: "Version", Plugin: "SE"}}); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"Test.esp":                "core",
+		"interface/settings.ini":  "se settings", // Higher priority
+		"SKSE/Plugins/Plugin.dll": "se",
+	}
+	for name, content := range expected {
+		data, err := os.ReadFile(filepath.Join(destDir, filepath.FromSlash(name)))
+		if err != nil || string(data) != content {
+			t.Errorf("expected %s to be %q, got %q, %v", name, content, data, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "ae.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected files of unselected options to be left out")
+	}
+}
+
+func TestInstallRejectsEscapingDestination(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-fomod")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	srcDir := filepath.Join(tmpDir, "src")
+	if err := os.MkdirAll(filepath.Join(srcDir, "fomod"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "x.desktop"), []byte("evil"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	destDir := filepath.Join(tmpDir, "mods", "dest")
+
+	for _, dest := range []string{`..\..\autostart\x.desktop`, `Data/../../x.desktop`, `/tmp/x.desktop`, `C:\x.desktop`} {
+		config := `<config><requiredInstallFiles><file source="x.desktop" destination="` + dest + `" /></requiredInstallFiles></config>`
+		if err := os.WriteFile(filepath.Join(srcDir, "fomod", "ModuleConfig.xml"), []byte(config), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if err := Install(srcDir, destDir, nil); err == nil {
+			t.Errorf("expected the destination %s to be rejected", dest)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "autostart")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be written outside the mod folder")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "x.desktop")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be written outside the mod folder")
+	}
+}
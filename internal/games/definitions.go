@@ -0,0 +1,328 @@
+package games
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+)
+
+//go:embed definitions/games.toml
+var builtinDefinitions embed.FS
+
+var (
+	loadOnce    sync.Once
+	loadedGames []Game
+	validID     = regexp.MustCompile(`^[a-z0-9_-]+$`)
+)
+
+// GetDefinitionsDir returns the directory user game definitions are read from.
+func GetDefinitionsDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(configDir, "fusion-core", "games"), nil
+}
+
+// definitions returns the merged game definitions, loading them on first use.
+func definitions() []Game {
+	loadOnce.Do(func() {
+		dir, err := GetDefinitionsDir()
+		if err != nil {
+			fmt.Printf("Skipping user game definitions: %v\n", err)
+		}
+		loadedGames, err = LoadDefinitions(dir)
+		if err != nil {
+			fmt.Printf("Failed to load game definitions: %v\n", err)
+		}
+	})
+	return loadedGames
+}
+
+// LoadDefinitions returns the built-in game definitions merged with the
+// .toml and .json files in userDir. A user definition with the ID of an
+// existing game only overrides the fields it sets. Invalid user files are
+// reported and skipped; an empty userDir loads only the built-in games.
+func LoadDefinitions(userDir string) ([]Game, error) {
+	data, err := builtinDefinitions.ReadFile("definitions/games.toml")
+	if err != nil {
+		return nil, err
+	}
+	builtin, err := parseDefinitions("games.toml", data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid built-in game definitions: %w", err)
+	}
+	if userDir == "" {
+		return builtin, nil
+	}
+
+	entries, err := os.ReadDir(userDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return builtin, nil
+		}
+		return builtin, fmt.Errorf("failed to read %s: %w", userDir, err)
+	}
+
+	// Files are applied in name order, so later files override earlier ones
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	merged := builtin
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".toml" && ext != ".json") {
+			continue
+		}
+		path := filepath.Join(userDir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Printf("Skipping game definitions in %s: %v\n", path, err)
+			continue
+		}
+		updated, err := parseDefinitions(entry.Name(), data, merged)
+		if err != nil {
+			fmt.Printf("Skipping game definitions in %s: %v\n", path, err)
+			continue
+		}
+		merged = updated
+	}
+	return merged, nil
+}
+
+// parseDefinitions parses a definitions file and merges it into base, which
+// is not modified. The file holds a list of games under the "game" key:
+// [[game]] tables in TOML, or {"game": [...]} in JSON.
+func parseDefinitions(name string, data []byte, base []Game) ([]Game, error) {
+	merged := make([]Game, len(base))
+	copy(merged, base)
+
+	// decode fills in a game, starting from the existing definition with
+	// the same ID so unset fields keep their values
+	var decode []func(g *Game) error
+	var ids []string
+	var checkUnknown func() error
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".toml":
+		var file struct{ Game []toml.Primitive }
+		md, err := toml.Decode(string(data), &file)
+		if err != nil {
+			return nil, err
+		}
+		for _, prim := range file.Game {
+			var header struct{ ID string }
+			if err := md.PrimitiveDecode(prim, &header); err != nil {
+				return nil, err
+			}
+			prim := prim
+			ids = append(ids, header.ID)
+			decode = append(decode, func(g *Game) error { return md.PrimitiveDecode(prim, g) })
+		}
+		// Keys of a primitive count as undecoded until it is decoded, so
+		// this can only be checked once every game has been decoded
+		checkUnknown = func() error {
+			if undecoded := md.Undecoded(); len(undecoded) > 0 {
+				return fmt.Errorf("unknown field %q", undecoded[0].String())
+			}
+			return nil
+		}
+	case ".json":
+		var file struct{ Game []json.RawMessage }
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, err
+		}
+		for _, raw := range file.Game {
+			var header struct{ ID string }
+			if err := json.Unmarshal(raw, &header); err != nil {
+				return nil, err
+			}
+			raw := raw
+			ids = append(ids, header.ID)
+			decode = append(decode, func(g *Game) error {
+				dec := json.NewDecoder(bytes.NewReader(raw))
+				dec.DisallowUnknownFields()
+				return dec.Decode(g)
+			})
+		}
+	default:
+		return nil, fmt.Errorf("unsupported definitions format %q", filepath.Ext(name))
+	}
+
+	for i, id := range ids {
+		index := -1
+		for j := range merged {
+			if merged[j].ID == id {
+				index = j
+				break
+			}
+		}
+
+		var game Game
+		if index >= 0 {
+			game = cloneGame(merged[index])
+		}
+		if err := decode[i](&game); err != nil {
+			return nil, fmt.Errorf("game %q: %w", id, err)
+		}
+		if game.DataSubDir == "" {
+			game.DataSubDir = "Data"
+		}
+		if game.PluginsFormat == "" {
+			game.PluginsFormat = PluginsFormatPlain
+		}
+		if game.ArchiveStrategy == "" {
+			game.ArchiveStrategy = ArchiveStrategyINI
+		}
+		if game.ArchiveKeyFormat == "" {
+			game.ArchiveKeyFormat = ArchiveKeyFormatList
+		}
+		if game.ArchiveSection == "" {
+			game.ArchiveSection = "Archive"
+		}
+		if game.DeployMode == "" {
+			game.DeployMode = DeployModeLoose
+		}
+		if err := game.Validate(); err != nil {
+			return nil, err
+		}
+
+		if index >= 0 {
+			merged[index] = game
+		} else {
+			merged = append(merged, game)
+		}
+	}
+
+	if checkUnknown != nil {
+		if err := checkUnknown(); err != nil {
+			return nil, err
+		}
+	}
+	return merged, nil
+}
+
+// cloneGame copies a game so decoding into it doesn't change the slices of the original.
+func cloneGame(g Game) Game {
+	g.ArchiveExts = append([]string(nil), g.ArchiveExts...)
+	g.GOGIDs = append([]string(nil), g.GOGIDs...)
+	g.EpicAppNames = append([]string(nil), g.EpicAppNames...)
+	g.ArchiveSuffixes = append([]string(nil), g.ArchiveSuffixes...)
+	g.VanillaArchives = append([]string(nil), g.VanillaArchives...)
+	g.VanillaFiles = append([]string(nil), g.VanillaFiles...)
+	g.Editions = append([]Edition(nil), g.Editions...)
+	if g.RequiredIni != nil {
+		required := make(map[string]string, len(g.RequiredIni))
+		for k, v := range g.RequiredIni {
+			required[k] = v
+		}
+		g.RequiredIni = required
+	}
+	return g
+}
+
+// Validate checks that a game definition has everything the mod manager needs.
+func (g *Game) Validate() error {
+	if !validID.MatchString(g.ID) {
+		return fmt.Errorf("invalid game ID %q, use lowercase letters, digits, '-' and '_'", g.ID)
+	}
+
+	required := []struct{ field, value string }{
+		{"name", g.Name},
+		{"nexus_domain", g.NexusName},
+		{"config_file", g.ConfigFile},
+		{"plugins_file", g.PluginsFile},
+		{"my_games_dir", g.MyGamesDir},
+		{"archive_key", g.ArchiveKey},
+	}
+	for _, r := range required {
+		if strings.TrimSpace(r.value) == "" {
+			return fmt.Errorf("game %q: missing %s", g.ID, r.field)
+		}
+	}
+
+	if g.AppID != "" {
+		for _, c := range g.AppID {
+			if c < '0' || c > '9' {
+				return fmt.Errorf("game %q: app_id must be numeric, got %q", g.ID, g.AppID)
+			}
+		}
+	}
+
+	if len(g.ArchiveExts) == 0 {
+		return fmt.Errorf("game %q: missing archive_exts", g.ID)
+	}
+	for _, ext := range g.ArchiveExts {
+		if !strings.HasPrefix(ext, ".") || len(ext) < 2 {
+			return fmt.Errorf("game %q: archive extension %q must start with a dot", g.ID, ext)
+		}
+	}
+
+	switch g.PluginsFormat {
+	case PluginsFormatPlain, PluginsFormatAsterisk, PluginsFormatTimestamp:
+	default:
+		return fmt.Errorf("game %q: unknown plugins_format %q", g.ID, g.PluginsFormat)
+	}
+
+	switch g.ArchiveStrategy {
+	case ArchiveStrategyINI:
+	case ArchiveStrategyPlugin:
+		if len(g.ArchiveSuffixes) == 0 {
+			return fmt.Errorf("game %q: archive_strategy %q needs archive_autoload_suffixes", g.ID, g.ArchiveStrategy)
+		}
+	default:
+		return fmt.Errorf("game %q: unknown archive_strategy %q", g.ID, g.ArchiveStrategy)
+	}
+
+	switch g.DeployMode {
+	case DeployModeLoose, DeployModeArchives:
+	default:
+		return fmt.Errorf("game %q: unknown deploy_mode %q", g.ID, g.DeployMode)
+	}
+
+	switch g.ArchiveKeyFormat {
+	case ArchiveKeyFormatList:
+	case ArchiveKeyFormatNumbered:
+		if g.ArchiveKeyLimit <= 0 {
+			return fmt.Errorf("game %q: archive_key_format %q needs archive_key_limit", g.ID, g.ArchiveKeyFormat)
+		}
+	default:
+		return fmt.Errorf("game %q: unknown archive_key_format %q", g.ID, g.ArchiveKeyFormat)
+	}
+
+	for field, dir := range map[string]string{"data_subdir": g.DataSubDir, "config_dir": g.ConfigDir, "plugins_dir": g.PluginsDir, "version_executable": g.VersionExe} {
+		if filepath.IsAbs(dir) || strings.Contains(dir, "..") {
+			return fmt.Errorf("game %q: %s must be relative to the game directory", g.ID, field)
+		}
+	}
+
+	for _, e := range g.Editions {
+		if strings.TrimSpace(e.Name) == "" {
+			return fmt.Errorf("game %q: edition without a name", g.ID)
+		}
+		if _, err := ParseVersion(e.MinVersion); err != nil {
+			return fmt.Errorf("game %q: edition %q: %w", g.ID, e.Name, err)
+		}
+	}
+
+	for _, pattern := range g.VanillaFiles {
+		if _, err := path.Match(pattern, ""); err != nil || strings.HasPrefix(pattern, "/") {
+			return fmt.Errorf("game %q: invalid vanilla_files pattern %q", g.ID, pattern)
+		}
+	}
+
+	for setting := range g.RequiredIni {
+		if section, key, ok := strings.Cut(setting, "."); !ok || section == "" || key == "" {
+			return fmt.Errorf("game %q: required_ini setting %q must be \"Section.Key\"", g.ID, setting)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package games
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuiltinDefinitions(t *testing.T) {
+	games, err := LoadDefinitions("")
+	if err != nil {
+		t.Fatalf("Failed to load built-in definitions: %v", err)
+	}
+	if len(games) == 0 || games[0].ID != "fallout76" {
+		t.Fatalf("expected Fallout 76 to be the first game, got %v", games)
+	}
+	for _, g := range games {
+		if err := g.Validate(); err != nil {
+			t.Errorf("invalid built-in definition: %v", err)
+		}
+	}
+}
+
+func TestUserDefinitions(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-game-definitions")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		// Overrides a single field of a built-in game
+		"10-fallout4.toml": `
+[[game]]
+id = "fallout4"
+game_dir = "Fallout 4 GOTY"
+epic_app_names = ["epic-fallout4"]
+`,
+		// Adds a new game
+		"20-enderal.json": `{"game": [{
+	"id": "enderal",
+	"name": "Enderal",
+	"app_id": "933480",
+	"nexus_domain": "enderal",
+	"config_file": "Enderal.ini",
+	"plugins_file": "plugins.txt",
+	"my_games_dir": "Enderal",
+	"archive_exts": [".bsa"],
+	"archive_key": "sResourceArchiveList2"
+}]}`,
+		// Invalid files are skipped
+		"30-typo.toml": `
+[[game]]
+id = "fallout3"
+archive_ext = ".bsa"
+`,
+		"40-missing.toml": `
+[[game]]
+id = "newgame"
+name = "New Game"
+`,
+		"50-extension.toml": `
+[[game]]
+id = "fallout76-copy"
+name = "Copy"
+nexus_domain = "fallout76"
+config_file = "Copy.ini"
+plugins_file = "plugins.txt"
+my_games_dir = "Copy"
+archive_exts = ["ba2"]
+archive_key = "sResourceArchive2List"
+`,
+		"notes.txt": "not a definition",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	games, err := LoadDefinitions(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to load definitions: %v", err)
+	}
+
+	byID := make(map[string]Game)
+	for _, g := range games {
+		byID[g.ID] = g
+	}
+
+	fo4 := byID["fallout4"]
+	if fo4.GameDir != "Fallout 4 GOTY" {
+		t.Errorf("expected the override to change the game dir, got %q", fo4.GameDir)
+	}
+	if fo4.AppID != "377160" || fo4.ScriptExtender != "f4se_loader.exe" || len(fo4.GOGIDs) != 1 {
+		t.Errorf("expected unset fields to keep their built-in values, got %+v", fo4)
+	}
+	if q := fo4.launcherQuery(); len(q.EpicAppNames) != 1 || q.EpicAppNames[0] != "epic-fallout4" {
+		t.Errorf("expected the Epic app names to be looked for in launchers, got %+v", q)
+	}
+
+	enderal, ok := byID["enderal"]
+	if !ok {
+		t.Fatalf("expected the user defined game to be loaded")
+	}
+	if enderal.DataSubDir != "Data" || enderal.PluginsFormat != PluginsFormatPlain {
+		t.Errorf("expected defaults to be filled in, got %+v", enderal)
+	}
+	if games[len(games)-1].ID != "enderal" {
+		t.Errorf("expected new games to be appended")
+	}
+
+	for _, id := range []string{"newgame", "fallout76-copy"} {
+		if _, ok := byID[id]; ok {
+			t.Errorf("expected invalid definition %s to be skipped", id)
+		}
+	}
+	if _, ok := byID["fallout3"]; !ok {
+		t.Errorf("expected fallout3 to survive a skipped override")
+	}
+
+	// The built-in definitions are not modified by overrides
+	builtin, _ := LoadDefinitions("")
+	for _, g := range builtin {
+		if g.ID == "fallout4" && g.GameDir != "Fallout 4" {
+			t.Errorf("expected built-in definition to be unchanged, got %q", g.GameDir)
+		}
+	}
+}
+
+func TestAutoLoadsArchive(t *testing.T) {
+	skyrim, err := GetGameByID("skyrimse")
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+	plugins := []string{"MyMod.esp"}
+	for archive, expected := range map[string]bool{
+		"MyMod.bsa":            true,
+		"mymod - textures.bsa": true,
+		"MyMod - Meshes.bsa":   false,
+		"Other.bsa":            false,
+	} {
+		if skyrim.AutoLoadsArchive(archive, plugins) != expected {
+			t.Errorf("expected AutoLoadsArchive(%q) to be %v", archive, expected)
+		}
+	}
+
+	// Games using the INI strategy always need the INI entry
+	fnv, err := GetGameByID("falloutnv")
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+	if fnv.AutoLoadsArchive("MyMod.bsa", plugins) {
+		t.Errorf("expected Fallout: New Vegas archives to be listed in the INI")
+	}
+}
+
+func TestVRGames(t *testing.T) {
+	for _, tc := range []struct {
+		id, appID, configFile, myGames, loader string
+	}{
+		{"fallout4vr", "611660", "Fallout4Custom.ini", "Fallout4VR", "f4sevr_loader.exe"},
+		{"skyrimvr", "611670", "SkyrimVR.ini", "Skyrim VR", "sksevr_loader.exe"},
+		{"enderalse", "976620", "Enderal.ini", "Enderal Special Edition", "skse64_loader.exe"},
+	} {
+		g, err := GetGameByID(tc.id)
+		if err != nil {
+			t.Fatalf("Failed to get %s: %v", tc.id, err)
+		}
+		if g.AppID != tc.appID || g.ConfigFile != tc.configFile || g.MyGamesDir != tc.myGames || g.ScriptExtender != tc.loader {
+			t.Errorf("unexpected definition for %s: %+v", tc.id, g)
+		}
+	}
+
+	// VR editions share the Nexus domain of the flat game
+	matching := GetGamesByNexusName("fallout4")
+	if len(matching) != 2 || matching[0].ID != "fallout4" || matching[1].ID != "fallout4vr" {
+		t.Errorf("expected Fallout 4 and Fallout 4 VR, got %v", matching)
+	}
+	if g, err := GetGameByNexusName("skyrimspecialedition"); err != nil || g.ID != "skyrimse" {
+		t.Errorf("expected Skyrim SE for its own domain, got %v (%v)", g, err)
+	}
+}
@@ -3,92 +3,106 @@ package games
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+
+	"github.com/bazsalanszky/fusioncore/internal/launchers"
+	"github.com/bazsalanszky/fusioncore/internal/prefix"
+	"github.com/bazsalanszky/fusioncore/internal/steam"
 )
 
-// Game represents a supported game with its configuration
+// Game represents a supported game with its configuration.
+// Games are defined in TOML or JSON, see definitions.go.
 type Game struct {
-	ID          string
-	Name        string
-	AppID       string
-	NexusName   string
-	GameDir     string
-	DataSubDir  string
-	ConfigFile  string
-	PluginsFile string
-	ArchiveExt  string
-}
-
-// GetSupportedGames returns all supported games
+	ID               string            `toml:"id" json:"id"`
+	Name             string            `toml:"name" json:"name"`
+	AppID            string            `toml:"app_id" json:"app_id"`
+	NexusName        string            `toml:"nexus_domain" json:"nexus_domain"`
+	GameDir          string            `toml:"game_dir" json:"game_dir"` // Default install folder name, used when no app manifest is found
+	DataSubDir       string            `toml:"data_subdir" json:"data_subdir"`
+	ConfigFile       string            `toml:"config_file" json:"config_file"`
+	PluginsFile      string            `toml:"plugins_file" json:"plugins_file"`
+	PluginsFormat    string            `toml:"plugins_format" json:"plugins_format"`                       // One of the PluginsFormat constants
+	MyGamesDir       string            `toml:"my_games_dir" json:"my_games_dir"`                           // Folder under Documents/My Games holding the INI files
+	AppDataDir       string            `toml:"appdata_dir" json:"appdata_dir"`                             // Folder under AppData/Local holding plugins.txt
+	RegistryName     string            `toml:"registry_name" json:"registry_name"`                         // Key under HKLM\Software\Bethesda Softworks
+	ArchiveExts      []string          `toml:"archive_exts" json:"archive_exts"`                           // Archive extensions, the first one is used for new archives
+	ArchiveKey       string            `toml:"archive_key" json:"archive_key"`                             // INI key in ArchiveSection listing extra archives
+	ArchiveSection   string            `toml:"archive_section" json:"archive_section"`                     // INI section of ArchiveKey, "Archive" if unset
+	ArchiveStrategy  string            `toml:"archive_strategy" json:"archive_strategy"`                   // One of the ArchiveStrategy constants
+	ArchiveSuffixes  []string          `toml:"archive_autoload_suffixes" json:"archive_autoload_suffixes"` // Suffixes of archives loaded with their plugin, e.g. " - Textures"
+	VanillaArchives  []string          `toml:"vanilla_archives" json:"vanilla_archives"`                   // Default value of ArchiveKey, kept when mods are added
+	VanillaFiles     []string          `toml:"vanilla_files" json:"vanilla_files"`                         // Patterns of the files the game ships in its data directory, see IsVanillaFile
+	ArchiveKeyFormat string            `toml:"archive_key_format" json:"archive_key_format"`               // One of the ArchiveKeyFormat constants
+	DeployMode       string            `toml:"deploy_mode" json:"deploy_mode"`                             // One of the DeployMode constants
+	ArchiveKeyLimit  int               `toml:"archive_key_limit" json:"archive_key_limit"`                 // Number of keys available with ArchiveKeyFormatNumbered
+	ConfigDir        string            `toml:"config_dir" json:"config_dir"`                               // Folder of ConfigFile relative to the game directory, instead of My Games
+	PluginsDir       string            `toml:"plugins_dir" json:"plugins_dir"`                             // Folder of PluginsFile relative to the game directory, instead of AppData
+	MyGamesData      bool              `toml:"my_games_data" json:"my_games_data"`                         // The game also loads loose files from Data in its My Games folder
+	RequiredIni      map[string]string `toml:"required_ini" json:"required_ini"`                           // "Section.Key" settings mods need, e.g. to enable loose files
+	Executable       string            `toml:"executable" json:"executable"`                               // Main game executable, used to recognise non-Steam installs
+	VersionExe       string            `toml:"version_executable" json:"version_executable"`               // Executable to read the game version from if it isn't Executable, relative to the game directory
+	Editions         []Edition         `toml:"editions" json:"editions"`                                   // Known game builds, oldest first
+	GOGIDs           []string          `toml:"gog_ids" json:"gog_ids"`                                     // GOG product IDs, used to match Heroic installs
+	EpicAppNames     []string          `toml:"epic_app_names" json:"epic_app_names"`                       // Epic Games Store app names, used to match Heroic installs
+	ScriptExtender   string            `toml:"script_extender" json:"script_extender"`                     // Script extender loader executable, empty if there is none
+}
+
+// Formats of the plugins file.
+const (
+	PluginsFormatPlain    = "plain"    // One plugin per line, all listed plugins are active
+	PluginsFormatAsterisk = "asterisk" // Active plugins are prefixed with '*'
+	// PluginsFormatTimestamp lists active plugins, the load order is the
+	// modification time of the plugin files, oldest first
+	PluginsFormatTimestamp = "timestamp"
+)
+
+// Ways the archive list is stored in the INI.
+const (
+	ArchiveKeyFormatList     = "list"     // A single comma separated ArchiveKey
+	ArchiveKeyFormatNumbered = "numbered" // One archive per key, ArchiveKey1 to ArchiveKeyN
+)
+
+// Ways a game can be made to load a mod's archives.
+const (
+	// ArchiveStrategyINI lists every archive in the ArchiveKey of the custom INI.
+	ArchiveStrategyINI = "ini"
+	// ArchiveStrategyPlugin relies on the game loading archives named after an
+	// active plugin, like "MyMod - Textures.bsa" for MyMod.esp, and lists only
+	// the other archives in the INI.
+	ArchiveStrategyPlugin = "plugin"
+)
+const (
+	// DeployModeLoose links every file of the active mods into the data
+	// directory, keeping the folders they are in.
+	DeployModeLoose = "loose"
+	// DeployModeArchives links only the archives of the active mods, into the
+	// root of the data directory, for games that shouldn't get loose files.
+	DeployModeArchives = "archives"
+)
+
+// pluginExts are the extensions of plugin files.
+var pluginExts = []string{".esm", ".esp", ".esl"}
+
+// GetSupportedGames returns all supported games, including the ones defined
+// by the user.
 func GetSupportedGames() []Game {
-	return []Game{
-		{
-			ID:          "fallout76",
-			Name:        "Fallout 76",
-			AppID:       "1151340",
-			NexusName:   "fallout76",
-			GameDir:     "Fallout76",
-			DataSubDir:  "Data",
-			ConfigFile:  "Fallout76Custom.ini",
-			PluginsFile: "plugins.txt",
-			ArchiveExt:  ".ba2",
-		},
-		{
-			ID:          "fallout4",
-			Name:        "Fallout 4",
-			AppID:       "377160",
-			NexusName:   "fallout4",
-			GameDir:     "Fallout 4",
-			DataSubDir:  "Data",
-			ConfigFile:  "Fallout4Custom.ini",
-			PluginsFile: "plugins.txt",
-			ArchiveExt:  ".ba2",
-		},
-		{
-			ID:          "fallout3",
-			Name:        "Fallout 3",
-			AppID:       "22300",
-			NexusName:   "fallout3",
-			GameDir:     "Fallout 3 goty",
-			DataSubDir:  "Data",
-			ConfigFile:  "Fallout.ini",
-			PluginsFile: "plugins.txt",
-			ArchiveExt:  ".bsa",
-		},
-		{
-			ID:          "falloutnv",
-			Name:        "Fallout: New Vegas",
-			AppID:       "22380",
-			NexusName:   "newvegas",
-			GameDir:     "Fallout New Vegas",
-			DataSubDir:  "Data",
-			ConfigFile:  "Fallout.ini",
-			PluginsFile: "plugins.txt",
-			ArchiveExt:  ".bsa",
-		},
-		{
-			ID:          "skyrim",
-			Name:        "The Elder Scrolls V: Skyrim",
-			AppID:       "72850",
-			NexusName:   "skyrim",
-			GameDir:     "Skyrim",
-			DataSubDir:  "Data",
-			ConfigFile:  "Skyrim.ini",
-			PluginsFile: "plugins.txt",
-			ArchiveExt:  ".bsa",
-		},
-		{
-			ID:          "skyrimse",
-			Name:        "The Elder Scrolls V: Skyrim Special Edition",
-			AppID:       "489830",
-			NexusName:   "skyrimspecialedition",
-			GameDir:     "Skyrim Special Edition",
-			DataSubDir:  "Data",
-			ConfigFile:  "Skyrim.ini",
-			PluginsFile: "plugins.txt",
-			ArchiveExt:  ".bsa",
-		},
+	defs := definitions()
+	supported := make([]Game, len(defs))
+	copy(supported, defs)
+	return supported
+}
+
+// IsArchive reports whether a file name has one of the game's archive extensions.
+func (g *Game) IsArchive(name string) bool {
+	ext := filepath.Ext(name)
+	for _, archiveExt := range g.ArchiveExts {
+		if strings.EqualFold(ext, archiveExt) {
+			return true
+		}
 	}
+	return false
 }
 
 // GetGameByID returns a game by its ID
@@ -101,35 +115,149 @@ func GetGameByID(id string) (*Game, error) {
 	return nil, fmt.Errorf("game not found: %s", id)
 }
 
-// GetGameByNexusName returns a game by its Nexus name
+// GetGameByNexusName returns a game by its Nexus name. VR editions share the
+// domain of the flat game, in which case the flat game is returned.
 func GetGameByNexusName(nexusName string) (*Game, error) {
+	matching := GetGamesByNexusName(nexusName)
+	if len(matching) == 0 {
+		return nil, fmt.Errorf("game not found for nexus name: %s", nexusName)
+	}
+	return &matching[0], nil
+}
+
+// GetGamesByNexusName returns every game whose mods are published under a Nexus name.
+func GetGamesByNexusName(nexusName string) []Game {
+	var matching []Game
 	for _, game := range GetSupportedGames() {
 		if game.NexusName == nexusName {
-			return &game, nil
+			matching = append(matching, game)
 		}
 	}
-	return nil, fmt.Errorf("game not found for nexus name: %s", nexusName)
+	return matching
 }
 
-// FindSteamRoot finds the root directory of the Steam installation
-func FindSteamRoot() (string, error) {
-	homeDir, err := os.UserHomeDir()
+// MyGamesPath returns the game's folder under Documents/My Games in a prefix.
+func (g *Game) MyGamesPath(prefixPath string) string {
+	return filepath.Join(prefix.DocumentsDir(prefixPath), "My Games", g.MyGamesDir)
+}
+
+// MyGamesDataDir returns the Data folder in My Games that games with
+// MyGamesData load loose files from, taking precedence over the install.
+func (g *Game) MyGamesDataDir(prefixPath string) string {
+	return filepath.Join(g.MyGamesPath(prefixPath), "Data")
+}
+
+// IsPlugin reports whether a file name is a plugin (.esm, .esp or .esl).
+func IsPlugin(name string) bool {
+	ext := filepath.Ext(name)
+	for _, pluginExt := range pluginExts {
+		if strings.EqualFold(ext, pluginExt) {
+			return true
+		}
+	}
+	return false
+}
+
+// ListPlugins returns the plugins in a data directory.
+func ListPlugins(dataDir string) ([]string, error) {
+	entries, err := os.ReadDir(dataDir)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	var plugins []string
+	for _, entry := range entries {
+		if !entry.IsDir() && IsPlugin(entry.Name()) {
+			plugins = append(plugins, entry.Name())
+		}
+	}
+	return plugins, nil
+}
+
+// IsVanillaFile reports whether a file in the data directory, given by its
+// slash separated relative path, ships with the game. Patterns of
+// VanillaFiles are matched case-insensitively against the path and each of
+// its parent directories, so "Video" covers everything in Data/Video.
+func (g *Game) IsVanillaFile(relPath string) bool {
+	relPath = strings.ToLower(relPath)
+	for _, archive := range g.VanillaArchives {
+		if relPath == strings.ToLower(archive) {
+			return true
+		}
+	}
+	for p := relPath; p != "." && p != "/"; p = path.Dir(p) {
+		for _, pattern := range g.VanillaFiles {
+			if matched, _ := path.Match(strings.ToLower(pattern), p); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AutoLoadsArchive reports whether the game loads an archive by itself
+// because it is named after one of the given plugins, so it doesn't need
+// to be listed in the INI.
+func (g *Game) AutoLoadsArchive(archive string, plugins []string) bool {
+	if g.ArchiveStrategy != ArchiveStrategyPlugin {
+		return false
+	}
+	base := strings.TrimSuffix(archive, filepath.Ext(archive))
+	for _, plugin := range plugins {
+		pluginBase := strings.TrimSuffix(plugin, filepath.Ext(plugin))
+		for _, suffix := range g.ArchiveSuffixes {
+			if strings.EqualFold(base, pluginBase+suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// findLibraries returns the Steam libraries of every Steam root to search for
+// the game, starting with the library libraryfolders.vdf lists the game in.
+func (g *Game) findLibraries() ([]steam.Library, error) {
+	libraries, err := steam.FindAllLibraries()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Steam libraries: %w", err)
 	}
 
-	possiblePaths := []string{
-		filepath.Join(homeDir, ".steam", "steam"),
-		filepath.Join(homeDir, ".local", "share", "Steam"),
+	return steam.OrderLibrariesForApp(libraries, g.AppID), nil
+}
+
+// MatchesShortcut reports whether a non-Steam shortcut launches this game.
+func (g *Game) MatchesShortcut(s steam.Shortcut) bool {
+	exe := filepath.Base(strings.ReplaceAll(s.Exe, `\`, "/"))
+	if g.Executable != "" && strings.EqualFold(exe, g.Executable) {
+		return true
 	}
+	return strings.EqualFold(strings.TrimSpace(s.AppName), g.Name)
+}
 
-	for _, path := range possiblePaths {
-		if _, err := os.Stat(path); err == nil {
-			return path, nil
+// findShortcuts returns the non-Steam shortcuts that launch this game.
+func (g *Game) findShortcuts() []steam.Shortcut {
+	shortcuts, err := steam.FindAllShortcuts()
+	if err != nil {
+		fmt.Printf("Skipping non-Steam shortcuts: %v\n", err)
+		return nil
+	}
+
+	var matching []steam.Shortcut
+	for _, s := range shortcuts {
+		if g.MatchesShortcut(s) {
+			matching = append(matching, s)
 		}
 	}
+	return matching
+}
 
-	return "", fmt.Errorf("Steam root directory not found")
+// launcherQuery describes the game for the non-Steam launcher backends.
+func (g *Game) launcherQuery() launchers.Query {
+	return launchers.Query{
+		Names:        []string{g.Name},
+		GOGIDs:       g.GOGIDs,
+		EpicAppNames: g.EpicAppNames,
+		Executable:   g.Executable,
+	}
 }
 
 // FindGameDir finds the game directory for a specific game
@@ -140,28 +268,92 @@ func (g *Game) FindGameDir() (string, error) {
 
 // FindGameDirWithCustomPath finds the game directory with an optional custom path
 func (g *Game) FindGameDirWithCustomPath(customPath string) (string, error) {
+	inst, err := g.FindInstallation(customPath)
+	if err != nil {
+		return "", err
+	}
+	return inst.GameDir, nil
+}
+
+// Installation describes where a game was found on disk.
+type Installation struct {
+	GameDir    string
+	Library    string             // Steam library path, empty for custom paths
+	Manifest   *steam.AppManifest // nil if the game has no app manifest
+	Launcher   string             // Non-Steam launcher the game was found in, empty for Steam
+	Command    []string           // Command that starts the game through Launcher
+	PrefixPath string             // Wine prefix reported by a non-Steam launcher
+	Shortcut   *steam.Shortcut    // Non-Steam shortcut the game was found through
+}
+
+// IsReady reports whether the installation can be safely modified.
+// Installations without an app manifest (e.g. custom paths) are assumed ready.
+func (i *Installation) IsReady() bool {
+	return i.Manifest == nil || i.Manifest.IsFullyInstalled()
+}
+
+// FindInstallation finds the game installation with an optional custom path.
+// Auto-discovery reads appmanifest_<AppID>.acf in each Steam library to get
+// the real install directory, build ID and install state, then falls back to
+// Heroic, Lutris and Bottles.
+func (g *Game) FindInstallation(customPath string) (*Installation, error) {
 	// First check if there's a custom path provided
 	if customPath != "" {
 		// Verify the custom path exists
 		if _, err := os.Stat(customPath); err == nil {
-			return customPath, nil
+			return &Installation{GameDir: customPath}, nil
 		}
 		// If custom path is set but doesn't exist, return an error
-		return "", fmt.Errorf("%s game directory not found at configured path: %s", g.Name, customPath)
+		return nil, fmt.Errorf("%s game directory not found at configured path: %s", g.Name, customPath)
 	}
 
-	// Fall back to auto-discovery
-	steamRoot, err := FindSteamRoot()
+	// Fall back to auto-discovery across all Steam libraries
+	libraries, err := g.findLibraries()
 	if err != nil {
-		return "", err
+		fmt.Printf("Skipping Steam libraries: %v\n", err)
 	}
 
-	gameDir := filepath.Join(steamRoot, "steamapps", "common", g.GameDir)
-	if _, err := os.Stat(gameDir); err == nil {
-		return gameDir, nil
+	for _, lib := range libraries {
+		manifest, err := steam.ReadAppManifest(lib.Path, g.AppID)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				fmt.Printf("Ignoring app manifest for %s in %s: %v\n", g.Name, lib.Path, err)
+			}
+			continue
+		}
+		if _, err := os.Stat(manifest.GameDir()); err == nil {
+			return &Installation{GameDir: manifest.GameDir(), Library: lib.Path, Manifest: manifest}, nil
+		}
+	}
+
+	// Libraries without a manifest may still contain a copied install
+	for _, lib := range libraries {
+		gameDir := filepath.Join(lib.SteamApps(), "common", g.GameDir)
+		if _, err := os.Stat(gameDir); err == nil {
+			return &Installation{GameDir: gameDir, Library: lib.Path}, nil
+		}
+	}
+
+	// Copies added to Steam as non-Steam shortcuts
+	for _, shortcut := range g.findShortcuts() {
+		shortcut := shortcut
+		gameDir := shortcut.StartDir
+		if gameDir == "" {
+			gameDir = filepath.Dir(shortcut.Exe)
+		}
+		if _, err := os.Stat(filepath.Join(gameDir, g.DataSubDir)); err == nil {
+			return &Installation{GameDir: gameDir, Shortcut: &shortcut}, nil
+		}
+	}
+
+	// Finally look for copies installed through other launchers
+	for _, found := range launchers.Find(g.launcherQuery()) {
+		if _, err := os.Stat(found.GameDir); err == nil {
+			return &Installation{GameDir: found.GameDir, Launcher: found.Launcher, Command: found.Command, PrefixPath: found.PrefixPath}, nil
+		}
 	}
 
-	return "", fmt.Errorf("%s game directory not found. Please set the game path in Settings", g.Name)
+	return nil, fmt.Errorf("%s game directory not found. Please set the game path in Settings", g.Name)
 }
 
 // FindDataDir finds the Data directory for a specific game
@@ -189,7 +381,10 @@ func (g *Game) FindCompatdata() (string, error) {
 	return g.FindCompatdataWithCustomPath("")
 }
 
-// FindCompatdataWithCustomPath finds the compatdata directory with an optional custom path
+// FindCompatdataWithCustomPath finds the compatdata directory with an optional custom path.
+// Otherwise the prefix belongs to the installation FindInstallation finds: the
+// compatdata of the app or shortcut for Steam, the plain Wine prefix for Heroic,
+// Lutris and Bottles.
 func (g *Game) FindCompatdataWithCustomPath(customPath string) (string, error) {
 	// First check if there's a custom path provided
 	if customPath != "" {
@@ -201,19 +396,43 @@ func (g *Game) FindCompatdataWithCustomPath(customPath string) (string, error) {
 		return "", fmt.Errorf("compatdata directory not found at configured path: %s", customPath)
 	}
 
-	// Fall back to auto-discovery
-	steamRoot, err := FindSteamRoot()
+	notFound := fmt.Errorf("compatdata directory not found for %s. Please set the compatdata path in Settings", g.Name)
+
+	// Games from other launchers use a plain Wine prefix
+	inst, instErr := g.FindInstallation("")
+	if instErr == nil && inst.Launcher != "" {
+		if inst.PrefixPath == "" {
+			return "", notFound
+		}
+		if _, err := os.Stat(inst.PrefixPath); err != nil {
+			return "", notFound
+		}
+		return inst.PrefixPath, nil
+	}
+
+	libraries, err := g.findLibraries()
 	if err != nil {
-		return "", err
+		fmt.Printf("Skipping Steam libraries: %v\n", err)
 	}
 
-	compatdataPath := filepath.Join(steamRoot, "steamapps", "compatdata", g.AppID)
-	fmt.Printf("Looking for compatdata at: %s\n", compatdataPath)
-	if _, err := os.Stat(compatdataPath); err == nil {
-		return compatdataPath, nil
+	// Non-Steam shortcuts get their own prefix under a generated app ID
+	if instErr == nil && inst.Shortcut != nil {
+		if compatdataPath, ok := steam.FindCompatdata(libraries, inst.Shortcut.CompatdataID()); ok {
+			return compatdataPath, nil
+		}
+		return "", notFound
+	}
+
+	// Steam installs, and games at a custom path that Steam may still run
+	for _, lib := range libraries {
+		compatdataPath := filepath.Join(lib.SteamApps(), "compatdata", g.AppID)
+		fmt.Printf("Looking for compatdata at: %s\n", compatdataPath)
+		if _, err := os.Stat(compatdataPath); err == nil {
+			return compatdataPath, nil
+		}
 	}
 
-	return "", fmt.Errorf("compatdata directory not found for %s. Please set the compatdata path in Settings", g.Name)
+	return "", notFound
 }
 
 // GetModsDir returns the mods directory for a specific game
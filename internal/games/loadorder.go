@@ -0,0 +1,63 @@
+package games
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// loadOrderEpoch is the modification time given to the first plugin when a
+// load order is written as timestamps. Vanilla plugins are older than this.
+var loadOrderEpoch = time.Date(2008, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// SetPluginTimestamps writes a load order for games using
+// PluginsFormatTimestamp by giving the plugins in dataDir increasing
+// modification times, one minute apart, in the given order. The game loads
+// masters before other plugins regardless of their times.
+func SetPluginTimestamps(dataDir string, plugins []string) error {
+	for i, plugin := range plugins {
+		path := filepath.Join(dataDir, plugin)
+		if _, err := os.Stat(path); err != nil {
+			if os.IsNotExist(err) {
+				continue // Listed but not installed, the game skips it too
+			}
+			return err
+		}
+		modTime := loadOrderEpoch.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			return fmt.Errorf("failed to set the load order of %s: %w", plugin, err)
+		}
+	}
+	return nil
+}
+
+// TimestampLoadOrder returns the plugins in dataDir in the order a game using
+// PluginsFormatTimestamp loads them: masters first, then by modification time.
+func TimestampLoadOrder(dataDir string) ([]string, error) {
+	plugins, err := ListPlugins(dataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	modTimes := make(map[string]time.Time, len(plugins))
+	for _, plugin := range plugins {
+		info, err := os.Stat(filepath.Join(dataDir, plugin))
+		if err != nil {
+			return nil, err
+		}
+		modTimes[plugin] = info.ModTime()
+	}
+
+	isMaster := func(name string) bool { return strings.EqualFold(filepath.Ext(name), ".esm") }
+	sort.SliceStable(plugins, func(i, j int) bool {
+		a, b := plugins[i], plugins[j]
+		if isMaster(a) != isMaster(b) {
+			return isMaster(a)
+		}
+		return modTimes[a].Before(modTimes[b])
+	})
+	return plugins, nil
+}
@@ -0,0 +1,37 @@
+package games
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTimestampLoadOrder(t *testing.T) {
+	dataDir, err := os.MkdirTemp("", "test-load-order")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dataDir)
+
+	for _, name := range []string{"Oblivion.esm", "A.esp", "B.esp", "Patch.esm", "readme.txt"} {
+		if err := os.WriteFile(filepath.Join(dataDir, name), nil, 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	order := []string{"Oblivion.esm", "B.esp", "Missing.esp", "Patch.esm", "A.esp"}
+	if err := SetPluginTimestamps(dataDir, order); err != nil {
+		t.Fatalf("Failed to set timestamps: %v", err)
+	}
+
+	loadOrder, err := TimestampLoadOrder(dataDir)
+	if err != nil {
+		t.Fatalf("Failed to read load order: %v", err)
+	}
+	// Masters always load first
+	expected := []string{"Oblivion.esm", "Patch.esm", "B.esp", "A.esp"}
+	if !reflect.DeepEqual(loadOrder, expected) {
+		t.Errorf("expected %v, got %v", expected, loadOrder)
+	}
+}
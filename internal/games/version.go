@@ -0,0 +1,241 @@
+package games
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Edition is a known build of a game, e.g. the Fallout 4 next-gen update.
+type Edition struct {
+	Name       string `toml:"name" json:"name"`
+	MinVersion string `toml:"min_version" json:"min_version"` // First executable version of the edition
+}
+
+// Version is an executable file version like 1.10.163.0.
+type Version [4]uint16
+
+// ParseVersion parses a version with up to four dot separated parts.
+func ParseVersion(s string) (Version, error) {
+	var v Version
+	parts := strings.Split(strings.TrimSpace(s), ".")
+	if len(parts) > len(v) {
+		return v, fmt.Errorf("invalid version %q", s)
+	}
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 16)
+		if err != nil {
+			return v, fmt.Errorf("invalid version %q", s)
+		}
+		v[i] = uint16(n)
+	}
+	return v, nil
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d.%d", v[0], v[1], v[2], v[3])
+}
+
+// Compare returns -1, 0 or 1 if v is older, the same or newer than other.
+func (v Version) Compare(other Version) int {
+	for i := range v {
+		if v[i] < other[i] {
+			return -1
+		}
+		if v[i] > other[i] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// Resource type and signature of the version information of a PE file.
+const (
+	rtVersion          = 16
+	fixedFileInfoMagic = 0xFEEF04BD
+)
+
+// ReadExeVersion reads the file version from the version resource of a
+// Windows executable.
+func ReadExeVersion(path string) (Version, error) {
+	f, err := pe.Open(path)
+	if err != nil {
+		return Version{}, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	defer f.Close()
+
+	// debug/pe accepts more data directories than the header has room for
+	var dirs []pe.DataDirectory
+	switch h := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		if int(h.NumberOfRvaAndSizes) > len(h.DataDirectory) {
+			return Version{}, fmt.Errorf("%s has an invalid number of data directories", filepath.Base(path))
+		}
+		dirs = h.DataDirectory[:h.NumberOfRvaAndSizes]
+	case *pe.OptionalHeader64:
+		if int(h.NumberOfRvaAndSizes) > len(h.DataDirectory) {
+			return Version{}, fmt.Errorf("%s has an invalid number of data directories", filepath.Base(path))
+		}
+		dirs = h.DataDirectory[:h.NumberOfRvaAndSizes]
+	}
+	if len(dirs) <= pe.IMAGE_DIRECTORY_ENTRY_RESOURCE || dirs[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE].Size == 0 {
+		return Version{}, fmt.Errorf("%s has no resources", filepath.Base(path))
+	}
+	rsrcRVA := dirs[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE].VirtualAddress
+
+	var section *pe.Section
+	for _, s := range f.Sections {
+		if rsrcRVA >= s.VirtualAddress && rsrcRVA < s.VirtualAddress+s.VirtualSize {
+			section = s
+			break
+		}
+	}
+	if section == nil {
+		return Version{}, fmt.Errorf("%s has no resource section", filepath.Base(path))
+	}
+	data, err := section.Data()
+	if err != nil {
+		return Version{}, fmt.Errorf("failed to read resources of %s: %w", filepath.Base(path), err)
+	}
+	// The raw data of a section can be shorter than its virtual size
+	offset := rsrcRVA - section.VirtualAddress
+	if int(offset) >= len(data) {
+		return Version{}, fmt.Errorf("%s has truncated resources", filepath.Base(path))
+	}
+	rsrc := data[offset:]
+
+	info, err := findVersionResource(rsrc, rsrcRVA)
+	if err != nil {
+		return Version{}, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return parseFixedFileInfo(info)
+}
+
+// findVersionResource walks the type, name and language levels of the
+// resource directory and returns the first version resource.
+func findVersionResource(rsrc []byte, rsrcRVA uint32) ([]byte, error) {
+	errInvalid := errors.New("invalid resource directory")
+
+	// Entries hold an ID and an offset, the high bit of which marks a subdirectory
+	entries := func(offset uint32) ([][2]uint32, error) {
+		if int(offset)+16 > len(rsrc) {
+			return nil, errInvalid
+		}
+		count := int(binary.LittleEndian.Uint16(rsrc[offset+12:])) + int(binary.LittleEndian.Uint16(rsrc[offset+14:]))
+		var result [][2]uint32
+		for i := 0; i < count; i++ {
+			pos := int(offset) + 16 + i*8
+			if pos+8 > len(rsrc) {
+				return nil, errInvalid
+			}
+			result = append(result, [2]uint32{binary.LittleEndian.Uint32(rsrc[pos:]), binary.LittleEndian.Uint32(rsrc[pos+4:])})
+		}
+		return result, nil
+	}
+
+	types, err := entries(0)
+	if err != nil {
+		return nil, err
+	}
+	offset := uint32(0)
+	for _, e := range types {
+		if e[0] == rtVersion {
+			offset = e[1]
+		}
+	}
+	if offset == 0 {
+		return nil, errors.New("no version information")
+	}
+
+	// Use the first name and language
+	for offset&0x80000000 != 0 {
+		sub, err := entries(offset &^ 0x80000000)
+		if err != nil {
+			return nil, err
+		}
+		if len(sub) == 0 {
+			return nil, errors.New("no version information")
+		}
+		offset = sub[0][1]
+	}
+
+	if int(offset)+8 > len(rsrc) {
+		return nil, errInvalid
+	}
+	dataRVA := binary.LittleEndian.Uint32(rsrc[offset:])
+	size := binary.LittleEndian.Uint32(rsrc[offset+4:])
+	start := int64(dataRVA) - int64(rsrcRVA)
+	if start < 0 || start+int64(size) > int64(len(rsrc)) {
+		return nil, errInvalid
+	}
+	return rsrc[start : start+int64(size)], nil
+}
+
+// parseFixedFileInfo reads the file version from the VS_FIXEDFILEINFO
+// structure of a version resource.
+func parseFixedFileInfo(info []byte) (Version, error) {
+	magic := make([]byte, 4)
+	binary.LittleEndian.PutUint32(magic, fixedFileInfoMagic)
+	pos := bytes.Index(info, magic)
+	if pos < 0 || pos+16 > len(info) {
+		return Version{}, errors.New("no fixed file information in the version resource")
+	}
+	ms := binary.LittleEndian.Uint32(info[pos+8:])
+	ls := binary.LittleEndian.Uint32(info[pos+12:])
+	return Version{uint16(ms >> 16), uint16(ms), uint16(ls >> 16), uint16(ls)}, nil
+}
+
+// VersionExecutablePath returns the executable the game version is read from.
+func (g *Game) VersionExecutablePath(gameDir string) string {
+	exe := g.VersionExe
+	if exe == "" {
+		exe = g.Executable
+	}
+	return filepath.Join(gameDir, filepath.FromSlash(exe))
+}
+
+// ExecutableVersion reads the version of the game installed in gameDir.
+func (g *Game) ExecutableVersion(gameDir string) (Version, error) {
+	if g.VersionExe == "" && g.Executable == "" {
+		return Version{}, fmt.Errorf("%s has no executable defined", g.Name)
+	}
+	return ReadExeVersion(g.VersionExecutablePath(gameDir))
+}
+
+// Edition returns the name of the edition an executable version belongs to,
+// or "" if the game has no known editions.
+func (g *Game) Edition(v Version) string {
+	name := ""
+	for _, e := range g.Editions {
+		min, err := ParseVersion(e.MinVersion)
+		if err == nil && v.Compare(min) >= 0 {
+			name = e.Name
+		}
+	}
+	return name
+}
+
+// BuildDescription summarises the installed build, e.g.
+// "1.10.163.0 (Old-Gen), Steam build 13154210".
+func (g *Game) BuildDescription(inst *Installation) string {
+	var parts []string
+	if v, err := g.ExecutableVersion(inst.GameDir); err == nil {
+		desc := v.String()
+		if edition := g.Edition(v); edition != "" {
+			desc += " (" + edition + ")"
+		}
+		parts = append(parts, desc)
+	}
+	if inst.Manifest != nil && inst.Manifest.BuildID != "" {
+		parts = append(parts, "Steam build "+inst.Manifest.BuildID)
+	}
+	if len(parts) == 0 {
+		return "unknown version"
+	}
+	return strings.Join(parts, ", ")
+}
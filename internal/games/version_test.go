@@ -0,0 +1,170 @@
+package games
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+)
+
+// writeTestExe writes a minimal 64-bit executable with a version resource.
+func writeTestExe(t *testing.T, path string, v Version) {
+	const rsrcRVA, rsrcOffset = 0x1000, 0x200
+
+	var rsrc bytes.Buffer
+	le := binary.LittleEndian
+	dir := func(id, offset uint32) {
+		binary.Write(&rsrc, le, [3]uint32{})
+		binary.Write(&rsrc, le, [2]uint16{0, 1})
+		binary.Write(&rsrc, le, [2]uint32{id, offset})
+	}
+	dir(rtVersion, 0x80000000|0x18)
+	dir(1, 0x80000000|0x30)
+	dir(0x409, 0x48)
+
+	var info bytes.Buffer
+	binary.Write(&info, le, [3]uint16{0, 52, 0})
+	binary.Write(&info, le, utf16.Encode([]rune("VS_VERSION_INFO\x00")))
+	binary.Write(&info, le, uint16(0))
+	binary.Write(&info, le, [13]uint32{
+		fixedFileInfoMagic, 0x10000,
+		uint32(v[0])<<16 | uint32(v[1]), uint32(v[2])<<16 | uint32(v[3]),
+	})
+	binary.Write(&rsrc, le, [4]uint32{rsrcRVA + 0x58, uint32(info.Len())})
+	rsrc.Write(info.Bytes())
+
+	var exe bytes.Buffer
+	exe.WriteString("MZ")
+	exe.Write(make([]byte, 0x3a))
+	binary.Write(&exe, le, uint32(0x40))
+	exe.WriteString("PE\x00\x00")
+	binary.Write(&exe, le, pe.FileHeader{
+		Machine:              pe.IMAGE_FILE_MACHINE_AMD64,
+		NumberOfSections:     1,
+		SizeOfOptionalHeader: uint16(binary.Size(pe.OptionalHeader64{})),
+		Characteristics:      pe.IMAGE_FILE_EXECUTABLE_IMAGE,
+	})
+	opt := pe.OptionalHeader64{Magic: 0x20b, NumberOfRvaAndSizes: 16, SectionAlignment: 0x1000, FileAlignment: 0x200}
+	opt.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE] = pe.DataDirectory{VirtualAddress: rsrcRVA, Size: uint32(rsrc.Len())}
+	binary.Write(&exe, le, opt)
+	section := pe.SectionHeader32{
+		VirtualSize:      uint32(rsrc.Len()),
+		VirtualAddress:   rsrcRVA,
+		SizeOfRawData:    uint32(rsrc.Len()),
+		PointerToRawData: rsrcOffset,
+	}
+	copy(section.Name[:], ".rsrc")
+	binary.Write(&exe, le, section)
+	exe.Write(make([]byte, rsrcOffset-exe.Len()))
+	exe.Write(rsrc.Bytes())
+
+	if err := os.WriteFile(path, exe.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write test executable: %v", err)
+	}
+}
+
+func TestReadExeVersion(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-exe-version")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	game, err := GetGameByID("fallout4")
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+
+	writeTestExe(t, filepath.Join(tmpDir, "Fallout4.exe"), Version{1, 10, 163, 0})
+	v, err := game.ExecutableVersion(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to read version: %v", err)
+	}
+	if v.String() != "1.10.163.0" {
+		t.Errorf("expected version 1.10.163.0, got %s", v)
+	}
+	if desc := game.BuildDescription(&Installation{GameDir: tmpDir}); desc != "1.10.163.0 (Old-Gen)" {
+		t.Errorf("unexpected build description %q", desc)
+	}
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "TESV.exe"), []byte("MZ not really"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := ReadExeVersion(filepath.Join(tmpDir, "TESV.exe")); err == nil {
+		t.Errorf("expected an error for an invalid executable")
+	}
+}
+
+func TestEditions(t *testing.T) {
+	tests := []struct {
+		game, version, edition string
+	}{
+		{"fallout4", "1.10.163.0", "Old-Gen"},
+		{"fallout4", "1.10.980.0", "Next-Gen"},
+		{"fallout4", "1.10.984.0", "Next-Gen"},
+		{"skyrimse", "1.5.97.0", "Special Edition 1.5"},
+		{"skyrimse", "1.6.1170.0", "Anniversary update 1.6"},
+		{"fallout76", "1.7.0.0", ""},
+	}
+	for _, tt := range tests {
+		game, err := GetGameByID(tt.game)
+		if err != nil {
+			t.Fatalf("Failed to get game: %v", err)
+		}
+		v, err := ParseVersion(tt.version)
+		if err != nil {
+			t.Fatalf("Failed to parse version: %v", err)
+		}
+		if edition := game.Edition(v); edition != tt.edition {
+			t.Errorf("%s %s: expected edition %q, got %q", tt.game, tt.version, tt.edition, edition)
+		}
+	}
+
+	if _, err := ParseVersion("1.x"); err == nil {
+		t.Errorf("expected an error for an invalid version")
+	}
+}
+
+func TestReadExeVersionMalformed(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-exe-version")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "Game.exe")
+	writeTestExe(t, path, Version{1, 0, 0, 0})
+	valid, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read test executable: %v", err)
+	}
+	optOffset := 0x40 + 4 + binary.Size(pe.FileHeader{})
+	sectionOffset := optOffset + binary.Size(pe.OptionalHeader64{})
+
+	for name, patch := range map[string]func(exe []byte) []byte{
+		"too many data directories": func(exe []byte) []byte {
+			// A 17th directory after the 16 of the header, taken from the padding before the section data
+			binary.LittleEndian.PutUint32(exe[optOffset+108:], 17)
+			binary.LittleEndian.PutUint16(exe[0x44+16:], uint16(binary.Size(pe.OptionalHeader64{})+8))
+			padded := append(append(append([]byte(nil), exe[:sectionOffset]...), make([]byte, 8)...), exe[sectionOffset:]...)
+			return append(padded[:0x1f8], padded[0x200:]...)
+		},
+		"raw data shorter than the virtual size": func(exe []byte) []byte {
+			// Resources 0x10 into the section, which has 8 bytes of raw data
+			binary.LittleEndian.PutUint32(exe[optOffset+112+8*pe.IMAGE_DIRECTORY_ENTRY_RESOURCE:], 0x1010)
+			binary.LittleEndian.PutUint32(exe[sectionOffset+16:], 8)
+			return exe
+		},
+	} {
+		exe := patch(append([]byte(nil), valid...))
+		if err := os.WriteFile(path, exe, 0644); err != nil {
+			t.Fatalf("Failed to write test executable: %v", err)
+		}
+		if _, err := ReadExeVersion(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -17,9 +17,13 @@ import (
 	"fyne.io/fyne/v2/widget"
 	"github.com/bazsalanszky/fusioncore/internal/config"
 	"github.com/bazsalanszky/fusioncore/internal/games"
+	"github.com/bazsalanszky/fusioncore/internal/launch"
 	"github.com/bazsalanszky/fusioncore/internal/mod"
+	"github.com/bazsalanszky/fusioncore/internal/modlist"
+	"github.com/bazsalanszky/fusioncore/internal/nexus"
+	"github.com/bazsalanszky/fusioncore/internal/prefix"
+	"github.com/bazsalanszky/fusioncore/internal/steam"
 	"github.com/bazsalanszky/fusioncore/internal/vfs"
-	"gopkg.in/ini.v1"
 )
 
 func newAPIKeyWindow(a fyne.App, onSave func(string)) fyne.Window {
@@ -151,12 +155,15 @@ func newModList(w fyne.Window, state *AppState) (*widget.List, []*mod.Mod) {
 			modName := widget.NewRichTextFromMarkdown("**Mod Name**")
 			modStatus := widget.NewLabel("Status")
 			modStatus.TextStyle.Italic = true
+			modDetails := widget.NewLabel("")
+			modDetails.Truncation = fyne.TextTruncateEllipsis
 
 			upBtn := widget.NewButtonWithIcon("", theme.MoveUpIcon(), nil)
 			downBtn := widget.NewButtonWithIcon("", theme.MoveDownIcon(), nil)
 			activateBtn := widget.NewButtonWithIcon("", theme.MediaPlayIcon(), nil)
 			activateBtn.Importance = widget.HighImportance
 			deactivateBtn := widget.NewButtonWithIcon("", theme.MediaPauseIcon(), nil)
+			renameBtn := widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), nil)
 			uninstallBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), nil)
 			uninstallBtn.Importance = widget.DangerImportance
 
@@ -168,12 +175,14 @@ func newModList(w fyne.Window, state *AppState) (*widget.List, []*mod.Mod) {
 				downBtn,
 				activateBtn,
 				deactivateBtn,
+				renameBtn,
 				uninstallBtn,
 			)
 
 			cardContent := container.NewVBox(
 				headerRow,
 				modStatus,
+				modDetails,
 			)
 
 			return container.NewStack(
@@ -188,6 +197,7 @@ func newModList(w fyne.Window, state *AppState) (*widget.List, []*mod.Mod) {
 			vboxContent := cardContent.Objects[0].(*fyne.Container)
 			headerRow := vboxContent.Objects[0].(*fyne.Container)
 			statusLabel := vboxContent.Objects[1].(*widget.Label)
+			detailsLabel := vboxContent.Objects[2].(*widget.Label)
 
 			statusIndicator := headerRow.Objects[0].(*canvas.Circle)
 			modName := headerRow.Objects[1].(*widget.RichText)
@@ -195,9 +205,16 @@ func newModList(w fyne.Window, state *AppState) (*widget.List, []*mod.Mod) {
 			downBtn := headerRow.Objects[4].(*widget.Button)
 			activateBtn := headerRow.Objects[5].(*widget.Button)
 			deactivateBtn := headerRow.Objects[6].(*widget.Button)
-			uninstallBtn := headerRow.Objects[7].(*widget.Button)
+			renameBtn := headerRow.Objects[7].(*widget.Button)
+			uninstallBtn := headerRow.Objects[8].(*widget.Button)
 
 			modName.ParseMarkdown(fmt.Sprintf("**%s**", m.Name))
+			if details := modDetails(m); details != "" {
+				detailsLabel.SetText(details)
+				detailsLabel.Show()
+			} else {
+				detailsLabel.Hide()
+			}
 
 			if m.Active {
 				statusIndicator.FillColor = color.NRGBA{R: 76, G: 175, B: 80, A: 255} // Green
@@ -213,12 +230,11 @@ func newModList(w fyne.Window, state *AppState) (*widget.List, []*mod.Mod) {
 			// Move up button
 			upBtn.OnTapped = func() {
 				if i > 0 {
-					state.mods[i], state.mods[i-1] = state.mods[i-1], state.mods[i]
-					if err := mod.SaveMods(state.mods, state.currentGame.ID); err != nil {
+					if err := moveMod(state, m.ID, -1); err != nil {
 						showErrorDialog(err, w)
 						return
 					}
-					if err := updateLoadOrder(state.mods, state.currentGame); err != nil {
+					if err := vfs.UpdateLoadOrder(state.mods, state.currentGame); err != nil {
 						showErrorDialog(err, w)
 					}
 					modList.Refresh()
@@ -228,12 +244,11 @@ func newModList(w fyne.Window, state *AppState) (*widget.List, []*mod.Mod) {
 			// Move down button
 			downBtn.OnTapped = func() {
 				if i < len(state.mods)-1 {
-					state.mods[i], state.mods[i+1] = state.mods[i+1], state.mods[i]
-					if err := mod.SaveMods(state.mods, state.currentGame.ID); err != nil {
+					if err := moveMod(state, m.ID, 1); err != nil {
 						showErrorDialog(err, w)
 						return
 					}
-					if err := updateLoadOrder(state.mods, state.currentGame); err != nil {
+					if err := vfs.UpdateLoadOrder(state.mods, state.currentGame); err != nil {
 						showErrorDialog(err, w)
 					}
 					modList.Refresh()
@@ -251,7 +266,7 @@ func newModList(w fyne.Window, state *AppState) (*widget.List, []*mod.Mod) {
 			}
 
 			activateBtn.OnTapped = func() {
-				if err := vfs.Activate(m.Name); err != nil {
+				if err := vfs.Activate(m.ID); err != nil {
 					showErrorDialog(err, w)
 					return
 				}
@@ -259,13 +274,28 @@ func newModList(w fyne.Window, state *AppState) (*widget.List, []*mod.Mod) {
 				modList.Refresh()
 			}
 			deactivateBtn.OnTapped = func() {
-				if err := vfs.Deactivate(m.Name); err != nil {
+				if err := vfs.Deactivate(m.ID); err != nil {
 					showErrorDialog(err, w)
 					return
 				}
 				state.mods, _ = mod.LoadMods(state.currentGame.ID)
 				modList.Refresh()
 			}
+			renameBtn.OnTapped = func() {
+				entry := widget.NewEntry()
+				entry.SetText(m.Name)
+				dialog.ShowCustomConfirm("Rename Mod", "Rename", "Cancel", entry, func(confirm bool) {
+					if !confirm {
+						return
+					}
+					if _, err := mod.Rename(state.currentGame.ID, m.ID, entry.Text); err != nil {
+						showErrorDialog(err, w)
+						return
+					}
+					state.mods, _ = mod.LoadMods(state.currentGame.ID)
+					modList.Refresh()
+				}, w)
+			}
 			uninstallBtn.OnTapped = func() {
 				dialog.ShowConfirm("Uninstall Mod", "Are you sure you want to uninstall "+m.Name+"?", func(confirm bool) {
 					if !confirm {
@@ -273,7 +303,7 @@ func newModList(w fyne.Window, state *AppState) (*widget.List, []*mod.Mod) {
 					}
 
 					if m.Active {
-						if err := vfs.Deactivate(m.Name); err != nil {
+						if err := vfs.Deactivate(m.ID); err != nil {
 							showErrorDialog(err, w)
 						}
 					}
@@ -282,14 +312,9 @@ func newModList(w fyne.Window, state *AppState) (*widget.List, []*mod.Mod) {
 						showErrorDialog(err, w)
 					}
 
-					var newMods []*mod.Mod
-					for _, modEntry := range state.mods {
-						if modEntry.Name != m.Name {
-							newMods = append(newMods, modEntry)
-						}
+					if err := removeMod(state, m.ID); err != nil {
+						showErrorDialog(err, w)
 					}
-					mod.SaveMods(newMods, state.currentGame.ID)
-					state.mods = newMods
 					modList.Refresh()
 				}, w)
 			}
@@ -307,81 +332,385 @@ func newModList(w fyne.Window, state *AppState) (*widget.List, []*mod.Mod) {
 	return modList, state.mods
 }
 
-func newHeader(w fyne.Window) fyne.CanvasObject {
+// moveMod moves a mod up (-1) or down (1) in the load order. The change is
+// applied to the stored list, which may have been changed by the CLI.
+func moveMod(state *AppState, id string, delta int) error {
+	mods, err := mod.UpdateMods(state.currentGame.ID, func(mods []*mod.Mod) ([]*mod.Mod, error) {
+		for i, m := range mods {
+			if m.ID == id {
+				if j := i + delta; j >= 0 && j < len(mods) {
+					mods[i], mods[j] = mods[j], mods[i]
+				}
+				break
+			}
+		}
+		return mods, nil
+	})
+	if err != nil {
+		return err
+	}
+	state.mods = mods
+	return nil
+}
+
+// removeMod removes a mod from the stored mod list of the current game.
+func removeMod(state *AppState, id string) error {
+	var removed []*mod.Mod
+	mods, err := mod.UpdateMods(state.currentGame.ID, func(mods []*mod.Mod) ([]*mod.Mod, error) {
+		var kept []*mod.Mod
+		for _, m := range mods {
+			if m.ID != id {
+				kept = append(kept, m)
+			} else {
+				removed = append(removed, m)
+			}
+		}
+		return kept, nil
+	})
+	if err != nil {
+		return err
+	}
+	state.mods = mods
+	for _, m := range removed {
+		if err := mod.DeleteManifest(state.currentGame.ID, m); err != nil {
+			fmt.Printf("Failed to delete the manifest of %s: %v\n", m.Name, err)
+		}
+	}
+	return nil
+}
+
+// addMod adds a newly installed mod to the stored mod list of the current
+// game and records the manifest of its files.
+func addMod(state *AppState, newMod *mod.Mod) error {
+	mods, err := mod.Add(state.currentGame.ID, newMod)
+	if err != nil {
+		return err
+	}
+	state.mods = mods
+	return nil
+}
+
+// showImportExistingDialog lists the files copied into the data directory by
+// hand and offers to move them into new mods.
+func showImportExistingDialog(w fyne.Window, state *AppState, modList *widget.List) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		showErrorDialog(err, w)
+		return
+	}
+	game := state.currentGame
+	dataDir, err := game.FindDataDirWithCustomPath(cfg.GamePaths[game.ID])
+	if err != nil {
+		showErrorDialog(err, w)
+		return
+	}
+	found, kept, err := vfs.ScanUnmanaged(game, dataDir)
+	if err != nil {
+		showErrorDialog(err, w)
+		return
+	}
+	if len(found) == 0 {
+		message := "There are no unmanaged files in " + dataDir + "."
+		if len(kept) > 0 {
+			message = fmt.Sprintf("There are no unmanaged archives in %s. %d other files stay where they are, %s only gets archives deployed.", dataDir, len(kept), game.Name)
+		}
+		dialog.ShowInformation("Import Existing Files", message, w)
+		return
+	}
+
+	var lines []string
+	for _, group := range found {
+		lines = append(lines, fmt.Sprintf("%s (%d files)", group.Name, len(group.Files)))
+		for _, file := range group.Files {
+			lines = append(lines, "    "+file)
+		}
+	}
+	if len(kept) > 0 {
+		lines = append(lines, fmt.Sprintf("Staying in place, %s only gets archives deployed (%d files)", game.Name, len(kept)))
+		for _, file := range kept {
+			lines = append(lines, "    "+file)
+		}
+	}
+	list := widget.NewLabel(strings.Join(lines, "\n"))
+	scroll := container.NewVScroll(list)
+	scroll.SetMinSize(fyne.NewSize(500, 300))
+	content := container.NewBorder(widget.NewLabel("Move these files into new mods?"), nil, nil, nil, scroll)
+
+	dialog.ShowCustomConfirm("Import Existing Files", "Import", "Cancel", content, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		adopted, adoptErr := vfs.AdoptUnmanaged(game, dataDir, found)
+		if mods, err := mod.LoadMods(game.ID); err == nil {
+			state.mods = mods
+			modList.Refresh()
+		}
+		// The groups adopted before a failure are out of Data already, so
+		// they are deployed either way
+		err := vfs.DeployImported(adopted)
+		if adoptErr != nil {
+			showErrorDialog(adoptErr, w)
+			return
+		}
+		if err != nil {
+			showErrorDialog(err, w)
+		}
+	}, w)
+}
+
+// showCollectionDialog lets the user pick the optional mods of a collection
+// revision and installs it.
+func showCollectionDialog(collection *nexus.Collection, apiKey string, progressBar *widget.ProgressBar, modList *widget.List, w fyne.Window, state *AppState) {
+	var lines, optional []string
+	for _, cm := range collection.Mods {
+		switch {
+		case cm.Optional:
+			optional = append(optional, cm.Name)
+		case cm.Source != "nexus":
+			lines = append(lines, fmt.Sprintf("%s (install by hand from %s)", cm.Name, cm.URL))
+		default:
+			lines = append(lines, fmt.Sprintf("%s %s", cm.Name, cm.Version))
+		}
+	}
+	list := widget.NewLabel(strings.Join(lines, "\n"))
+	items := container.NewVBox(list)
+	var optionalGroup *widget.CheckGroup
+	if len(optional) > 0 {
+		optionalGroup = widget.NewCheckGroup(optional, nil)
+		items.Add(widget.NewLabel("Optional mods:"))
+		items.Add(optionalGroup)
+	}
+	scroll := container.NewVScroll(items)
+	scroll.SetMinSize(fyne.NewSize(500, 300))
+	title := fmt.Sprintf("Install %s (revision %d)?", collection.Name, collection.Revision)
+	content := container.NewBorder(widget.NewLabel(title), nil, nil, nil, scroll)
+
+	dialog.ShowCustomConfirm("Install Collection", "Install", "Cancel", content, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		selected := make(map[string]bool)
+		if optionalGroup != nil {
+			for _, name := range optionalGroup.Selected {
+				selected[name] = true
+			}
+		}
+		game := state.currentGame
+		go func() {
+			fyne.Do(progressBar.Show)
+			defer fyne.Do(progressBar.Hide)
+			installErr := modlist.InstallCollection(collection, game, apiKey, func(cm *nexus.CollectionMod) bool {
+				return selected[cm.Name]
+			})
+			if mods, err := mod.LoadMods(game.ID); err == nil {
+				fyne.Do(func() {
+					if state.currentGame.ID == game.ID {
+						state.mods = mods
+						modList.Refresh()
+					}
+				})
+			}
+			fyne.Do(func() {
+				if installErr != nil {
+					showErrorDialog(installErr, w)
+					return
+				}
+				dialog.ShowInformation("Install Collection", collection.Name+" is installed.", w)
+			})
+		}()
+	}, w)
+}
+
+// showConflictsDialog lists the files several active mods provide and which
+// mod wins each, optionally only those of one mod.
+func showConflictsDialog(w fyne.Window, state *AppState) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		showErrorDialog(err, w)
+		return
+	}
+	game := state.currentGame
+	dataDir, err := game.FindDataDirWithCustomPath(cfg.GamePaths[game.ID])
+	if err != nil {
+		showErrorDialog(err, w)
+		return
+	}
+	conflicts, err := vfs.FindConflicts(state.mods, game, dataDir)
+	if err != nil {
+		showErrorDialog(err, w)
+		return
+	}
+	if len(conflicts) == 0 {
+		dialog.ShowInformation("File Conflicts", "No conflicts between the active mods or with files in Data.", w)
+		return
+	}
+
+	const allMods = "All mods"
+	shown := conflicts
+	list := widget.NewList(
+		func() int { return len(shown) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			c := shown[i]
+			o.(*widget.Label).SetText(fmt.Sprintf("%s: %s wins over %s", filepath.ToSlash(c.Path), c.Winner.Name, strings.Join(c.LoserNames(), ", ")))
+		},
+	)
+
+	options := []string{allMods}
+	seen := make(map[string]bool)
+	for _, m := range state.mods {
+		for _, c := range conflicts {
+			if !seen[m.ID] && c.Involves(m) {
+				options = append(options, m.Name)
+				seen[m.ID] = true
+			}
+		}
+	}
+	filter := widget.NewSelect(options, func(name string) {
+		shown = conflicts
+		if name != allMods {
+			shown = nil
+			for _, m := range state.mods {
+				if m.Name != name {
+					continue
+				}
+				for _, c := range conflicts {
+					if c.Involves(m) {
+						shown = append(shown, c)
+					}
+				}
+				break
+			}
+		}
+		list.Refresh()
+	})
+	filter.SetSelected(allMods)
+
+	hint := widget.NewLabel(fmt.Sprintf("%d conflicting files. Move a mod down in the list to make it win.", len(conflicts)))
+	content := container.NewBorder(container.NewVBox(hint, filter), nil, nil, nil, list)
+	d := dialog.NewCustom("File Conflicts", "Close", content, w)
+	d.Resize(fyne.NewSize(700, 500))
+	d.Show()
+}
+
+// modDetails describes a mod from its Nexus Mods metadata, e.g.
+// "Version 1.2 (installed 1.1) by Author · Weapons · Adds new guns".
+func modDetails(m *mod.Mod) string {
+	md := m.Metadata
+	if md == nil {
+		return ""
+	}
+	var parts []string
+	if md.Version != "" {
+		version := "Version " + md.Version
+		if md.FileVersion != "" && md.FileVersion != md.Version {
+			version += " (installed " + md.FileVersion + ")"
+		}
+		if md.Author != "" {
+			version += " by " + md.Author
+		}
+		parts = append(parts, version)
+	}
+	if md.Category != "" {
+		parts = append(parts, md.Category)
+	}
+	if md.Summary != "" {
+		parts = append(parts, md.Summary)
+	}
+	return strings.Join(parts, " · ")
+}
+
+func newHeader(w fyne.Window, state *AppState) (fyne.CanvasObject, *widget.Label) {
 	title := widget.NewRichTextFromMarkdown("# Fusion Core ☢️")
 	subtitle := widget.NewLabel("Powering Fallout Mods on Linux")
 	subtitle.TextStyle.Italic = true
+	gameInfo := widget.NewLabel("")
+	updateGameInfo(gameInfo, state)
 
 	header := container.NewVBox(
 		title,
 		subtitle,
+		gameInfo,
 	)
 
-	return container.NewPadded(header)
+	return container.NewPadded(header), gameInfo
 }
 
-func newStatusBar(w fyne.Window) (*widget.Label, *widget.Button) {
+// updateGameInfo shows the installed version of the current game, which is
+// read in the background because it means opening the game executable. The
+// result is dropped if another game was picked in the meantime.
+func updateGameInfo(label *widget.Label, state *AppState) {
+	game := state.currentGame
+	label.SetText(game.Name)
+	go func() {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return
+		}
+		text := game.Name + ": not found"
+		if inst, err := game.FindInstallation(cfg.GamePaths[game.ID]); err == nil {
+			text = game.Name + ": " + game.BuildDescription(inst)
+		}
+		fyne.Do(func() {
+			if state.currentGame.ID == game.ID {
+				label.SetText(text)
+			}
+		})
+	}()
+}
+
+func newStatusBar(w fyne.Window, state *AppState) (*widget.Label, *widget.Button, *widget.Button) {
 	usernameLabel := widget.NewLabel("Fetching username...")
 	usernameLabel.TextStyle.Monospace = true
 
 	launchButton := widget.NewButtonWithIcon("Launch Game", theme.MediaPlayIcon(), nil)
 	launchButton.Importance = widget.HighImportance
 
-	return usernameLabel, launchButton
-}
+	shortcutButton := widget.NewButtonWithIcon("Add to Steam", theme.ContentAddIcon(), func() {
+		showCreateShortcutDialog(w, state)
+	})
 
-// updateLoadOrder updates the INI file with the current mod order
-func updateLoadOrder(mods []*mod.Mod, game *games.Game) error {
-	var archives []string
-	for _, m := range mods {
-		if m.Active {
-			archiveFiles, err := findArchiveFiles(m.Path, game.ArchiveExt)
-			if err != nil {
-				return err
-			}
-			archives = append(archives, archiveFiles...)
-		}
-	}
-	return setArchiveList(archives, game)
+	return usernameLabel, launchButton, shortcutButton
 }
 
-// findArchiveFiles finds all archive files with the given extension in a directory
-func findArchiveFiles(dir, ext string) ([]string, error) {
-	var archiveFiles []string
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() && filepath.Ext(path) == ext {
-			archiveFiles = append(archiveFiles, info.Name())
-		}
-		return nil
-	})
-	return archiveFiles, err
-}
+// showCreateShortcutDialog asks how the modded launch shortcut should start
+// the current game and adds it to Steam.
+func showCreateShortcutDialog(w fyne.Window, state *AppState) {
+	game := state.currentGame
 
-// setArchiveList sets the complete archive list in the INI file
-func setArchiveList(archives []string, game *games.Game) error {
-	prefixPath, err := game.FindCompatdata()
-	if err != nil {
-		return err
+	loaderCheck := widget.NewCheck("Launch the script extender directly", nil)
+	if game.ScriptExtender == "" {
+		loaderCheck.Disable()
 	}
+	protonEntry := widget.NewEntry()
+	protonEntry.SetText(steam.DefaultCompatTool)
 
-	iniPath := config.GetCustomIniPath(prefixPath, game.ConfigFile)
-	cfg, err := ini.Load(iniPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			cfg = ini.Empty()
-		} else {
-			return fmt.Errorf("failed to load %s: %w", game.ConfigFile, err)
-		}
-	}
+	info := widget.NewLabel(fmt.Sprintf("Adds \"%s\" to your Steam library, so you can start the modded game from Big Picture or Game Mode. Steam must be closed.", launch.ShortcutName(game)))
+	info.Wrapping = fyne.TextWrapWord
 
-	section := cfg.Section("Archive")
-	key := section.Key("sResourceArchive2List")
-	key.SetValue(strings.Join(archives, ", "))
+	form := container.NewVBox(
+		info,
+		loaderCheck,
+		widget.NewLabel("Proton version (for the script extender):"),
+		protonEntry,
+	)
 
-	return cfg.SaveTo(iniPath)
+	d := dialog.NewCustomConfirm("Add to Steam", "Add", "Cancel", form, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		shortcut, err := launch.CreateShortcut(game, launch.ShortcutOptions{
+			UseLoader:  loaderCheck.Checked,
+			CompatTool: protonEntry.Text,
+		})
+		if err != nil {
+			showErrorDialog(err, w)
+			return
+		}
+		dialog.ShowInformation("Add to Steam", fmt.Sprintf("%s was added to Steam. Start Steam to see it in your library.", shortcut.AppName), w)
+	}, w)
+	d.Resize(fyne.NewSize(450, 250))
+	d.Show()
 }
 
 func newSettingsWindow(a fyne.App, w fyne.Window, state *AppState) fyne.Window {
@@ -443,7 +772,10 @@ func newSettingsWindow(a fyne.App, w fyne.Window, state *AppState) fyne.Window {
 
 				// Save the custom path
 				cfg.GamePaths[game.ID] = selectedPath
-				if err := config.SaveConfig(cfg); err != nil {
+				if _, err := config.UpdateConfig(func(c *config.Config) error {
+					c.GamePaths[game.ID] = selectedPath
+					return nil
+				}); err != nil {
 					showErrorDialog(err, settingsWindow)
 					return
 				}
@@ -456,7 +788,10 @@ func newSettingsWindow(a fyne.App, w fyne.Window, state *AppState) fyne.Window {
 		gameClearButton := widget.NewButton("Clear", func() {
 			if cfg.GamePaths != nil {
 				delete(cfg.GamePaths, game.ID)
-				if err := config.SaveConfig(cfg); err != nil {
+				if _, err := config.UpdateConfig(func(c *config.Config) error {
+					delete(c.GamePaths, game.ID)
+					return nil
+				}); err != nil {
 					showErrorDialog(err, settingsWindow)
 					return
 				}
@@ -507,16 +842,18 @@ func newSettingsWindow(a fyne.App, w fyne.Window, state *AppState) fyne.Window {
 
 				selectedPath := uri.Path()
 
-				// Verify this looks like a valid compatdata directory (should have pfx subdirectory)
-				pfxDir := filepath.Join(selectedPath, "pfx")
-				if _, err := os.Stat(pfxDir); err != nil {
-					dialog.ShowError(fmt.Errorf("Selected directory does not contain a 'pfx' subdirectory. Please select the compatdata directory (e.g., steamapps/compatdata/%s)", game.AppID), settingsWindow)
+				// Verify this looks like a valid compatdata directory (pfx subdirectory) or a plain Wine prefix (drive_c)
+				if _, err := os.Stat(prefix.DriveC(selectedPath)); err != nil {
+					dialog.ShowError(fmt.Errorf("Selected directory is not a Proton or Wine prefix. Please select the compatdata directory (e.g., steamapps/compatdata/%s) or a Wine prefix containing 'drive_c'", game.AppID), settingsWindow)
 					return
 				}
 
 				// Save the custom path
 				cfg.CompatdataPaths[game.ID] = selectedPath
-				if err := config.SaveConfig(cfg); err != nil {
+				if _, err := config.UpdateConfig(func(c *config.Config) error {
+					c.CompatdataPaths[game.ID] = selectedPath
+					return nil
+				}); err != nil {
 					showErrorDialog(err, settingsWindow)
 					return
 				}
@@ -529,7 +866,10 @@ func newSettingsWindow(a fyne.App, w fyne.Window, state *AppState) fyne.Window {
 		compatdataClearButton := widget.NewButton("Clear", func() {
 			if cfg.CompatdataPaths != nil {
 				delete(cfg.CompatdataPaths, game.ID)
-				if err := config.SaveConfig(cfg); err != nil {
+				if _, err := config.UpdateConfig(func(c *config.Config) error {
+					delete(c.CompatdataPaths, game.ID)
+					return nil
+				}); err != nil {
 					showErrorDialog(err, settingsWindow)
 					return
 				}
@@ -558,7 +898,7 @@ func newSettingsWindow(a fyne.App, w fyne.Window, state *AppState) fyne.Window {
 	gameInfoText.Wrapping = fyne.TextWrapWord
 
 	compatdataHeader := widget.NewRichTextFromMarkdown("### Proton Prefix Paths (compatdata)")
-	compatdataInfoText := widget.NewLabel("Configure the Proton prefix directory (usually in steamapps/compatdata/[AppID]).")
+	compatdataInfoText := widget.NewLabel("Configure the Proton prefix directory (usually in steamapps/compatdata/[AppID]) or the Wine prefix used by Heroic, Lutris or Bottles.")
 	compatdataInfoText.Wrapping = fyne.TextWrapWord
 
 	closeButton := widget.NewButton("Close", func() {
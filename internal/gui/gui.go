@@ -17,7 +17,6 @@ import (
 	"fyne.io/fyne/v2/widget"
 	"github.com/bazsalanszky/fusioncore/assets"
 	"github.com/bazsalanszky/fusioncore/internal/config"
-	"github.com/bazsalanszky/fusioncore/internal/extractor"
 	"github.com/bazsalanszky/fusioncore/internal/games"
 	"github.com/bazsalanszky/fusioncore/internal/instance"
 	"github.com/bazsalanszky/fusioncore/internal/mod"
@@ -31,8 +30,8 @@ type AppState struct {
 }
 
 func buildUI(a fyne.App, w fyne.Window, state *AppState) (fyne.CanvasObject, *widget.ProgressBar, *widget.Label, *widget.Button, *widget.List) {
-	header := newHeader(w)
-	usernameLabel, launchButton := newStatusBar(w)
+	header, gameInfoLabel := newHeader(w, state)
+	usernameLabel, launchButton, shortcutButton := newStatusBar(w, state)
 	modList, _ := newModList(w, state)
 
 	progressBar := widget.NewProgressBar()
@@ -47,6 +46,7 @@ func buildUI(a fyne.App, w fyne.Window, state *AppState) (fyne.CanvasObject, *wi
 				container.NewHBox(
 					layout.NewSpacer(),
 					usernameLabel,
+					shortcutButton,
 					launchButton,
 				),
 				progressBar,
@@ -78,7 +78,7 @@ func buildUI(a fyne.App, w fyne.Window, state *AppState) (fyne.CanvasObject, *wi
 
 				fileName := reader.URI().Name()
 				modName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
-				modDir := filepath.Join(modsDir, modName)
+				modDir := mod.UniqueDir(filepath.Join(modsDir, modName))
 
 				if err := os.MkdirAll(modDir, 0755); err != nil {
 					showErrorDialog(err, w)
@@ -98,24 +98,19 @@ func buildUI(a fyne.App, w fyne.Window, state *AppState) (fyne.CanvasObject, *wi
 					return
 				}
 
-				newMod := &mod.Mod{
-					Name:   modName,
-					Path:   modDir,
-					Active: false,
-					ModID:  "local",
-					FileID: "local",
-					Game:   state.currentGame.ID,
-				}
-				state.mods = append(state.mods, newMod)
-				if err := mod.SaveMods(state.mods, state.currentGame.ID); err != nil {
+				newMod := mod.New(modName, modDir, "local", "local", state.currentGame.ID)
+				if err := addMod(state, newMod); err != nil {
 					showErrorDialog(err, w)
 					return
 				}
 				modList.Refresh()
 			}, w)
-			fd.SetFilter(storage.NewExtensionFileFilter([]string{state.currentGame.ArchiveExt}))
+			fd.SetFilter(storage.NewExtensionFileFilter(state.currentGame.ArchiveExts))
 			fd.Show()
 		}),
+		fyne.NewMenuItem("Import existing files from Data", func() {
+			showImportExistingDialog(w, state, modList)
+		}),
 		fyne.NewMenuItem("Load from URL (nxm://)", func() {
 			entry := widget.NewEntry()
 			dialog.ShowCustomConfirm("Load from URL", "Load", "Cancel", entry, func(confirm bool) {
@@ -124,6 +119,9 @@ func buildUI(a fyne.App, w fyne.Window, state *AppState) (fyne.CanvasObject, *wi
 				}
 			}, w)
 		}),
+		fyne.NewMenuItem("Show file conflicts", func() {
+			showConflictsDialog(w, state)
+		}),
 		fyne.NewMenuItemSeparator(),
 		fyne.NewMenuItem("Settings", func() {
 			settingsWin := newSettingsWindow(a, w, state)
@@ -133,21 +131,20 @@ func buildUI(a fyne.App, w fyne.Window, state *AppState) (fyne.CanvasObject, *wi
 
 	accountMenu := fyne.NewMenu("Account",
 		fyne.NewMenuItem("Switch Account", func() {
-			cfg, err := config.LoadConfig()
+			_, err := config.UpdateConfig(func(cfg *config.Config) error {
+				cfg.APIKey = ""
+				return nil
+			})
 			if err != nil {
 				showErrorDialog(err, w)
 				return
 			}
-			cfg.APIKey = ""
-			if err := config.SaveConfig(cfg); err != nil {
-				showErrorDialog(err, w)
-				return
-			}
 			dialog.ShowInformation("Switch Account", "Please restart the application to switch accounts.", w)
 		}),
 	)
 
 	var gameMenuItems []*fyne.MenuItem
+	var gameMenuIDs []string
 	for _, game := range games.GetSupportedGames() {
 		game := game // capture loop variable
 		var menuItem *fyne.MenuItem
@@ -155,17 +152,16 @@ func buildUI(a fyne.App, w fyne.Window, state *AppState) (fyne.CanvasObject, *wi
 			if game.ID == state.currentGame.ID {
 				return // Already active
 			}
-			cfg, err := config.LoadConfig()
+			_, err := config.UpdateConfig(func(cfg *config.Config) error {
+				cfg.CurrentGame = game.ID
+				return nil
+			})
 			if err != nil {
 				showErrorDialog(err, w)
 				return
 			}
-			cfg.CurrentGame = game.ID
-			if err := config.SaveConfig(cfg); err != nil {
-				showErrorDialog(err, w)
-				return
-			}
 			state.currentGame = &game
+			updateGameInfo(gameInfoLabel, state)
 			mods, err := mod.LoadMods(game.ID)
 			if err != nil {
 				showErrorDialog(err, w)
@@ -183,116 +179,141 @@ func buildUI(a fyne.App, w fyne.Window, state *AppState) (fyne.CanvasObject, *wi
 			menuItem.Checked = true
 		}
 		gameMenuItems = append(gameMenuItems, menuItem)
+		gameMenuIDs = append(gameMenuIDs, game.ID)
 	}
 	gamesMenu := fyne.NewMenu("Games", gameMenuItems...)
 
 	mainMenu := fyne.NewMainMenu(fileMenu, accountMenu, gamesMenu)
 	w.SetMainMenu(mainMenu)
 
+	// Pick up changes the CLI makes while the GUI is open
+	err := watchStore(state, func(gameChanged bool) {
+		if gameChanged {
+			updateGameInfo(gameInfoLabel, state)
+			for i, item := range gameMenuItems {
+				item.Checked = gameMenuIDs[i] == state.currentGame.ID
+			}
+			mainMenu.Refresh()
+		}
+		modList.Refresh()
+	})
+	if err != nil {
+		fmt.Printf("Not watching for changes made outside the GUI: %v\n", err)
+	}
+
 	return content, progressBar, usernameLabel, launchButton, modList
 }
 
+// handleDownload installs the mod or collection of an nxm URL. It runs on
+// its own goroutine, so the state and widgets are only touched through
+// fyne.Do.
 func handleDownload(nxmURL string, progressBar *widget.ProgressBar, modList *widget.List, w fyne.Window, state *AppState) {
-	progressBar.Show()
-	defer progressBar.Hide()
+	fyne.Do(progressBar.Show)
+	defer fyne.Do(progressBar.Hide)
+	showError := func(err error) {
+		fyne.Do(func() { showErrorDialog(err, w) })
+	}
 
 	info, err := nexus.ParseNxmURL(nxmURL)
 	if err != nil {
-		showErrorDialog(err, w)
+		showError(err)
 		return
 	}
 
+	var game *games.Game
+	var mods []*mod.Mod
+	fyne.DoAndWait(func() { game, mods = state.currentGame, state.mods })
+
 	// Validate game compatibility
-	if info.Game != state.currentGame.NexusName {
-		dialog.ShowError(fmt.Errorf("mod is for %s but current game is %s", info.Game, state.currentGame.Name), w)
+	if info.Game != game.NexusName {
+		showError(fmt.Errorf("mod is for %s but current game is %s", info.Game, game.Name))
 		return
 	}
 
-	for i, m := range state.mods {
+	apiKey, err := nexusAPIKey()
+	if err != nil {
+		showError(err)
+		return
+	}
+
+	if info.IsCollection() {
+		collection, err := nexus.GetCollection(info, apiKey)
+		if err != nil {
+			showError(err)
+			return
+		}
+		fyne.Do(func() { showCollectionDialog(collection, apiKey, progressBar, modList, w, state) })
+		return
+	}
+
+	for _, m := range mods {
 		if m.ModID == info.ModID {
 			if m.FileID == info.FileID {
-				dialog.ShowInformation("Mod already installed", "This mod is already installed.", w)
+				fyne.Do(func() { dialog.ShowInformation("Mod already installed", "This mod is already installed.", w) })
 				return
 			}
 
 			// A different version of the same mod is already installed, so we ask the user if they want to update.
-			dialog.ShowConfirm("Update mod?", fmt.Sprintf("A different version of %s is already installed. Do you want to update?", m.Name), func(ok bool) {
-				if !ok {
-					return
-				}
+			fyne.Do(func() {
+				dialog.ShowConfirm("Update mod?", fmt.Sprintf("A different version of %s is already installed. Do you want to update?", m.Name), func(ok bool) {
+					if !ok {
+						return
+					}
 
-				if err := vfs.Deactivate(m.Name); err != nil {
-					showErrorDialog(err, w)
-					return
-				}
-				if err := os.RemoveAll(m.Path); err != nil {
-					showErrorDialog(err, w)
-					return
-				}
-				state.mods = append(state.mods[:i], state.mods[i+1:]...)
-			}, w)
-		}
-	}
-	apiKey := os.Getenv("NEXUS_API_KEY")
-	if apiKey == "" {
-		cfg, err := config.LoadConfig()
-		if err != nil {
-			showErrorDialog(err, w)
-			return
+					if err := vfs.Deactivate(m.ID); err != nil {
+						showErrorDialog(err, w)
+						return
+					}
+					if err := os.RemoveAll(m.Path); err != nil {
+						showErrorDialog(err, w)
+						return
+					}
+					if err := removeMod(state, m.ID); err != nil {
+						showErrorDialog(err, w)
+						return
+					}
+					modList.Refresh()
+				}, w)
+			})
 		}
-		apiKey = cfg.APIKey
 	}
-
 	if _, err := nexus.ValidateAPIKey(apiKey); err != nil {
-		showErrorDialog(err, w)
+		showError(err)
 		return
 	}
 
-	downloadURL, err := nexus.GetDownloadURL(info, apiKey)
+	newMod, err := nexus.Install(info, game, apiKey, func(progress float64) {
+		fyne.Do(func() { progressBar.SetValue(progress) })
+	})
 	if err != nil {
-		showErrorDialog(err, w)
+		showError(err)
 		return
 	}
-
-	destDir, err := state.currentGame.GetModsDir()
+	// The game may have been switched during the download
+	mods, err = mod.Add(game.ID, newMod)
 	if err != nil {
-		showErrorDialog(err, w)
+		showError(err)
 		return
 	}
-
-	filePath, err := nexus.DownloadFile(downloadURL, destDir, func(progress float64) {
-		progressBar.SetValue(progress)
+	fyne.Do(func() {
+		if state.currentGame.ID == game.ID {
+			state.mods = mods
+			modList.Refresh()
+		}
 	})
-	if err != nil {
-		showErrorDialog(err, w)
-		return
-	}
-
-	extractDir := strings.TrimSuffix(filePath, filepath.Ext(filePath))
-	if err := extractor.Extract(filePath, extractDir); err != nil {
-		showErrorDialog(err, w)
-		return
-	}
-
-	// Clean up the downloaded archive
-	if err := os.Remove(filePath); err != nil {
-		showErrorDialog(err, w)
-	}
+}
 
-	newMod := &mod.Mod{
-		Name:   filepath.Base(extractDir),
-		Path:   extractDir,
-		Active: false,
-		ModID:  info.ModID,
-		FileID: info.FileID,
-		Game:   state.currentGame.ID,
+// nexusAPIKey returns the API key from NEXUS_API_KEY, or the one of the
+// logged in account.
+func nexusAPIKey() (string, error) {
+	if apiKey := os.Getenv("NEXUS_API_KEY"); apiKey != "" {
+		return apiKey, nil
 	}
-	state.mods = append(state.mods, newMod)
-	if err := mod.SaveMods(state.mods, state.currentGame.ID); err != nil {
-		showErrorDialog(err, w)
-		return
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return "", err
 	}
-	modList.Refresh()
+	return cfg.APIKey, nil
 }
 
 func updateUsername(usernameChan chan string, w fyne.Window) {
@@ -362,7 +383,10 @@ func Show(nxmURL string) {
 	if cfg.APIKey == "" {
 		apiKeyWindow := newAPIKeyWindow(a, func(apiKey string) {
 			cfg.APIKey = apiKey
-			if err := config.SaveConfig(cfg); err != nil {
+			if _, err := config.UpdateConfig(func(c *config.Config) error {
+				c.APIKey = apiKey
+				return nil
+			}); err != nil {
 				showErrorDialog(err, w)
 			}
 			currentGame, err := games.GetGameByID(cfg.CurrentGame)
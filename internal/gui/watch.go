@@ -0,0 +1,103 @@
+package gui
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"github.com/bazsalanszky/fusioncore/internal/config"
+	"github.com/bazsalanszky/fusioncore/internal/games"
+	"github.com/bazsalanszky/fusioncore/internal/mod"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchStore reloads the current game and its mods when config.json or the
+// mod list is changed by another process, like the CLI, so the GUI doesn't
+// overwrite those changes with its stale copy. onReload is called after
+// the state has been updated. The state is only read and updated on the
+// UI goroutine, like the rest of the GUI does.
+func watchStore(state *AppState, onReload func(gameChanged bool)) error {
+	configPath, err := config.GetConfigPath()
+	if err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch the config directory: %w", err)
+	}
+	// Files are replaced by renaming, so the directory is watched instead of the files
+	if err := watcher.Add(filepath.Dir(configPath)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch the config directory: %w", err)
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		// Saves come in bursts of events, so reload once they settle down
+		var timer *time.Timer
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Rename) == 0 {
+					continue
+				}
+				var gameID string
+				fyne.DoAndWait(func() { gameID = state.currentGame.ID })
+				modsPath, err := mod.GetModsConfigPath(gameID)
+				if err != nil || (event.Name != configPath && event.Name != modsPath) {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(200*time.Millisecond, func() {
+					fyne.Do(func() {
+						gameChanged, err := reloadState(state)
+						if err != nil {
+							fmt.Printf("Failed to reload changed files: %v\n", err)
+							return
+						}
+						onReload(gameChanged)
+					})
+				})
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				fmt.Printf("Config watcher error: %v\n", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// reloadState reads the current game and its mods from disk. It reports
+// whether the current game was changed. It must run on the UI goroutine.
+func reloadState(state *AppState) (bool, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return false, err
+	}
+
+	game := state.currentGame
+	gameChanged := cfg.CurrentGame != state.currentGame.ID
+	if gameChanged {
+		if game, err = games.GetGameByID(cfg.CurrentGame); err != nil {
+			return false, err
+		}
+	}
+
+	mods, err := mod.LoadMods(game.ID)
+	if err != nil {
+		return false, err
+	}
+	state.currentGame = game
+	state.mods = mods
+	return gameChanged, nil
+}
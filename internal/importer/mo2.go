@@ -0,0 +1,315 @@
+// Package importer converts the mods of other mod managers into Fusion Core mods.
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bazsalanszky/fusioncore/internal/config"
+	"github.com/bazsalanszky/fusioncore/internal/games"
+	"github.com/bazsalanszky/fusioncore/internal/mod"
+	"github.com/bazsalanszky/fusioncore/internal/prefix"
+	"gopkg.in/ini.v1"
+)
+
+// MO2Mod is a mod of a Mod Organizer 2 instance.
+type MO2Mod struct {
+	Name        string
+	Path        string
+	Active      bool
+	ModID       string // Nexus mod ID from meta.ini, empty if unknown
+	FileID      string
+	Version     string
+	InstallFile string // Archive the mod was installed from
+	Overwrite   bool   // The overwrite folder, which is copied as MO2 needs it
+}
+
+// MO2Instance is what is imported from a Mod Organizer 2 instance.
+type MO2Instance struct {
+	Dir     string
+	Profile string
+	Mods    []MO2Mod // In Fusion Core order, the lowest priority first
+	// Plugins in load order. Games using the asterisk format keep
+	// inactive plugins, which are listed without the '*'.
+	Plugins []string
+}
+
+// ReadMO2 reads a Mod Organizer 2 instance, the folder holding
+// ModOrganizer.ini. The profile selected in MO2 is used if profile is empty.
+func ReadMO2(dir string, game *games.Game, profile string) (*MO2Instance, error) {
+	settings, err := ini.LoadSources(ini.LoadOptions{IgnoreInlineComment: true}, filepath.Join(dir, "ModOrganizer.ini"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ModOrganizer.ini: %w", err)
+	}
+
+	general := settings.Section("General")
+	if profile == "" {
+		profile = qtString(general.Key("selected_profile").String())
+	}
+	if profile == "" {
+		profile = "Default"
+	}
+
+	paths := settings.Section("Settings")
+	baseDir := dir
+	if value := paths.Key("base_directory").String(); value != "" {
+		if baseDir, err = mo2Path(dir, qtString(value)); err != nil {
+			return nil, err
+		}
+	}
+	dirSetting := func(key, fallback string) (string, error) {
+		value := qtString(paths.Key(key).String())
+		if value == "" {
+			return filepath.Join(baseDir, fallback), nil
+		}
+		return mo2Path(dir, strings.ReplaceAll(value, "%BASE_DIR%", baseDir))
+	}
+	modsDir, err := dirSetting("mod_directory", "mods")
+	if err != nil {
+		return nil, err
+	}
+	profilesDir, err := dirSetting("profiles_directory", "profiles")
+	if err != nil {
+		return nil, err
+	}
+	overwriteDir, err := dirSetting("overwrite_directory", "overwrite")
+	if err != nil {
+		return nil, err
+	}
+
+	profileDir := filepath.Join(profilesDir, profile)
+	modlist, err := readLines(filepath.Join(profileDir, "modlist.txt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the mod list of profile %s: %w", profile, err)
+	}
+
+	instance := &MO2Instance{Dir: dir, Profile: profile}
+
+	// modlist.txt starts with the mod of the highest priority, the one
+	// whose files win, which goes last in Fusion Core
+	listed := make(map[string]bool)
+	for i := len(modlist) - 1; i >= 0; i-- {
+		line := modlist[i]
+		if len(line) < 2 || (line[0] != '+' && line[0] != '-') {
+			continue // '*' marks DLCs and files MO2 doesn't manage
+		}
+		name := line[1:]
+		if strings.HasSuffix(name, "_separator") {
+			continue
+		}
+		listed[name] = true
+		if _, err := os.Stat(filepath.Join(modsDir, name)); err != nil {
+			fmt.Printf("Skipping %s, its folder is missing\n", name)
+			continue
+		}
+		m, err := readMO2Mod(filepath.Join(modsDir, name))
+		if err != nil {
+			return nil, err
+		}
+		m.Active = line[0] == '+'
+		instance.Mods = append(instance.Mods, m)
+	}
+
+	// Mods missing from the profile are imported as inactive
+	entries, err := os.ReadDir(modsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the mods of %s: %w", dir, err)
+	}
+	var unlisted []MO2Mod
+	for _, entry := range entries {
+		if !entry.IsDir() || listed[entry.Name()] || strings.HasSuffix(entry.Name(), "_separator") {
+			continue
+		}
+		m, err := readMO2Mod(filepath.Join(modsDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		unlisted = append(unlisted, m)
+	}
+	instance.Mods = append(unlisted, instance.Mods...)
+
+	// Files tools wrote to the overwrite folder win over every mod
+	if files, err := os.ReadDir(overwriteDir); err == nil && len(files) > 0 {
+		instance.Mods = append(instance.Mods, MO2Mod{Name: "Overwrite", Path: overwriteDir, Active: true, Overwrite: true})
+	}
+
+	if instance.Plugins, err = readMO2Plugins(profileDir, game); err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+// readMO2Mod reads the Nexus IDs and version of a mod from its meta.ini, if
+// it has one.
+func readMO2Mod(dir string) (MO2Mod, error) {
+	m := MO2Mod{Name: filepath.Base(dir), Path: dir}
+	meta, err := ini.LoadSources(ini.LoadOptions{IgnoreInlineComment: true, Loose: true}, filepath.Join(dir, "meta.ini"))
+	if err != nil {
+		return m, fmt.Errorf("failed to read meta.ini of %s: %w", m.Name, err)
+	}
+	general := meta.Section("General")
+	if modID, err := general.Key("modid").Int(); err == nil && modID > 0 {
+		m.ModID = strconv.Itoa(modID)
+	}
+	m.Version = general.Key("version").String()
+	if installFile := general.Key("installationFile").String(); installFile != "" {
+		m.InstallFile = filepath.Base(strings.ReplaceAll(qtString(installFile), `\`, "/"))
+	}
+	// The files installed into the mod, usually only one
+	installed := meta.Section("installedFiles")
+	if m.ModID != "" && installed.Key(`1\modid`).String() == m.ModID {
+		m.FileID = installed.Key(`1\fileid`).String()
+	}
+	return m, nil
+}
+
+// readMO2Plugins returns the plugin order of a profile in the format of the
+// game's plugins file. Vanilla plugins are left out.
+func readMO2Plugins(profileDir string, game *games.Game) ([]string, error) {
+	plugins, err := readLines(filepath.Join(profileDir, "plugins.txt"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read plugins.txt: %w", err)
+	}
+	// MO2 writes plugins.txt in the format of the game
+	active := make(map[string]bool)
+	for _, p := range plugins {
+		if game.PluginsFormat != games.PluginsFormatAsterisk || strings.HasPrefix(p, "*") {
+			active[strings.ToLower(strings.TrimPrefix(p, "*"))] = true
+		}
+	}
+
+	// loadorder.txt also lists inactive plugins
+	order, err := readLines(filepath.Join(profileDir, "loadorder.txt"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read loadorder.txt: %w", err)
+		}
+		for _, p := range plugins {
+			order = append(order, strings.TrimPrefix(p, "*"))
+		}
+	}
+
+	var result []string
+	for _, p := range order {
+		if game.IsVanillaFile(p) {
+			continue
+		}
+		isActive := active[strings.ToLower(p)]
+		switch {
+		case game.PluginsFormat == games.PluginsFormatAsterisk && isActive:
+			result = append(result, "*"+p)
+		case game.PluginsFormat == games.PluginsFormatAsterisk, isActive:
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+// Import moves the mods of the instance into the game's mods directory,
+// adds them to its mod list and replaces its plugins file with the plugin
+// order of the profile. The overwrite folder is copied, but MO2 can't use
+// the moved mods afterwards. Their meta.ini is dropped as its details are
+// kept in the mod list and it would be deployed to Data otherwise.
+func (instance *MO2Instance) Import(game *games.Game) ([]*mod.Mod, error) {
+	modsDir, err := game.GetModsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var imported []*mod.Mod
+	for _, m := range instance.Mods {
+		modDir := mod.UniqueDir(filepath.Join(modsDir, m.Name))
+		if m.Overwrite {
+			if err := mod.CopyDir(m.Path, modDir); err != nil {
+				os.RemoveAll(modDir)
+				return imported, fmt.Errorf("failed to copy %s: %w", m.Name, err)
+			}
+		} else {
+			if err := mod.MoveDir(m.Path, modDir); err != nil {
+				return imported, fmt.Errorf("failed to move %s: %w", m.Name, err)
+			}
+			if err := os.Remove(filepath.Join(modDir, "meta.ini")); err != nil && !os.IsNotExist(err) {
+				return imported, fmt.Errorf("failed to remove meta.ini of %s: %w", m.Name, err)
+			}
+		}
+
+		newMod := mod.New(m.Name, modDir, "local", "local", game.ID)
+		if m.ModID != "" {
+			newMod.ModID, newMod.FileID = m.ModID, m.FileID
+		}
+		newMod.Active = m.Active
+		if m.Version != "" || m.InstallFile != "" {
+			newMod.Metadata = &mod.Metadata{FileVersion: m.Version, FileName: m.InstallFile}
+		}
+		if _, err := mod.Add(game.ID, newMod); err != nil {
+			return imported, fmt.Errorf("failed to add %s to the mod list, its files are in %s: %w", m.Name, modDir, err)
+		}
+		fmt.Printf("Imported %s\n", m.Name)
+		imported = append(imported, newMod)
+	}
+
+	if len(instance.Plugins) > 0 {
+		prefixPath, err := config.FindGamePrefix(game)
+		if err != nil {
+			return imported, fmt.Errorf("failed to find the prefix to write the plugin order to: %w", err)
+		}
+		if err := config.WriteGamePlugins(prefixPath, game, instance.Plugins); err != nil {
+			return imported, err
+		}
+	}
+	return imported, nil
+}
+
+// mo2Path converts a path from MO2's settings to a Linux path. Windows paths
+// are resolved with the drives of the Wine prefix the instance lives in.
+func mo2Path(instanceDir, p string) (string, error) {
+	if len(p) < 2 || p[1] != ':' {
+		p = filepath.FromSlash(strings.ReplaceAll(p, `\`, "/"))
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(instanceDir, p)
+		}
+		return p, nil
+	}
+	for dir := instanceDir; dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, "dosdevices")); err == nil {
+			return prefix.ToUnixPath(dir, p)
+		}
+	}
+	// Outside of a prefix only Z:, the root of the Linux file system, is known
+	if strings.EqualFold(p[:2], "z:") {
+		return prefix.ToUnixPath("", p)
+	}
+	return "", fmt.Errorf("failed to resolve %s, the instance is not in a Wine prefix", p)
+}
+
+// qtString returns the text of a value MO2 stored as a Qt byte array, like
+// "@ByteArray(Default)", with Qt's escaped backslashes undone.
+func qtString(value string) string {
+	if strings.HasPrefix(value, "@ByteArray(") && strings.HasSuffix(value, ")") {
+		value = value[len("@ByteArray(") : len(value)-1]
+	}
+	return strings.ReplaceAll(value, `\\`, `\`)
+}
+
+// readLines returns the lines of a text file, without comments and empty lines.
+func readLines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if line != "" && line[0] != '#' {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
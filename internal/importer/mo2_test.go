@@ -0,0 +1,141 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bazsalanszky/fusioncore/internal/config"
+	"github.com/bazsalanszky/fusioncore/internal/games"
+	"github.com/bazsalanszky/fusioncore/internal/mod"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestImportMO2(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-mo2")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	t.Setenv("HOME", tmpDir)
+
+	game, err := games.GetGameByID("fallout4")
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+
+	// An instance in a Wine prefix, with the mods on the Linux side through Z:
+	prefixPath := filepath.Join(tmpDir, "prefix")
+	instanceDir := filepath.Join(prefixPath, "drive_c", "users", "steamuser", "AppData", "Local", "ModOrganizer", "Fallout 4")
+	if err := os.MkdirAll(filepath.Join(prefixPath, "dosdevices"), 0755); err != nil {
+		t.Fatalf("Failed to create dosdevices: %v", err)
+	}
+	modsDir := filepath.Join(tmpDir, "mo2-mods")
+	writeFiles(t, instanceDir, map[string]string{
+		"ModOrganizer.ini": "[General]\ngameName=Fallout 4\nselected_profile=@ByteArray(Survival)\n\n" +
+			"[Settings]\nmod_directory=Z:" + filepath.ToSlash(modsDir) + "\n",
+		"profiles/Survival/modlist.txt": "# This file was automatically generated by Mod Organizer.\n" +
+			"+Better Armor\n-Weapons_separator\n-Old Weapons\n*DLC: Far Harbor\n+Unofficial Patch\n",
+		"profiles/Survival/plugins.txt":   "# This file was automatically generated by Mod Organizer.\n*Unofficial Fallout 4 Patch.esp\n*BetterArmor.esp\nOldWeapons.esp\n",
+		"profiles/Survival/loadorder.txt": "Fallout4.esm\nDLCCoast.esm\nUnofficial Fallout 4 Patch.esp\nOldWeapons.esp\nBetterArmor.esp\n",
+		"overwrite/tools/output.txt":      "generated",
+	})
+	writeFiles(t, modsDir, map[string]string{
+		"Unofficial Patch/Unofficial Fallout 4 Patch.esp": "patch",
+		"Unofficial Patch/meta.ini":                       "[General]\nmodid=4598\nversion=2.1.5\ninstallationFile=C:\\\\Downloads\\\\UFO4P 2.1.5-4598-2-1-5.7z\n\n[installedFiles]\n1\\modid=4598\n1\\fileid=259215\nsize=1\n",
+		"Old Weapons/OldWeapons.esp":                      "weapons",
+		"Old Weapons/meta.ini":                            "[General]\nmodid=0\n",
+		"Better Armor/BetterArmor.esp":                    "armor",
+		"Better Armor/meta.ini":                           "[General]\nmodid=1234\nversion=1.0\n",
+		"Unlisted/Unlisted.esp":                           "unlisted",
+	})
+
+	instance, err := ReadMO2(instanceDir, game, "")
+	if err != nil {
+		t.Fatalf("Failed to read instance: %v", err)
+	}
+	if instance.Profile != "Survival" {
+		t.Errorf("expected the selected profile, got %s", instance.Profile)
+	}
+	var names []string
+	for _, m := range instance.Mods {
+		names = append(names, m.Name)
+	}
+	expectedNames := []string{"Unlisted", "Unofficial Patch", "Old Weapons", "Better Armor", "Overwrite"}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Fatalf("expected mods %v, got %v", expectedNames, names)
+	}
+	patch := instance.Mods[1]
+	if !patch.Active || patch.ModID != "4598" || patch.FileID != "259215" || patch.Version != "2.1.5" || patch.InstallFile != "UFO4P 2.1.5-4598-2-1-5.7z" {
+		t.Errorf("unexpected patch %+v", patch)
+	}
+	if instance.Mods[2].Active || instance.Mods[2].ModID != "" {
+		t.Errorf("expected an inactive local mod, got %+v", instance.Mods[2])
+	}
+	if instance.Mods[3].FileID != "" {
+		t.Errorf("expected no file ID without installedFiles, got %+v", instance.Mods[3])
+	}
+	expectedPlugins := []string{"*Unofficial Fallout 4 Patch.esp", "OldWeapons.esp", "*BetterArmor.esp"}
+	if !reflect.DeepEqual(instance.Plugins, expectedPlugins) {
+		t.Errorf("expected plugins %v, got %v", expectedPlugins, instance.Plugins)
+	}
+
+	// The plugins are written to the prefix of the game
+	gamePrefix := filepath.Join(tmpDir, "compatdata")
+	if err := os.MkdirAll(filepath.Join(gamePrefix, "pfx"), 0755); err != nil {
+		t.Fatalf("Failed to create prefix: %v", err)
+	}
+	err = config.SaveConfig(&config.Config{CurrentGame: "fallout4", CompatdataPaths: map[string]string{"fallout4": gamePrefix}})
+	if err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	if _, err := instance.Import(game); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	mods, err := mod.LoadMods("fallout4")
+	if err != nil {
+		t.Fatalf("Failed to load mods: %v", err)
+	}
+	if len(mods) != len(expectedNames) || mods[1].ModID != "4598" || mods[1].Metadata.FileVersion != "2.1.5" || mods[2].ModID != "local" {
+		t.Fatalf("unexpected mods %+v", mods)
+	}
+	if _, err := os.Stat(filepath.Join(mods[1].Path, "Unofficial Fallout 4 Patch.esp")); err != nil {
+		t.Errorf("expected the mod to be moved: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(modsDir, "Unofficial Patch")); !os.IsNotExist(err) {
+		t.Errorf("expected the MO2 folder to be gone")
+	}
+	if _, err := os.Stat(filepath.Join(mods[1].Path, "meta.ini")); !os.IsNotExist(err) {
+		t.Errorf("expected meta.ini to be left out so it isn't deployed")
+	}
+	// MO2 keeps its overwrite folder
+	if _, err := os.Stat(filepath.Join(mods[4].Path, "tools", "output.txt")); err != nil {
+		t.Errorf("expected the overwrite folder to be copied: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(instanceDir, "overwrite", "tools", "output.txt")); err != nil {
+		t.Errorf("expected the overwrite folder to be kept: %v", err)
+	}
+
+	plugins, err := config.ReadGamePlugins(gamePrefix, game)
+	if err != nil {
+		t.Fatalf("Failed to read plugins: %v", err)
+	}
+	if !reflect.DeepEqual(plugins, expectedPlugins) {
+		t.Errorf("expected plugins.txt %v, got %v", expectedPlugins, plugins)
+	}
+}
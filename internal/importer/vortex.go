@@ -0,0 +1,354 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bazsalanszky/fusioncore/internal/config"
+	"github.com/bazsalanszky/fusioncore/internal/games"
+	"github.com/bazsalanszky/fusioncore/internal/mod"
+	"github.com/bazsalanszky/fusioncore/internal/nexus"
+	"github.com/bazsalanszky/fusioncore/internal/prefix"
+)
+
+// Files Vortex leaves in the folders it manages.
+const (
+	vortexManifest      = "vortex.deployment.json"
+	vortexStagingMarker = "__vortex_staging_folder"
+	vortexFolderMarker  = "__folder_managed_by_vortex" // In folders Vortex created in Data
+	vortexBackupSuffix  = ".vortex_backup"             // Files in Data that Vortex deployed over
+)
+
+// nexusArchiveName matches the names Nexus Mods gives downloads, which
+// Vortex uses for the staging folders, like
+// "Unofficial Fallout 4 Patch-4598-2-1-5-1693069437": name, mod ID,
+// version with dashes for dots and upload time.
+var nexusArchiveName = regexp.MustCompile(`^(.+?)-(\d+)-(.+)-(\d{9,10})(\.\d+)?$`)
+
+// VortexMod is a mod in the staging folder of Vortex.
+type VortexMod struct {
+	Name     string
+	Path     string
+	Active   bool   // Vortex deployed files of the mod
+	ModID    string // Nexus mod ID from the folder name, empty if unknown
+	FileID   string // Nexus file ID, only known after ResolveFileIDs
+	Version  string
+	Uploaded int64 // Upload time of the file from the folder name
+}
+
+// VortexDeployment is what is imported from a Vortex deployment.
+type VortexDeployment struct {
+	DataDir    string
+	StagingDir string
+	Method     string
+	Mods       []VortexMod // Mods whose files won conflicts go after the ones they won against
+	files      []vortexFile
+}
+
+type vortexFile struct {
+	RelPath string `json:"relPath"`
+	Source  string `json:"source"` // Staging folder of the winning mod
+}
+
+type vortexManifestFile struct {
+	DeploymentMethod string       `json:"deploymentMethod"`
+	StagingPath      string       `json:"stagingPath"`
+	Files            []vortexFile `json:"files"`
+}
+
+// ReadVortex reads the deployment manifest Vortex keeps in a game's data
+// directory and the mods in its staging folder. stagingDir overrides the
+// staging path of the manifest, which is a Windows path that is resolved
+// in the game's prefix otherwise.
+func ReadVortex(game *games.Game, dataDir, stagingDir string) (*VortexDeployment, error) {
+	data, err := os.ReadFile(filepath.Join(dataDir, vortexManifest))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the Vortex deployment: %w", err)
+	}
+	var manifest vortexManifestFile
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", vortexManifest, err)
+	}
+	switch manifest.DeploymentMethod {
+	case "", "hardlink_activator", "symlink_activator", "symlink_activator_elevate":
+	default:
+		return nil, fmt.Errorf("unsupported Vortex deployment method %s, purge the mods in Vortex and use import-existing instead", manifest.DeploymentMethod)
+	}
+
+	if stagingDir == "" {
+		if stagingDir, err = vortexStagingDir(game, manifest.StagingPath); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := os.Stat(filepath.Join(stagingDir, vortexStagingMarker)); err != nil {
+		return nil, fmt.Errorf("%s is not a Vortex staging folder, set it with --staging: %w", stagingDir, err)
+	}
+
+	deployment := &VortexDeployment{DataDir: dataDir, StagingDir: stagingDir, Method: manifest.DeploymentMethod}
+	for _, f := range manifest.Files {
+		f.RelPath = filepath.FromSlash(strings.ReplaceAll(f.RelPath, `\`, "/"))
+		deployment.files = append(deployment.files, f)
+	}
+
+	entries, err := os.ReadDir(stagingDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the staging folder: %w", err)
+	}
+	deployed := make(map[string]bool)
+	for _, f := range deployment.files {
+		deployed[f.Source] = true
+	}
+	var folders []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || strings.HasPrefix(name, "__") || strings.HasSuffix(name, ".installing") {
+			continue
+		}
+		folders = append(folders, name)
+	}
+
+	for _, folder := range vortexOrder(stagingDir, folders, deployment.files) {
+		m := VortexMod{Name: folder, Path: filepath.Join(stagingDir, folder), Active: deployed[folder]}
+		if match := nexusArchiveName.FindStringSubmatch(folder); match != nil {
+			m.Name = match[1]
+			m.ModID = match[2]
+			m.Version = strings.ReplaceAll(match[3], "-", ".")
+			m.Uploaded, _ = strconv.ParseInt(match[4], 10, 64)
+		}
+		deployment.Mods = append(deployment.Mods, m)
+	}
+	return deployment, nil
+}
+
+// ResolveFileIDs looks up the Nexus file IDs of the mods, which Vortex
+// doesn't keep in the staging folder. The file of a mod is the one uploaded
+// at the time in its folder name. Mods whose file isn't found keep only
+// their mod ID.
+func (d *VortexDeployment) ResolveFileIDs(game *games.Game, apiKey string) {
+	for i := range d.Mods {
+		m := &d.Mods[i]
+		if m.ModID == "" || m.FileID != "" {
+			continue
+		}
+		files, err := nexus.GetModFiles(game.NexusName, m.ModID, apiKey)
+		if err != nil {
+			fmt.Printf("Failed to get the files of %s, only its mod ID is kept: %v\n", m.Name, err)
+			continue
+		}
+		if m.FileID = uploadedFile(files, m.Uploaded, m.Version); m.FileID == "" {
+			fmt.Printf("No file of %s was uploaded at the time in its folder name, only its mod ID is kept\n", m.Name)
+		}
+	}
+}
+
+// uploadedFile returns the ID of the file uploaded at the given time, or of
+// the only file with the given version if none was.
+func uploadedFile(files []nexus.FileInfo, uploaded int64, version string) string {
+	var sameVersion []nexus.FileInfo
+	for _, f := range files {
+		if uploaded != 0 && f.UploadedTime == uploaded {
+			return strconv.Itoa(f.FileID)
+		}
+		if f.Version == version {
+			sameVersion = append(sameVersion, f)
+		}
+	}
+	if len(sameVersion) == 1 {
+		return strconv.Itoa(sameVersion[0].FileID)
+	}
+	return ""
+}
+
+// vortexStagingDir resolves the staging path of a deployment manifest.
+func vortexStagingDir(game *games.Game, stagingPath string) (string, error) {
+	if stagingPath == "" {
+		return "", fmt.Errorf("the Vortex deployment has no staging path, set it with --staging")
+	}
+	if filepath.IsAbs(stagingPath) {
+		return stagingPath, nil
+	}
+	prefixPath, err := config.FindGamePrefix(game)
+	// Z: is the root of the Linux file system, other drives need the prefix
+	onZ := len(stagingPath) >= 2 && strings.EqualFold(stagingPath[:2], "z:")
+	if err != nil && !onZ {
+		return "", fmt.Errorf("failed to find the prefix to resolve the staging path %s in, set it with --staging: %w", stagingPath, err)
+	}
+	return prefix.ToUnixPath(prefixPath, stagingPath)
+}
+
+// vortexOrder sorts staging folders so that every mod comes after the mods
+// it won file conflicts against, as the last mod wins in Fusion Core.
+// Otherwise the folders stay in alphabetical order.
+func vortexOrder(stagingDir string, folders []string, files []vortexFile) []string {
+	sort.Strings(folders)
+
+	// Which folders have each file, matched like Windows does
+	providers := make(map[string][]string)
+	for _, folder := range folders {
+		root := filepath.Join(stagingDir, folder)
+		filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(root, path)
+			if err == nil {
+				key := strings.ToLower(rel)
+				providers[key] = append(providers[key], folder)
+			}
+			return nil
+		})
+	}
+
+	after := make(map[string]map[string]bool) // Folder to the folders it must come after
+	for _, f := range files {
+		for _, folder := range providers[strings.ToLower(f.RelPath)] {
+			if folder == f.Source {
+				continue
+			}
+			if after[f.Source] == nil {
+				after[f.Source] = make(map[string]bool)
+			}
+			after[f.Source][folder] = true
+		}
+	}
+
+	var order []string
+	placed := make(map[string]bool)
+	for len(order) < len(folders) {
+		progress := false
+		for _, folder := range folders {
+			if placed[folder] {
+				continue
+			}
+			ready := true
+			for other := range after[folder] {
+				if !placed[other] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				order = append(order, folder)
+				placed[folder] = true
+				progress = true
+			}
+		}
+		// Vortex allows cyclic file overrides, place the first remaining folder
+		if !progress {
+			for _, folder := range folders {
+				if !placed[folder] {
+					order = append(order, folder)
+					placed[folder] = true
+					break
+				}
+			}
+		}
+	}
+	return order
+}
+
+// Undeploy removes the links Vortex deployed to the data directory, along
+// with the folders it created there, and puts back the game files Vortex
+// replaced. Files that were replaced since are kept and reported.
+func (d *VortexDeployment) Undeploy() error {
+	for _, f := range d.files {
+		target := filepath.Join(d.DataDir, f.RelPath)
+		info, err := os.Lstat(target)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			staged, err := os.Stat(filepath.Join(d.StagingDir, f.Source, f.RelPath))
+			if err != nil || !os.SameFile(info, staged) {
+				fmt.Printf("Keeping %s, it is no longer the file Vortex deployed\n", f.RelPath)
+				continue
+			}
+		}
+		if err := os.Remove(target); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", target, err)
+		}
+	}
+
+	// Put back the files Vortex renamed to deploy over them, and remove the
+	// markers of the folders Vortex created, and the folders themselves once
+	// they are empty, deepest first
+	var backups, markers []string
+	filepath.WalkDir(d.DataDir, func(path string, entry os.DirEntry, err error) error {
+		switch {
+		case err != nil || entry.IsDir():
+		case entry.Name() == vortexFolderMarker:
+			markers = append(markers, path)
+		case strings.HasSuffix(entry.Name(), vortexBackupSuffix):
+			backups = append(backups, path)
+		}
+		return nil
+	})
+	for _, backup := range backups {
+		original := strings.TrimSuffix(backup, vortexBackupSuffix)
+		if _, err := os.Lstat(original); err == nil {
+			rel, _ := filepath.Rel(d.DataDir, backup)
+			fmt.Printf("Keeping %s, the file it backs up was replaced since\n", rel)
+			continue
+		}
+		if err := os.Rename(backup, original); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", original, err)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(markers)))
+	for _, marker := range markers {
+		if err := os.Remove(marker); err != nil {
+			return err
+		}
+		os.Remove(filepath.Dir(marker)) // Fails unless empty
+
+	}
+
+	if err := os.Remove(filepath.Join(d.DataDir, vortexManifest)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Import undeploys the Vortex mods, moves them from the staging folder into
+// the game's mods directory and adds them to its mod list. Vortex can't use
+// them afterwards. The plugins file is left alone, Vortex already wrote it.
+func (d *VortexDeployment) Import(game *games.Game) ([]*mod.Mod, error) {
+	modsDir, err := game.GetModsDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := d.Undeploy(); err != nil {
+		return nil, fmt.Errorf("failed to undo the Vortex deployment: %w", err)
+	}
+
+	var imported []*mod.Mod
+	for _, m := range d.Mods {
+		modDir := mod.UniqueDir(filepath.Join(modsDir, m.Name))
+		if err := mod.MoveDir(m.Path, modDir); err != nil {
+			return imported, fmt.Errorf("failed to move %s: %w", m.Name, err)
+		}
+
+		newMod := mod.New(m.Name, modDir, "local", "local", game.ID)
+		if m.ModID != "" {
+			// Without ResolveFileIDs only the mod ID is known, refresh-metadata works without the file ID
+			newMod.ModID, newMod.FileID = m.ModID, m.FileID
+			newMod.Metadata = &mod.Metadata{FileVersion: m.Version}
+		}
+		newMod.Active = m.Active
+		if _, err := mod.Add(game.ID, newMod); err != nil {
+			return imported, fmt.Errorf("failed to add %s to the mod list, its files are in %s: %w", m.Name, modDir, err)
+		}
+		fmt.Printf("Imported %s\n", m.Name)
+		imported = append(imported, newMod)
+	}
+	return imported, nil
+}
@@ -0,0 +1,222 @@
+package importer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bazsalanszky/fusioncore/internal/games"
+	"github.com/bazsalanszky/fusioncore/internal/mod"
+	"github.com/bazsalanszky/fusioncore/internal/nexus"
+)
+
+func TestImportVortex(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-vortex")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	t.Setenv("HOME", tmpDir)
+
+	game, err := games.GetGameByID("skyrimse")
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+
+	stagingDir := filepath.Join(tmpDir, "staging")
+	dataDir := filepath.Join(tmpDir, "Data")
+	writeFiles(t, stagingDir, map[string]string{
+		"__vortex_staging_folder":                             `{"instance": "abc"}`,
+		"SkyUI_5_2_SE-12604-5-2SE-1573434533/SkyUI_SE.esp":    "skyui",
+		"Textures-100-1-0-1600000000/textures/sky.dds":        "sky",
+		"Textures-100-1-0-1600000000/textures/ground.dds":     "ground",
+		"Better Textures-200-2-0-1600000001/textures/sky.dds": "better sky",
+		"Disabled Mod/disabled.esp":                           "disabled",
+		"Half Installed.installing/file.esp":                  "partial",
+	})
+	writeFiles(t, dataDir, map[string]string{
+		"Skyrim.esm":                        "vanilla",
+		"textures/vanilla.dds":              "vanilla",
+		"meshes/__folder_managed_by_vortex": "",
+		"vortex.deployment.json": `{"version": 1, "instance": "abc", "deploymentMethod": "hardlink_activator",
+			"stagingPath": "Z:` + filepath.ToSlash(stagingDir) + `", "files": [
+			{"relPath": "SkyUI_SE.esp", "source": "SkyUI_5_2_SE-12604-5-2SE-1573434533"},
+			{"relPath": "textures\\sky.dds", "source": "Better Textures-200-2-0-1600000001"},
+			{"relPath": "textures\\ground.dds", "source": "Textures-100-1-0-1600000000"},
+			{"relPath": "meshes\\replaced.nif", "source": "Textures-100-1-0-1600000000"}]}`,
+		"meshes/replaced.nif":               "edited by hand",
+		"textures/ground.dds.vortex_backup": "vanilla ground",
+	})
+	for _, link := range [][2]string{
+		{"SkyUI_5_2_SE-12604-5-2SE-1573434533/SkyUI_SE.esp", "SkyUI_SE.esp"},
+		{"Better Textures-200-2-0-1600000001/textures/sky.dds", "textures/sky.dds"},
+		{"Textures-100-1-0-1600000000/textures/ground.dds", "textures/ground.dds"},
+	} {
+		if err := os.Link(filepath.Join(stagingDir, link[0]), filepath.Join(dataDir, link[1])); err != nil {
+			t.Fatalf("Failed to create hardlink: %v", err)
+		}
+	}
+
+	deployment, err := ReadVortex(game, dataDir, "")
+	if err != nil {
+		t.Fatalf("Failed to read deployment: %v", err)
+	}
+	var names []string
+	for _, m := range deployment.Mods {
+		names = append(names, m.Name)
+	}
+	// Better Textures won sky.dds, so it goes after Textures
+	expected := []string{"Disabled Mod", "SkyUI_5_2_SE", "Textures", "Better Textures"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected mods %v, got %v", expected, names)
+	}
+	skyui := deployment.Mods[1]
+	if !skyui.Active || skyui.ModID != "12604" || skyui.Version != "5.2SE" || skyui.Uploaded != 1573434533 {
+		t.Errorf("unexpected SkyUI %+v", skyui)
+	}
+	deployment.Mods[1].FileID = "35407" // As ResolveFileIDs finds it
+	if deployment.Mods[0].Active || deployment.Mods[0].ModID != "" {
+		t.Errorf("expected an inactive local mod, got %+v", deployment.Mods[0])
+	}
+
+	imported, err := deployment.Import(game)
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if len(imported) != len(expected) {
+		t.Fatalf("expected %d mods, got %d", len(expected), len(imported))
+	}
+
+	for _, gone := range []string{"SkyUI_SE.esp", "textures/sky.dds", "vortex.deployment.json", "meshes/__folder_managed_by_vortex"} {
+		if _, err := os.Lstat(filepath.Join(dataDir, gone)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed from Data", gone)
+		}
+	}
+	for _, kept := range []string{"Skyrim.esm", "textures/vanilla.dds", "meshes/replaced.nif"} {
+		if _, err := os.Lstat(filepath.Join(dataDir, kept)); err != nil {
+			t.Errorf("expected %s to be kept: %v", kept, err)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(dataDir, "textures", "ground.dds")); err != nil || string(data) != "vanilla ground" {
+		t.Errorf("expected the file Vortex replaced to be restored, got %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "textures", "ground.dds.vortex_backup")); !os.IsNotExist(err) {
+		t.Errorf("expected the Vortex backup to be gone")
+	}
+	if _, err := os.Stat(filepath.Join(stagingDir, "Half Installed.installing")); err != nil {
+		t.Errorf("expected unfinished installs to stay in the staging folder")
+	}
+
+	mods, err := mod.LoadMods("skyrimse")
+	if err != nil {
+		t.Fatalf("Failed to load mods: %v", err)
+	}
+	if len(mods) != len(expected) || mods[1].ModID != "12604" || mods[1].FileID != "35407" || !mods[1].Active || mods[1].Metadata.FileVersion != "5.2SE" {
+		t.Fatalf("unexpected mods %+v", mods)
+	}
+	if data, err := os.ReadFile(filepath.Join(mods[3].Path, "textures", "sky.dds")); err != nil || string(data) != "better sky" {
+		t.Errorf("expected the staged files to be moved, got %q, %v", data, err)
+	}
+}
+
+func TestUploadedFile(t *testing.T) {
+	files := []nexus.FileInfo{
+		{FileID: 1, Version: "1.0", UploadedTime: 1600000000},
+		{FileID: 2, Version: "1.1", UploadedTime: 1600000100},
+		{FileID: 3, Version: "1.1", UploadedTime: 1600000200},
+		{FileID: 4, Version: "2.0", UploadedTime: 1600000300},
+	}
+	for _, tc := range []struct {
+		uploaded int64
+		version  string
+		expected string
+	}{
+		{1600000200, "1.1", "3"},
+		{1600000000, "9.9", "1"}, // The upload time wins
+		{0, "2.0", "4"},
+		{0, "1.1", ""}, // Several files with the version
+		{1700000000, "3.0", ""},
+	} {
+		if id := uploadedFile(files, tc.uploaded, tc.version); id != tc.expected {
+			t.Errorf("expected file %q for %d %s, got %q", tc.expected, tc.uploaded, tc.version, id)
+		}
+	}
+}
+
+// redirectTransport sends every request to a test server.
+type redirectTransport struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+func (t *redirectTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme, r.URL.Host = t.target.Scheme, t.target.Host
+	return t.next.RoundTrip(r)
+}
+
+func TestResolveFileIDs(t *testing.T) {
+	responses := map[string]string{
+		"/v1/games/skyrimspecialedition/mods/12604/files.json": `{"files": [
+			{"file_id": 35406, "name": "SkyUI", "version": "5.2SE", "uploaded_timestamp": 1573434000},
+			{"file_id": 35407, "name": "SkyUI", "version": "5.2SE", "uploaded_timestamp": 1573434533}
+		]}`,
+		"/v1/games/skyrimspecialedition/mods/100/files.json": `{"files": [
+			{"file_id": 1, "name": "Main", "version": "1.0", "uploaded_timestamp": 1500000000}
+		]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("apikey") != "test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server URL: %v", err)
+	}
+	oldTransport := http.DefaultTransport
+	http.DefaultTransport = &redirectTransport{target: target, next: oldTransport}
+	defer func() { http.DefaultTransport = oldTransport }()
+
+	game, err := games.GetGameByID("skyrimse")
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+	deployment := &VortexDeployment{Mods: []VortexMod{
+		{Name: "SkyUI", ModID: "12604", Version: "5.2SE", Uploaded: 1573434533},
+		{Name: "Textures", ModID: "100", Version: "2.0", Uploaded: 1600000000}, // Not listed anymore
+		{Name: "Missing", ModID: "999", Version: "1.0", Uploaded: 1600000000},
+		{Name: "Local"},
+		{Name: "Known", ModID: "12604", FileID: "1"},
+	}}
+	deployment.ResolveFileIDs(game, "test-key")
+
+	var fileIDs []string
+	for _, m := range deployment.Mods {
+		fileIDs = append(fileIDs, m.FileID)
+	}
+	expected := []string{"35407", "", "", "", "1"}
+	if !reflect.DeepEqual(fileIDs, expected) {
+		t.Errorf("expected file IDs %v, got %v", expected, fileIDs)
+	}
+
+	deployment.Mods[0].FileID = ""
+	deployment.ResolveFileIDs(game, "wrong-key")
+	if deployment.Mods[0].FileID != "" {
+		t.Errorf("expected no file ID with an invalid API key, got %s", deployment.Mods[0].FileID)
+	}
+}
@@ -0,0 +1,154 @@
+package launch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/bazsalanszky/fusioncore/internal/config"
+	"github.com/bazsalanszky/fusioncore/internal/games"
+	"github.com/bazsalanszky/fusioncore/internal/steam"
+	"github.com/bazsalanszky/fusioncore/internal/vfs"
+)
+
+// ShortcutOptions configures the "modded launch" non-Steam shortcut.
+type ShortcutOptions struct {
+	UseLoader  bool   // Launch the script extender loader instead of "fusion-core launch"
+	CompatTool string // Proton version used for the loader, defaults to steam.DefaultCompatTool
+	UserID     string // Steam user to add the shortcut for, defaults to the most recent one
+}
+
+// ShortcutName returns the name of the modded launch shortcut of a game.
+func ShortcutName(game *games.Game) string {
+	return game.Name + " (Fusion Core)"
+}
+
+// CreateShortcut adds a non-Steam shortcut that starts the game with all mods
+// deployed, so it can be launched from Big Picture or Game Mode.
+func CreateShortcut(game *games.Game, opts ShortcutOptions) (*steam.Shortcut, error) {
+	if steam.IsRunning() {
+		return nil, errors.New("Steam is running. Please exit Steam first, it overwrites its shortcuts when it exits")
+	}
+
+	steamRoot, err := steam.FindRoot()
+	if err != nil {
+		return nil, err
+	}
+	userID := opts.UserID
+	if userID == "" {
+		if userID, err = steam.FindActiveUserID(steamRoot); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	shortcut := steam.Shortcut{AppName: ShortcutName(game)}
+	if opts.UseLoader {
+		if game.ScriptExtender == "" {
+			return nil, fmt.Errorf("%s has no script extender", game.Name)
+		}
+		gameDir, err := game.FindGameDirWithCustomPath(cfg.GamePaths[game.ID])
+		if err != nil {
+			return nil, err
+		}
+		loader := filepath.Join(gameDir, game.ScriptExtender)
+		if _, err := os.Stat(loader); err != nil {
+			return nil, fmt.Errorf("%s is not installed in %s", game.ScriptExtender, gameDir)
+		}
+		shortcut.Exe = loader
+		shortcut.StartDir = gameDir
+
+		// Run the loader in the game's own prefix instead of a new one,
+		// so it sees the INI files and plugins.txt we manage
+		if compatdata, err := game.FindCompatdataWithCustomPath(cfg.CompatdataPaths[game.ID]); err == nil {
+			shortcut.LaunchOptions = fmt.Sprintf("STEAM_COMPAT_DATA_PATH=%q %%command%%", compatdata)
+		}
+	} else {
+		executable, err := os.Executable()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get executable path: %w", err)
+		}
+		shortcut.Exe = executable
+		shortcut.StartDir = filepath.Dir(executable)
+		shortcut.LaunchOptions = "launch --game " + game.ID
+	}
+
+	shortcut, err = steam.AddShortcut(steamRoot, userID, shortcut)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.UseLoader {
+		tool := opts.CompatTool
+		if tool == "" {
+			tool = steam.DefaultCompatTool
+		}
+		if err := steam.SetCompatTool(steamRoot, shortcut.CompatdataID(), tool); err != nil {
+			return nil, err
+		}
+	}
+
+	return &shortcut, nil
+}
+
+// Run deploys the mods of a game and starts it through Steam, the non-Steam
+// shortcut or the launcher it was found in. The game becomes the current
+// game if it isn't already.
+func Run(game *games.Game) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.CurrentGame != game.ID {
+		_, err := config.UpdateConfig(func(cfg *config.Config) error {
+			cfg.CurrentGame = game.ID
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	inst, err := game.FindInstallation(cfg.GamePaths[game.ID])
+	if err != nil {
+		return err
+	}
+	args, err := launchCommand(game, inst)
+	if err != nil {
+		return err
+	}
+
+	if err := vfs.SyncLinks(); err != nil {
+		return fmt.Errorf("failed to deploy mods: %w", err)
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", args[0], err)
+	}
+	return nil
+}
+
+// launchCommand returns the command that starts an installation of a game.
+func launchCommand(game *games.Game, inst *games.Installation) ([]string, error) {
+	switch {
+	case inst.Shortcut != nil:
+		return []string{"steam", fmt.Sprintf("steam://rungameid/%d", inst.Shortcut.GameID())}, nil
+	case inst.Launcher != "":
+		if len(inst.Command) == 0 {
+			return nil, fmt.Errorf("%s doesn't know how to start %s, please start it from %s", inst.Launcher, game.Name, inst.Launcher)
+		}
+		return inst.Command, nil
+	case game.AppID != "" && (inst.Library != "" || strings.Contains(filepath.ToSlash(inst.GameDir), "/steamapps/common/")):
+		// Custom paths inside a Steam library are Steam installs too
+		return []string{"steam", "steam://rungameid/" + game.AppID}, nil
+	}
+	return nil, fmt.Errorf("%s at %s wasn't found through Steam or a launcher, please start it yourself", game.Name, inst.GameDir)
+}
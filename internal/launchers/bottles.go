@@ -0,0 +1,114 @@
+package launchers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// bottlesDirs returns the directories Bottles keeps its bottles in.
+func bottlesDirs() []string {
+	return homePaths(
+		filepath.Join(".local", "share", "bottles", "bottles"),
+		filepath.Join(".var", "app", "com.usebottles.bottles", "data", "bottles", "bottles"),
+	)
+}
+
+// bottleConfig is the part of a bottle.yml file we need.
+type bottleConfig struct {
+	Name             string `yaml:"Name"`
+	Path             string `yaml:"Path"`
+	CustomPath       bool   `yaml:"Custom_Path"`
+	ExternalPrograms map[string]struct {
+		Name       string `yaml:"name"`
+		Executable string `yaml:"executable"`
+		Path       string `yaml:"path"`
+	} `yaml:"External_Programs"`
+}
+
+// bottleSearchDirs are the directories inside drive_c searched for games that
+// were installed into the bottle rather than added as external programs.
+var bottleSearchDirs = []string{"GOG Games", "Games", "Program Files", "Program Files (x86)"}
+
+func findBottles(q Query) ([]Installation, error) {
+	var installations []Installation
+	for _, dir := range bottlesDirs() {
+		found, err := findBottlesIn(dir, q)
+		if err != nil {
+			return nil, err
+		}
+		installations = append(installations, found...)
+	}
+	return installations, nil
+}
+
+// findBottlesIn looks for the game in every bottle of a Bottles data directory.
+func findBottlesIn(bottlesDir string, q Query) ([]Installation, error) {
+	entries, err := os.ReadDir(bottlesDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var installations []Installation
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		configPath := filepath.Join(bottlesDir, entry.Name(), "bottle.yml")
+		data, err := os.ReadFile(configPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		var cfg bottleConfig
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", configPath, err)
+		}
+
+		// The bottle directory is the Wine prefix itself
+		prefixPath := filepath.Join(bottlesDir, entry.Name())
+		if cfg.CustomPath && filepath.IsAbs(cfg.Path) {
+			prefixPath = cfg.Path
+		}
+
+		gameDir := ""
+		var command []string
+		for _, program := range cfg.ExternalPrograms {
+			if q.isExecutable(program.Path) || q.isExecutable(program.Executable) || q.matchesTitle(program.Name) {
+				gameDir = filepath.Dir(program.Path)
+				command = []string{"bottles-cli", "run", "-b", cfg.Name, "-p", program.Name}
+				break
+			}
+		}
+		if gameDir == "" {
+			for _, dir := range bottleSearchDirs {
+				if found := q.findExecutableDir(filepath.Join(prefixPath, "drive_c", dir), 2); found != "" {
+					gameDir = found
+					command = []string{"bottles-cli", "run", "-b", cfg.Name, "-e", q.executablePath(found)}
+					break
+				}
+			}
+		}
+		if gameDir == "" {
+			continue
+		}
+
+		installations = append(installations, Installation{
+			Launcher:   "bottles",
+			Title:      cfg.Name,
+			GameDir:    gameDir,
+			PrefixPath: prefixPath,
+			Command:    command,
+		})
+	}
+
+	return installations, nil
+}
@@ -0,0 +1,141 @@
+package launchers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// heroicConfigDirs returns the Heroic configuration directories of the native
+// and the Flatpak version.
+func heroicConfigDirs() []string {
+	return homePaths(
+		filepath.Join(".config", "heroic"),
+		filepath.Join(".var", "app", "com.heroicgameslauncher.hgl", "config", "heroic"),
+	)
+}
+
+// heroicGOGInstalled is the format of gog_store/installed.json.
+type heroicGOGInstalled struct {
+	Installed []struct {
+		AppName     string `json:"appName"`
+		InstallPath string `json:"install_path"`
+		Platform    string `json:"platform"`
+		IsDLC       bool   `json:"is_dlc"`
+	} `json:"installed"`
+}
+
+// heroicEpicInstalled is the format of legendary's installed.json, keyed by app name.
+type heroicEpicInstalled map[string]struct {
+	AppName     string `json:"app_name"`
+	Title       string `json:"title"`
+	InstallPath string `json:"install_path"`
+	Executable  string `json:"executable"`
+	Platform    string `json:"platform"`
+}
+
+// heroicGameConfig is the per game entry in GamesConfig/<appName>.json.
+type heroicGameConfig struct {
+	WinePrefix string `json:"winePrefix"`
+}
+
+func findHeroic(q Query) ([]Installation, error) {
+	var installations []Installation
+	for _, dir := range heroicConfigDirs() {
+		found, err := findHeroicIn(dir, q)
+		if err != nil {
+			return nil, err
+		}
+		installations = append(installations, found...)
+	}
+	return installations, nil
+}
+
+// findHeroicIn looks for the game in the GOG and Epic stores of a single Heroic configuration directory.
+func findHeroicIn(configDir string, q Query) ([]Installation, error) {
+	var installations []Installation
+
+	var gog heroicGOGInstalled
+	if err := readJSON(filepath.Join(configDir, "gog_store", "installed.json"), &gog); err != nil {
+		return nil, err
+	}
+	for _, game := range gog.Installed {
+		if game.IsDLC || !strings.EqualFold(game.Platform, "windows") {
+			continue
+		}
+		title := filepath.Base(game.InstallPath)
+		if !q.matchesGOGID(game.AppName) && !q.matchesTitle(title) && !q.hasExecutable(game.InstallPath) {
+			continue
+		}
+		installations = append(installations, Installation{
+			Launcher:   "heroic",
+			Title:      title,
+			GameDir:    game.InstallPath,
+			PrefixPath: heroicWinePrefix(configDir, game.AppName),
+			Command:    heroicCommand("gog", game.AppName),
+		})
+	}
+
+	var epic heroicEpicInstalled
+	if err := readJSON(filepath.Join(configDir, "legendaryConfig", "legendary", "installed.json"), &epic); err != nil {
+		return nil, err
+	}
+	for appName, game := range epic {
+		if !strings.EqualFold(game.Platform, "windows") {
+			continue
+		}
+		if !q.matchesEpicAppName(appName) && !q.matchesTitle(game.Title) && !q.isExecutable(game.Executable) {
+			continue
+		}
+		installations = append(installations, Installation{
+			Launcher:   "heroic",
+			Title:      game.Title,
+			GameDir:    game.InstallPath,
+			PrefixPath: heroicWinePrefix(configDir, appName),
+			Command:    heroicCommand("legendary", appName),
+		})
+	}
+
+	return installations, nil
+}
+
+// heroicCommand returns the command that starts a game through Heroic's
+// protocol handler, which works for the native and the Flatpak version.
+func heroicCommand(runner, appName string) []string {
+	return []string{"xdg-open", "heroic://launch?appName=" + url.QueryEscape(appName) + "&runner=" + runner}
+}
+
+// heroicWinePrefix reads the Wine prefix Heroic uses for a game from GamesConfig.
+func heroicWinePrefix(configDir, appName string) string {
+	var configs map[string]json.RawMessage
+	if err := readJSON(filepath.Join(configDir, "GamesConfig", appName+".json"), &configs); err != nil {
+		return ""
+	}
+	raw, ok := configs[appName]
+	if !ok {
+		return ""
+	}
+	var cfg heroicGameConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return ""
+	}
+	return cfg.WinePrefix
+}
+
+// readJSON decodes a JSON file into v. A missing file leaves v untouched.
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package launchers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Installation is a game installed through a non-Steam launcher.
+type Installation struct {
+	Launcher   string // "heroic", "lutris" or "bottles"
+	Title      string
+	GameDir    string
+	PrefixPath string   // Wine prefix of the game, empty if the launcher doesn't know it
+	Command    []string // Command that starts the game through the launcher
+}
+
+// Query describes the game to look for. A launcher entry matches if any of
+// the store IDs match, its title matches one of the names, or the game
+// executable is present in its install directory.
+type Query struct {
+	Names        []string
+	GOGIDs       []string
+	EpicAppNames []string
+	Executable   string
+}
+
+// backend finds installations of a game in a single launcher.
+type backend struct {
+	name string
+	find func(q Query) ([]Installation, error)
+}
+
+var backends = []backend{
+	{"heroic", findHeroic},
+	{"lutris", findLutris},
+	{"bottles", findBottles},
+}
+
+// Find returns every installation of the game found in Heroic, Lutris and Bottles.
+// Launchers that are not installed are skipped silently; broken configuration
+// files are reported and skipped.
+func Find(q Query) []Installation {
+	var installations []Installation
+	for _, b := range backends {
+		found, err := b.find(q)
+		if err != nil {
+			fmt.Printf("Failed to read %s configuration: %v\n", b.name, err)
+			continue
+		}
+		installations = append(installations, found...)
+	}
+	return installations
+}
+
+// homePaths joins each of the given relative paths to the home directory.
+func homePaths(paths ...string) []string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	var result []string
+	for _, p := range paths {
+		result = append(result, filepath.Join(homeDir, p))
+	}
+	return result
+}
+
+func (q Query) matchesTitle(title string) bool {
+	normalized := normalizeTitle(title)
+	if normalized == "" {
+		return false
+	}
+	for _, name := range q.Names {
+		if normalizeTitle(name) == normalized {
+			return true
+		}
+	}
+	return false
+}
+
+func (q Query) matchesGOGID(id string) bool {
+	return contains(q.GOGIDs, id)
+}
+
+func (q Query) matchesEpicAppName(appName string) bool {
+	return contains(q.EpicAppNames, appName)
+}
+
+// hasExecutable reports whether the game executable is directly inside dir.
+func (q Query) hasExecutable(dir string) bool {
+	return q.executablePath(dir) != ""
+}
+
+// executablePath returns the path of the game executable directly inside dir,
+// with the case of the file on disk, or "" if it isn't there.
+func (q Query) executablePath(dir string) string {
+	if q.Executable == "" || dir == "" {
+		return ""
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if strings.EqualFold(entry.Name(), q.Executable) {
+			return filepath.Join(dir, entry.Name())
+		}
+	}
+	return ""
+}
+
+// isExecutable reports whether path points at the game executable.
+func (q Query) isExecutable(path string) bool {
+	normalized := strings.ReplaceAll(path, `\`, "/")
+	return q.Executable != "" && strings.EqualFold(filepath.Base(normalized), q.Executable)
+}
+
+// findExecutableDir searches dir and up to depth levels of subdirectories for
+// the game executable and returns the directory containing it.
+func (q Query) findExecutableDir(dir string, depth int) string {
+	if q.hasExecutable(dir) {
+		return dir
+	}
+	if depth == 0 {
+		return ""
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if found := q.findExecutableDir(filepath.Join(dir, entry.Name()), depth-1); found != "" {
+				return found
+			}
+		}
+	}
+	return ""
+}
+
+// normalizeTitle lowercases a title and drops everything but letters and digits,
+// so "Fallout: New Vegas" matches "Fallout New Vegas".
+func normalizeTitle(title string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(title) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func contains(list []string, s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package launchers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory for %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestFindHeroic(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-heroic")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	gameDir := filepath.Join(tmpDir, "Games", "Fallout 3 GOTY")
+	prefixDir := filepath.Join(tmpDir, "Prefixes", "Fallout 3")
+	writeTestFile(t, filepath.Join(tmpDir, "gog_store", "installed.json"), `{"installed": [
+		{"appName": "1454315831", "install_path": "`+gameDir+`", "platform": "windows", "is_dlc": false},
+		{"appName": "1", "install_path": "/somewhere/else", "platform": "windows", "is_dlc": false}
+	]}`)
+	writeTestFile(t, filepath.Join(tmpDir, "GamesConfig", "1454315831.json"), `{"1454315831": {"winePrefix": "`+prefixDir+`"}}`)
+	// Epic titles often differ from the game name, the app name matches instead
+	epicDir := filepath.Join(tmpDir, "Games", "Fallout3Epic")
+	writeTestFile(t, filepath.Join(tmpDir, "legendaryConfig", "legendary", "installed.json"), `{
+		"epic-fallout3": {"app_name": "epic-fallout3", "title": "Fallout 3: GOTY",
+			"install_path": "`+epicDir+`", "executable": "Launcher.exe", "platform": "Windows"},
+		"other": {"app_name": "other", "title": "Other", "install_path": "/somewhere/else", "executable": "Other.exe", "platform": "Windows"}
+	}`)
+
+	found, err := findHeroicIn(tmpDir, Query{Names: []string{"Fallout 3"}, GOGIDs: []string{"1454315831"}, EpicAppNames: []string{"epic-fallout3"}})
+	if err != nil {
+		t.Fatalf("findHeroicIn failed: %v", err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("expected 2 installations, got %d", len(found))
+	}
+	if found[1].GameDir != epicDir {
+		t.Errorf("expected the Epic install to match by app name, got %+v", found[1])
+	}
+	if found[0].GameDir != gameDir || found[0].PrefixPath != prefixDir {
+		t.Errorf("unexpected installation: %+v", found[0])
+	}
+	if expected := []string{"xdg-open", "heroic://launch?appName=1454315831&runner=gog"}; !reflect.DeepEqual(found[0].Command, expected) {
+		t.Errorf("expected command %v, got %v", expected, found[0].Command)
+	}
+}
+
+func TestFindLutris(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-lutris")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFile(t, filepath.Join(tmpDir, "fallout-new-vegas-1700000000.yml"), `game:
+  exe: drive_c/GOG Games/Fallout New Vegas/FalloutNV.exe
+  prefix: /home/user/Games/fallout-new-vegas
+system: {}
+wine:
+  version: lutris-GE-Proton8-26-x86_64
+`)
+	writeTestFile(t, filepath.Join(tmpDir, "other-game-1700000001.yml"), "game:\n  exe: /games/other/other.exe\n")
+
+	found, err := findLutrisIn(tmpDir, Query{Executable: "FalloutNV.exe"})
+	if err != nil {
+		t.Fatalf("findLutrisIn failed: %v", err)
+	}
+	if len(found) != 1 {
+		t.Fatalf("expected 1 installation, got %d", len(found))
+	}
+	expectedDir := "/home/user/Games/fallout-new-vegas/drive_c/GOG Games/Fallout New Vegas"
+	if found[0].GameDir != expectedDir {
+		t.Errorf("expected game dir %q, got %q", expectedDir, found[0].GameDir)
+	}
+	if found[0].PrefixPath != "/home/user/Games/fallout-new-vegas" {
+		t.Errorf("unexpected prefix %q", found[0].PrefixPath)
+	}
+	if expected := []string{"lutris", "lutris:rungame/fallout-new-vegas"}; !reflect.DeepEqual(found[0].Command, expected) {
+		t.Errorf("expected command %v, got %v", expected, found[0].Command)
+	}
+}
+
+func TestFindBottles(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-bottles")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	bottleDir := filepath.Join(tmpDir, "Fallout3")
+	writeTestFile(t, filepath.Join(bottleDir, "bottle.yml"), "Name: Fallout 3\nPath: Fallout3\nCustom_Path: false\nExternal_Programs: {}\n")
+	writeTestFile(t, filepath.Join(bottleDir, "drive_c", "GOG Games", "Fallout 3 GOTY", "Fallout3.exe"), "")
+
+	found, err := findBottlesIn(tmpDir, Query{Executable: "fallout3.exe"})
+	if err != nil {
+		t.Fatalf("findBottlesIn failed: %v", err)
+	}
+	if len(found) != 1 {
+		t.Fatalf("expected 1 installation, got %d", len(found))
+	}
+	if found[0].PrefixPath != bottleDir {
+		t.Errorf("expected prefix %q, got %q", bottleDir, found[0].PrefixPath)
+	}
+	if expected := filepath.Join(bottleDir, "drive_c", "GOG Games", "Fallout 3 GOTY"); found[0].GameDir != expected {
+		t.Errorf("expected game dir %q, got %q", expected, found[0].GameDir)
+	}
+	expected := []string{"bottles-cli", "run", "-b", "Fallout 3", "-e", filepath.Join(found[0].GameDir, "Fallout3.exe")}
+	if !reflect.DeepEqual(found[0].Command, expected) {
+		t.Errorf("expected command %v, got %v", expected, found[0].Command)
+	}
+}
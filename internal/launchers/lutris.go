@@ -0,0 +1,102 @@
+package launchers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// lutrisGameDirs returns the directories Lutris keeps its game YAML files in.
+// Newer versions moved them from ~/.config to ~/.local/share.
+func lutrisGameDirs() []string {
+	return homePaths(
+		filepath.Join(".config", "lutris", "games"),
+		filepath.Join(".local", "share", "lutris", "games"),
+		filepath.Join(".var", "app", "net.lutris.Lutris", "config", "lutris", "games"),
+		filepath.Join(".var", "app", "net.lutris.Lutris", "data", "lutris", "games"),
+	)
+}
+
+// lutrisGameConfig is the part of a Lutris game YAML file we need.
+type lutrisGameConfig struct {
+	Game struct {
+		Exe        string `yaml:"exe"`
+		Prefix     string `yaml:"prefix"`
+		WorkingDir string `yaml:"working_dir"`
+	} `yaml:"game"`
+}
+
+func findLutris(q Query) ([]Installation, error) {
+	var installations []Installation
+	for _, dir := range lutrisGameDirs() {
+		found, err := findLutrisIn(dir, q)
+		if err != nil {
+			return nil, err
+		}
+		installations = append(installations, found...)
+	}
+	return installations, nil
+}
+
+// findLutrisIn looks for the game in the YAML files of a Lutris games directory.
+func findLutrisIn(gamesDir string, q Query) ([]Installation, error) {
+	entries, err := os.ReadDir(gamesDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var installations []Installation
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".yml" {
+			continue
+		}
+
+		path := filepath.Join(gamesDir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var cfg lutrisGameConfig
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+		}
+		if cfg.Game.Exe == "" {
+			continue // Not a Wine game
+		}
+
+		exe := cfg.Game.Exe
+		if !filepath.IsAbs(exe) && cfg.Game.Prefix != "" {
+			exe = filepath.Join(cfg.Game.Prefix, exe)
+		}
+
+		// Files are named <slug>-<timestamp>.yml
+		slug := strings.TrimSuffix(entry.Name(), ".yml")
+		if i := strings.LastIndex(slug, "-"); i > 0 {
+			slug = slug[:i]
+		}
+
+		if !q.isExecutable(exe) && !q.matchesTitle(slug) {
+			continue
+		}
+
+		gameDir := cfg.Game.WorkingDir
+		if gameDir == "" {
+			gameDir = filepath.Dir(exe)
+		}
+		installations = append(installations, Installation{
+			Launcher:   "lutris",
+			Title:      slug,
+			GameDir:    gameDir,
+			PrefixPath: cfg.Game.Prefix,
+			Command:    []string{"lutris", "lutris:rungame/" + slug},
+		})
+	}
+
+	return installations, nil
+}
@@ -0,0 +1,268 @@
+package mod
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bazsalanszky/fusioncore/internal/store"
+)
+
+// Manifest lists the files of a mod as they were installed, so changes made
+// later by tools like BodySlide or xEdit can be detected.
+type Manifest struct {
+	Created time.Time      `json:"created"`
+	Files   []ManifestFile `json:"files"`
+}
+
+// ManifestFile is a file of a mod, relative to the mod folder.
+type ManifestFile struct {
+	Path   string `json:"path"` // Uses forward slashes
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Drift lists how the files of a mod differ from its manifest.
+type Drift struct {
+	Modified []string
+	Deleted  []string
+	Added    []string
+}
+
+// IsClean reports whether the mod folder still matches its manifest.
+func (d *Drift) IsClean() bool {
+	return len(d.Modified) == 0 && len(d.Deleted) == 0 && len(d.Added) == 0
+}
+
+// GetManifestPath returns the path of the manifest of a mod.
+func GetManifestPath(gameID, id string) (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(configDir, "fusion-core", "manifests", gameID, id+".json"), nil
+}
+
+// BuildManifest lists and hashes the files in dir. Files are hashed in parallel.
+func BuildManifest(dir string) (*Manifest, error) {
+	sizes, err := listFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(sizes))
+	for path := range sizes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	hashes, err := hashFiles(dir, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{Created: time.Now().UTC()}
+	for _, path := range paths {
+		manifest.Files = append(manifest.Files, ManifestFile{Path: path, Size: sizes[path], SHA256: hashes[path]})
+	}
+	return manifest, nil
+}
+
+// RecordManifest builds the manifest of a mod's folder and saves it.
+func RecordManifest(gameID string, m *Mod) error {
+	manifest, err := BuildManifest(m.Path)
+	if err != nil {
+		return fmt.Errorf("failed to build manifest of %s: %w", m.Name, err)
+	}
+	path, err := GetManifestPath(gameID, m.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	return store.WriteFile(path, data, 0644)
+}
+
+// LoadManifest loads the manifest of a mod. It returns an error satisfying
+// os.IsNotExist if the mod has none.
+func LoadManifest(gameID string, m *Mod) (*Manifest, error) {
+	path, err := GetManifestPath(gameID, m.ID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest of %s: %w", m.Name, err)
+	}
+	return &manifest, nil
+}
+
+// DeleteManifest removes the manifest of a mod, if it has one.
+func DeleteManifest(gameID string, m *Mod) error {
+	path, err := GetManifestPath(gameID, m.ID)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Verify compares the files in dir with the manifest. Only files whose size
+// is unchanged are hashed, a different size already means a modified file.
+func (manifest *Manifest) Verify(dir string) (*Drift, error) {
+	sizes, err := listFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	drift := &Drift{}
+	expected := make(map[string]ManifestFile, len(manifest.Files))
+	var toHash []string
+	for _, f := range manifest.Files {
+		expected[f.Path] = f
+		size, ok := sizes[f.Path]
+		switch {
+		case !ok:
+			drift.Deleted = append(drift.Deleted, f.Path)
+		case size != f.Size:
+			drift.Modified = append(drift.Modified, f.Path)
+		default:
+			toHash = append(toHash, f.Path)
+		}
+	}
+	for path := range sizes {
+		if _, ok := expected[path]; !ok {
+			drift.Added = append(drift.Added, path)
+		}
+	}
+
+	hashes, err := hashFiles(dir, toHash)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range toHash {
+		if hashes[path] != expected[path].SHA256 {
+			drift.Modified = append(drift.Modified, path)
+		}
+	}
+
+	sort.Strings(drift.Modified)
+	sort.Strings(drift.Deleted)
+	sort.Strings(drift.Added)
+	return drift, nil
+}
+
+// Print prints heading with the number of changed files, then lists them.
+func (d *Drift) Print(heading string) {
+	fmt.Printf("%s: %d modified, %d deleted, %d added\n", heading, len(d.Modified), len(d.Deleted), len(d.Added))
+	for _, path := range d.Modified {
+		fmt.Printf("    modified: %s\n", path)
+	}
+	for _, path := range d.Deleted {
+		fmt.Printf("    deleted:  %s\n", path)
+	}
+	for _, path := range d.Added {
+		fmt.Printf("    added:    %s\n", path)
+	}
+}
+
+// listFiles returns the sizes of the regular files below dir, keyed by
+// their slash separated path relative to dir.
+func listFiles(dir string) (map[string]int64, error) {
+	sizes := make(map[string]int64)
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		sizes[filepath.ToSlash(rel)] = info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files in %s: %w", dir, err)
+	}
+	return sizes, nil
+}
+
+// hashFiles returns the SHA-256 of each of the given files below dir,
+// hashing one file per CPU at a time.
+func hashFiles(dir string, paths []string) (map[string]string, error) {
+	type result struct {
+		path, hash string
+		err        error
+	}
+
+	jobs := make(chan string)
+	results := make(chan result)
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				hash, err := hashFile(filepath.Join(dir, filepath.FromSlash(path)))
+				results <- result{path, hash, err}
+			}
+		}()
+	}
+	go func() {
+		for _, path := range paths {
+			jobs <- path
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	hashes := make(map[string]string, len(paths))
+	var firstErr error
+	for r := range results {
+		if r.err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to hash %s: %w", r.path, r.err)
+		}
+		hashes[r.path] = r.hash
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return hashes, nil
+}
+
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
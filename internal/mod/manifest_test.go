@@ -0,0 +1,85 @@
+package mod
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestManifestVerify(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-manifest")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	t.Setenv("HOME", tmpDir)
+
+	modDir := filepath.Join(tmpDir, "Armor")
+	files := map[string]string{
+		"Armor.esp":                  "plugin",
+		"Armor - Main.ba2":           "archive",
+		"meshes/armor/body.nif":      "mesh",
+		"meshes/armor/gloves.nif":    "gloves",
+		"textures/armor/body_d.dds":  "texture",
+		"textures/armor/body_n.dds":  "normal",
+		"Tools/BodySlide/preset.xml": "preset",
+	}
+	for name, content := range files {
+		path := filepath.Join(modDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	m := New("Armor", modDir, "local", "local", "fallout4")
+	if err := RecordManifest("fallout4", m); err != nil {
+		t.Fatalf("Failed to record manifest: %v", err)
+	}
+	manifest, err := LoadManifest("fallout4", m)
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	if len(manifest.Files) != len(files) || manifest.Files[0].Path != "Armor - Main.ba2" {
+		t.Fatalf("unexpected manifest %+v", manifest.Files)
+	}
+
+	drift, err := manifest.Verify(modDir)
+	if err != nil {
+		t.Fatalf("Failed to verify: %v", err)
+	}
+	if !drift.IsClean() {
+		t.Errorf("expected no changes, got %+v", drift)
+	}
+
+	// Same size, different content
+	os.WriteFile(filepath.Join(modDir, "meshes/armor/body.nif"), []byte("MESH"), 0644)
+	// Different size
+	os.WriteFile(filepath.Join(modDir, "textures/armor/body_n.dds"), []byte("rebuilt normal"), 0644)
+	os.Remove(filepath.Join(modDir, "meshes/armor/gloves.nif"))
+	os.WriteFile(filepath.Join(modDir, "meshes/armor/body_1.nif"), []byte("built"), 0644)
+
+	drift, err = manifest.Verify(modDir)
+	if err != nil {
+		t.Fatalf("Failed to verify: %v", err)
+	}
+	expected := &Drift{
+		Modified: []string{"meshes/armor/body.nif", "textures/armor/body_n.dds"},
+		Deleted:  []string{"meshes/armor/gloves.nif"},
+		Added:    []string{"meshes/armor/body_1.nif"},
+	}
+	if !reflect.DeepEqual(drift, expected) {
+		t.Errorf("expected %+v, got %+v", expected, drift)
+	}
+
+	if err := DeleteManifest("fallout4", m); err != nil {
+		t.Fatalf("Failed to delete manifest: %v", err)
+	}
+	if _, err := LoadManifest("fallout4", m); !os.IsNotExist(err) {
+		t.Errorf("expected the manifest to be gone, got %v", err)
+	}
+}
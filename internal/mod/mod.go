@@ -1,20 +1,44 @@
 package mod
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bazsalanszky/fusioncore/internal/store"
 )
 
 // Mod represents a single mod.
 type Mod struct {
-	Name   string `json:"name"`
+	ID     string `json:"id"`   // Unique and never changes, unlike Name
+	Name   string `json:"name"` // Display name, can be edited freely
 	Path   string `json:"path"`
 	Active bool   `json:"active"`
 	ModID  string `json:"mod_id"`
 	FileID string `json:"file_id"`
 	Game   string `json:"game"`
+
+	Metadata *Metadata `json:"metadata,omitempty"` // Details from Nexus Mods, nil for local mods
+}
+
+// Metadata describes a mod and the installed file as listed on Nexus Mods.
+type Metadata struct {
+	Version      string    `json:"version"` // Latest version of the mod
+	Author       string    `json:"author"`
+	Summary      string    `json:"summary"`
+	CategoryID   int       `json:"category_id"`
+	Category     string    `json:"category"`
+	PictureURL   string    `json:"picture_url"`
+	FileName     string    `json:"file_name"`
+	FileVersion  string    `json:"file_version"` // Version of the installed file
+	FileUploaded time.Time `json:"file_uploaded"`
+	Fetched      time.Time `json:"fetched"`
 }
 
 // GetModsConfigPath returns the path to the mods.json file for a specific game.
@@ -26,6 +50,20 @@ func GetModsConfigPath(gameID string) (string, error) {
 	return filepath.Join(configDir, "fusion-core", gameID+"-mods.json"), nil
 }
 
+// modsFile is the layout of a mods.json file.
+type modsFile struct {
+	Version int    `json:"version"`
+	Mods    []*Mod `json:"mods"`
+}
+
+// modsMigrations upgrade mods.json files written by older releases, see store.Migrate.
+var modsMigrations = []store.Migration{
+	// Version 0 was a bare list of mods
+	func(data []byte) ([]byte, error) {
+		return json.Marshal(map[string]json.RawMessage{"mods": data})
+	},
+}
+
 // LoadMods loads the list of mods for a specific game.
 func LoadMods(gameID string) ([]*Mod, error) {
 	modsPath, err := GetModsConfigPath(gameID)
@@ -33,46 +71,225 @@ func LoadMods(gameID string) ([]*Mod, error) {
 		return nil, err
 	}
 
-	file, err := os.Open(modsPath)
+	mods, err := readMods(modsPath)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return []*Mod{}, nil // Return empty list if file doesn't exist
-		}
-		return nil, fmt.Errorf("failed to open mods.json: %w", err)
+		return nil, err
 	}
-	defer file.Close()
 
-	var mods []*Mod
-	if err := json.NewDecoder(file).Decode(&mods); err != nil {
-		return nil, fmt.Errorf("failed to decode mods.json: %w", err)
+	// Mods saved before IDs existed get one, which is stored right away so
+	// it stays the same on the next load
+	for _, m := range mods {
+		if m.ID == "" {
+			return UpdateMods(gameID, func(mods []*Mod) ([]*Mod, error) { return mods, nil })
+		}
 	}
 
 	return mods, nil
 }
 
-// SaveMods saves the list of mods for a specific game.
+// SaveMods saves the list of mods for a specific game, replacing the stored
+// list. Use UpdateMods to change a list that may have been changed by
+// another process since it was loaded.
 func SaveMods(mods []*Mod, gameID string) error {
 	modsPath, err := GetModsConfigPath(gameID)
 	if err != nil {
 		return err
 	}
 
-	// Create the directory if it doesn't exist.
-	if err := os.MkdirAll(filepath.Dir(modsPath), 0755); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
+	unlock, err := store.Lock(modsPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return writeMods(modsPath, mods)
+}
+
+// Add records the manifest of a newly installed mod and appends it to the
+// mod list of a game. It returns the saved list.
+func Add(gameID string, m *Mod) ([]*Mod, error) {
+	if err := RecordManifest(gameID, m); err != nil {
+		fmt.Printf("Failed to record the files of %s: %v\n", m.Name, err)
+	}
+	return UpdateMods(gameID, func(mods []*Mod) ([]*Mod, error) {
+		return append(mods, m), nil
+	})
+}
+
+// UpdateMods loads the mods of a game, changes them with update and saves
+// the result while holding the lock of the mod list, so changes made by the
+// CLI and the GUI at the same time aren't lost. It returns the saved list.
+func UpdateMods(gameID string, update func(mods []*Mod) ([]*Mod, error)) ([]*Mod, error) {
+	modsPath, err := GetModsConfigPath(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := store.Lock(modsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	mods, err := readMods(modsPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range mods {
+		if m.ID == "" {
+			m.ID = NewID()
+		}
+	}
+
+	mods, err = update(mods)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeMods(modsPath, mods); err != nil {
+		return nil, err
 	}
+	return mods, nil
+}
 
-	file, err := os.Create(modsPath)
+// readMods reads and migrates a mods.json file.
+func readMods(modsPath string) ([]*Mod, error) {
+	data, err := os.ReadFile(modsPath)
 	if err != nil {
-		return fmt.Errorf("failed to create mods.json: %w", err)
+		if os.IsNotExist(err) {
+			return []*Mod{}, nil // Return empty list if file doesn't exist
+		}
+		return nil, fmt.Errorf("failed to open mods.json: %w", err)
 	}
-	defer file.Close()
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(mods); err != nil {
-		return fmt.Errorf("failed to encode mods.json: %w", err)
+	data, err = store.Migrate(data, modsMigrations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", modsPath, err)
+	}
+	var file modsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode %s, backups are kept in %s: %w", modsPath, store.BackupDir(modsPath), err)
 	}
+	if file.Mods == nil {
+		return []*Mod{}, nil
+	}
+	return file.Mods, nil
+}
 
+// writeMods saves a mods.json file. The previous list is kept as a backup
+// and the file is replaced atomically.
+func writeMods(modsPath string, mods []*Mod) error {
+	if mods == nil {
+		mods = []*Mod{}
+	}
+	if err := store.SaveJSON(modsPath, modsFile{Version: len(modsMigrations), Mods: mods}, 0644); err != nil {
+		return fmt.Errorf("failed to save mods.json: %w", err)
+	}
 	return nil
 }
+
+// New returns an inactive mod with a new ID.
+func New(name, path, modID, fileID, gameID string) *Mod {
+	return &Mod{
+		ID:     NewID(),
+		Name:   name,
+		Path:   path,
+		ModID:  modID,
+		FileID: fileID,
+		Game:   gameID,
+	}
+}
+
+// IsFromNexus reports whether the mod was downloaded from Nexus Mods, as
+// opposed to being added from a local file.
+func (m *Mod) IsFromNexus() bool {
+	return IsNexusModID(m.ModID)
+}
+
+// IsNexusModID reports whether a mod ID is one of Nexus Mods. Local mods use
+// "local" instead.
+func IsNexusModID(modID string) bool {
+	_, err := strconv.Atoi(modID)
+	return err == nil
+}
+
+// NewID returns a random mod ID.
+func NewID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to generate mod ID: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// Find returns the mod with the given ID or name. A name shared by several
+// mods is an error listing their IDs.
+func Find(mods []*Mod, idOrName string) (*Mod, error) {
+	for _, m := range mods {
+		if m.ID == idOrName {
+			return m, nil
+		}
+	}
+
+	var matches []*Mod
+	for _, m := range mods {
+		if m.Name == idOrName {
+			matches = append(matches, m)
+		}
+	}
+	// Fall back to ignoring case if nothing matches exactly
+	if len(matches) == 0 {
+		for _, m := range mods {
+			if strings.EqualFold(m.Name, idOrName) {
+				matches = append(matches, m)
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("mod not found: %s", idOrName)
+	case 1:
+		return matches[0], nil
+	default:
+		ids := make([]string, len(matches))
+		for i, m := range matches {
+			ids[i] = m.ID
+		}
+		return nil, fmt.Errorf("%d mods are named %s, use one of their IDs instead: %s", len(matches), idOrName, strings.Join(ids, ", "))
+	}
+}
+
+// Rename changes the display name of a mod. Its files and ID stay the same.
+func Rename(gameID, idOrName, newName string) (*Mod, error) {
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return nil, fmt.Errorf("the mod name can't be empty")
+	}
+
+	var renamed *Mod
+	_, err := UpdateMods(gameID, func(mods []*Mod) ([]*Mod, error) {
+		m, err := Find(mods, idOrName)
+		if err != nil {
+			return nil, err
+		}
+		m.Name = newName
+		renamed = m
+		return mods, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return renamed, nil
+}
+
+// UniqueDir returns dir, or dir with a " (2)", " (3)", ... suffix if dir
+// already exists, so a new mod never shares its folder with another one.
+func UniqueDir(dir string) string {
+	candidate := dir
+	for i := 2; ; i++ {
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s (%d)", dir, i)
+	}
+}
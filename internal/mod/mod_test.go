@@ -0,0 +1,167 @@
+package mod
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	mods := []*Mod{
+		{ID: "a1", Name: "Textures"},
+		{ID: "b2", Name: "Textures"},
+		{ID: "c3", Name: "Weapons"},
+	}
+
+	if m, err := Find(mods, "b2"); err != nil || m != mods[1] {
+		t.Errorf("expected to find the mod by ID, got %v, %v", m, err)
+	}
+	if m, err := Find(mods, "weapons"); err != nil || m != mods[2] {
+		t.Errorf("expected to find the mod by name, got %v, %v", m, err)
+	}
+	if _, err := Find(mods, "Textures"); err == nil || !strings.Contains(err.Error(), "a1, b2") {
+		t.Errorf("expected an ambiguity error listing the IDs, got %v", err)
+	}
+	if _, err := Find(mods, "Armor"); err == nil {
+		t.Errorf("expected an error for a missing mod")
+	}
+}
+
+func TestModIDs(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-mod-ids")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	t.Setenv("HOME", tmpDir)
+
+	// A mod list saved before mods had IDs
+	modsPath, err := GetModsConfigPath("fallout4")
+	if err != nil {
+		t.Fatalf("Failed to get mods path: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(modsPath), 0755); err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+	legacy := `[{"name": "Armor", "path": "/mods/Armor", "active": true, "mod_id": "1", "file_id": "2", "game": "fallout4"}]`
+	if err := os.WriteFile(modsPath, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write mods: %v", err)
+	}
+
+	mods, err := LoadMods("fallout4")
+	if err != nil {
+		t.Fatalf("Failed to load mods: %v", err)
+	}
+	if len(mods) != 1 || mods[0].ID == "" {
+		t.Fatalf("expected the mod to get an ID, got %+v", mods)
+	}
+	id := mods[0].ID
+
+	renamed, err := Rename("fallout4", "Armor", "Better Armor")
+	if err != nil {
+		t.Fatalf("Failed to rename mod: %v", err)
+	}
+	if renamed.ID != id || renamed.Path != "/mods/Armor" {
+		t.Errorf("expected the ID and path to stay the same, got %+v", renamed)
+	}
+
+	mods, err = LoadMods("fallout4")
+	if err != nil {
+		t.Fatalf("Failed to load mods: %v", err)
+	}
+	if mods[0].ID != id || mods[0].Name != "Better Armor" {
+		t.Errorf("expected the renamed mod with ID %s, got %+v", id, mods[0])
+	}
+
+	if _, err := Rename("fallout4", id, "  "); err == nil {
+		t.Errorf("expected an error for an empty name")
+	}
+}
+
+func TestUniqueDir(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-unique-dir")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dir := filepath.Join(tmpDir, "Mod")
+	if got := UniqueDir(dir); got != dir {
+		t.Errorf("expected %s, got %s", dir, got)
+	}
+	os.Mkdir(dir, 0755)
+	os.Mkdir(dir+" (2)", 0755)
+	if got := UniqueDir(dir); got != dir+" (3)" {
+		t.Errorf("expected %s (3), got %s", dir, got)
+	}
+}
+
+func TestModsFileVersion(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-mods-version")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	t.Setenv("HOME", tmpDir)
+
+	mods := []*Mod{New("Armor", "/mods/Armor", "1", "2", "fallout4")}
+	if err := SaveMods(mods, "fallout4"); err != nil {
+		t.Fatalf("Failed to save mods: %v", err)
+	}
+	modsPath, _ := GetModsConfigPath("fallout4")
+	data, err := os.ReadFile(modsPath)
+	if err != nil {
+		t.Fatalf("Failed to read mods: %v", err)
+	}
+	if !strings.Contains(string(data), `"version": 1`) {
+		t.Errorf("expected a versioned file, got %s", data)
+	}
+
+	// Files from a newer release are not overwritten
+	if err := os.WriteFile(modsPath, []byte(`{"version": 99, "mods": []}`), 0644); err != nil {
+		t.Fatalf("Failed to write mods: %v", err)
+	}
+	if _, err := LoadMods("fallout4"); err == nil {
+		t.Errorf("expected an error for a newer mods file")
+	}
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-mods-update")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	t.Setenv("HOME", tmpDir)
+
+	// Every update must see the changes of the ones before it
+	const writers = 10
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := UpdateMods("fallout4", func(mods []*Mod) ([]*Mod, error) {
+				return append(mods, New(fmt.Sprintf("Mod %d", i), "", "local", "local", "fallout4")), nil
+			})
+			if err != nil {
+				t.Errorf("Failed to update mods: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	mods, err := LoadMods("fallout4")
+	if err != nil {
+		t.Fatalf("Failed to load mods: %v", err)
+	}
+	if len(mods) != writers {
+		t.Errorf("expected %d mods, got %d", writers, len(mods))
+	}
+}
@@ -0,0 +1,137 @@
+package modlist
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bazsalanszky/fusioncore/internal/config"
+	"github.com/bazsalanszky/fusioncore/internal/games"
+	"github.com/bazsalanszky/fusioncore/internal/mod"
+	"github.com/bazsalanszky/fusioncore/internal/nexus"
+	"github.com/bazsalanszky/fusioncore/internal/vfs"
+)
+
+// InstallCollection installs the mods of a Nexus collection that aren't
+// installed yet, in the order of the collection, and applies its plugin
+// order. Optional mods are only installed if withOptional returns true for
+// them. Mods that aren't part of the collection stay active and go before
+// its mods.
+func InstallCollection(c *nexus.Collection, game *games.Game, apiKey string, withOptional func(*nexus.CollectionMod) bool) error {
+	if c.Game != game.NexusName {
+		return fmt.Errorf("the collection is for %s but the current game is %s", c.Game, game.Name)
+	}
+
+	installed, err := mod.LoadMods(game.ID)
+	if err != nil {
+		return err
+	}
+
+	var members []*mod.Mod // Mods of the collection in its order
+	var failed []string
+	for i := range c.Mods {
+		cm := &c.Mods[i]
+		if cm.Optional && (withOptional == nil || !withOptional(cm)) {
+			continue
+		}
+
+		var existing *mod.Mod
+		for _, m := range installed {
+			if cm.Source == "nexus" && m.ModID == cm.ModID && m.FileID == cm.FileID {
+				existing = m
+				break
+			}
+		}
+		if existing != nil {
+			members = append(members, existing)
+			continue
+		}
+
+		fmt.Printf("Installing %s %s\n", cm.Name, cm.Version)
+		m, err := c.InstallMod(cm, game, apiKey, nil)
+		if err != nil {
+			fmt.Printf("Failed to install %s: %v\n", cm.Name, err)
+			failed = append(failed, cm.Name)
+			continue
+		}
+		if _, err := mod.Add(game.ID, m); err != nil {
+			return err
+		}
+		members = append(members, m)
+	}
+
+	mods, err := mod.UpdateMods(game.ID, func(mods []*mod.Mod) ([]*mod.Mod, error) {
+		byID := make(map[string]*mod.Mod, len(mods))
+		for _, m := range mods {
+			byID[m.ID] = m
+		}
+		var collection []*mod.Mod
+		for _, m := range members {
+			if current := byID[m.ID]; current != nil {
+				current.Active = true
+				collection = append(collection, current)
+				delete(byID, m.ID)
+			}
+		}
+		var ordered []*mod.Mod
+		for _, m := range mods {
+			if byID[m.ID] != nil {
+				ordered = append(ordered, m)
+			}
+		}
+		return append(ordered, collection...), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(c.Plugins) > 0 {
+		if err := applyCollectionPlugins(c, game); err != nil {
+			return err
+		}
+	}
+	if err := vfs.UpdateLoadOrder(mods, game); err != nil {
+		return err
+	}
+	if err := vfs.SyncLinks(); err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d mods of the collection are missing: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// applyCollectionPlugins writes the plugin order of a collection. Plugins
+// the collection doesn't know keep their place after its plugins.
+func applyCollectionPlugins(c *nexus.Collection, game *games.Game) error {
+	prefixPath, err := config.FindGamePrefix(game)
+	if err != nil {
+		return fmt.Errorf("failed to find the prefix to write the plugin order to: %w", err)
+	}
+	current, err := config.ReadGamePlugins(prefixPath, game)
+	if err != nil {
+		return err
+	}
+
+	var lines []string
+	listed := make(map[string]bool)
+	for _, p := range c.Plugins {
+		listed[strings.ToLower(p.Name)] = true
+		if game.IsVanillaFile(p.Name) {
+			continue
+		}
+		switch {
+		case game.PluginsFormat == games.PluginsFormatAsterisk && p.Enabled:
+			lines = append(lines, "*"+p.Name)
+		case game.PluginsFormat == games.PluginsFormatAsterisk, p.Enabled:
+			lines = append(lines, p.Name)
+		}
+	}
+	for _, line := range current {
+		if !listed[strings.ToLower(strings.TrimPrefix(line, "*"))] {
+			lines = append(lines, line)
+		}
+	}
+	return config.WriteGamePlugins(prefixPath, game, lines)
+}
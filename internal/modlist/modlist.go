@@ -0,0 +1,328 @@
+// Package modlist exports the mod setup of a game to a portable file and
+// reproduces it on another machine.
+package modlist
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bazsalanszky/fusioncore/internal/config"
+	"github.com/bazsalanszky/fusioncore/internal/games"
+	"github.com/bazsalanszky/fusioncore/internal/mod"
+	"github.com/bazsalanszky/fusioncore/internal/nexus"
+	"github.com/bazsalanszky/fusioncore/internal/vfs"
+)
+
+// FormatVersion is the version of the modlist file format.
+const FormatVersion = 1
+
+// Modlist is a mod setup of a game.
+type Modlist struct {
+	Version     int       `json:"version"`
+	Game        string    `json:"game"`
+	GameVersion string    `json:"game_version,omitempty"` // Version of the game executable, if it was found
+	Exported    time.Time `json:"exported"`
+	Mods        []Entry   `json:"mods"`              // In mod order
+	Plugins     []string  `json:"plugins,omitempty"` // Lines of the game's plugins file
+}
+
+// Entry is a mod of a modlist.
+type Entry struct {
+	Name    string             `json:"name"`
+	ModID   string             `json:"mod_id"`
+	FileID  string             `json:"file_id"`
+	Version string             `json:"version,omitempty"`
+	Active  bool               `json:"active"`
+	Files   []mod.ManifestFile `json:"files"` // As installed, see mod.Manifest
+}
+
+// IsFromNexus reports whether the mod is from Nexus Mods, the same way as
+// mod.Mod.IsFromNexus.
+func (e *Entry) IsFromNexus() bool {
+	return mod.IsNexusModID(e.ModID)
+}
+
+// CanDownload reports whether the file of the mod can be downloaded from
+// Nexus Mods, which takes a file ID too.
+func (e *Entry) CanDownload() bool {
+	return e.IsFromNexus() && e.FileID != ""
+}
+
+// matches reports whether an installed mod is the one of the entry. Nexus
+// mods match by mod and file ID, others by name. Without exact, mods that
+// miss a file ID on either side, like ones imported without an API key,
+// match by mod ID or name instead.
+func (e *Entry) matches(m *mod.Mod, exact bool) bool {
+	if e.IsFromNexus() && m.IsFromNexus() && e.FileID != "" && m.FileID != "" {
+		return m.ModID == e.ModID && m.FileID == e.FileID
+	}
+	if exact {
+		return !e.IsFromNexus() && !m.IsFromNexus() && strings.EqualFold(m.Name, e.Name)
+	}
+	if e.FileID != "" && m.FileID != "" {
+		return false
+	}
+	return e.IsFromNexus() && m.ModID == e.ModID || strings.EqualFold(m.Name, e.Name)
+}
+
+// Export builds the modlist of a game. The files of each mod are taken from
+// its manifest, so changes made after installing don't end up in the list.
+func Export(game *games.Game) (*Modlist, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	mods, err := mod.LoadMods(game.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &Modlist{Version: FormatVersion, Game: game.ID, Exported: time.Now().UTC()}
+	if gameDir, err := game.FindGameDirWithCustomPath(cfg.GamePaths[game.ID]); err == nil {
+		if v, err := game.ExecutableVersion(gameDir); err == nil {
+			list.GameVersion = v.String()
+		}
+	}
+
+	for _, m := range mods {
+		manifest, err := mod.LoadManifest(game.ID, m)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				return nil, err
+			}
+			if manifest, err = mod.BuildManifest(m.Path); err != nil {
+				return nil, err
+			}
+		}
+		entry := Entry{Name: m.Name, ModID: m.ModID, FileID: m.FileID, Active: m.Active, Files: manifest.Files}
+		if m.Metadata != nil {
+			entry.Version = m.Metadata.FileVersion
+		}
+		switch {
+		case !entry.IsFromNexus():
+			fmt.Printf("%s wasn't downloaded from Nexus Mods, it has to be copied to other machines by hand\n", m.Name)
+		case !entry.CanDownload():
+			fmt.Printf("%s has no Nexus file ID, it has to be downloaded on other machines by hand\n", m.Name)
+		}
+		list.Mods = append(list.Mods, entry)
+	}
+
+	if prefixPath, err := config.FindGamePrefix(game); err == nil {
+		if list.Plugins, err = config.ReadGamePlugins(prefixPath, game); err != nil {
+			return nil, err
+		}
+	} else {
+		fmt.Printf("Not exporting the plugin order: %v\n", err)
+	}
+	return list, nil
+}
+
+// Save writes a modlist to a file.
+func Save(path string, list *Modlist) error {
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode modlist: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write modlist: %w", err)
+	}
+	return nil
+}
+
+// Load reads a modlist from a file.
+func Load(path string) (*Modlist, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read modlist: %w", err)
+	}
+	var list Modlist
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode modlist: %w", err)
+	}
+	if list.Version > FormatVersion {
+		return nil, fmt.Errorf("the modlist was written by a newer version of Fusion Core (format %d, this one reads up to %d)", list.Version, FormatVersion)
+	}
+	return &list, nil
+}
+
+// Match returns the installed mod of each entry of the modlist, nil for the
+// ones that aren't installed. Nexus mods match by mod and file ID, others by
+// name, see Entry.matches.
+func (list *Modlist) Match(installed []*mod.Mod) []*mod.Mod {
+	matched := make([]*mod.Mod, len(list.Mods))
+	used := make(map[*mod.Mod]bool)
+	// Exact matches go first so a fallback can't take the mod of another entry
+	for _, exact := range []bool{true, false} {
+		for i, e := range list.Mods {
+			if matched[i] != nil {
+				continue
+			}
+			for _, m := range installed {
+				if !used[m] && e.matches(m, exact) {
+					matched[i] = m
+					used[m] = true
+					break
+				}
+			}
+		}
+	}
+	return matched
+}
+
+// CheckGameVersion warns if the modlist was exported from another version
+// of the game than the installed one.
+func (list *Modlist) CheckGameVersion(game *games.Game) {
+	if list.GameVersion == "" {
+		return
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return
+	}
+	gameDir, err := game.FindGameDirWithCustomPath(cfg.GamePaths[game.ID])
+	if err != nil {
+		return
+	}
+	if v, err := game.ExecutableVersion(gameDir); err == nil && v.String() != list.GameVersion {
+		fmt.Printf("Warning: the modlist was made for %s %s, this is %s\n", game.Name, list.GameVersion, v)
+	}
+}
+
+// Apply reproduces the modlist for the current game. Missing Nexus mods are
+// downloaded and their files checked against the hashes of the modlist,
+// mods are ordered and activated as listed and the plugin order is written.
+// The files of mods that are already installed are checked too, and
+// differences are reported. Installed mods that aren't in the modlist are
+// deactivated and moved last.
+func (list *Modlist) Apply(game *games.Game, apiKey string) error {
+	if list.Game != game.ID {
+		return fmt.Errorf("the modlist is for %s, switch to it with 'switch-game %s' first", list.Game, list.Game)
+	}
+	list.CheckGameVersion(game)
+
+	installed, err := mod.LoadMods(game.ID)
+	if err != nil {
+		return err
+	}
+	matched := list.Match(installed)
+
+	differing, err := list.CheckInstalled(matched)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for i, e := range list.Mods {
+		if matched[i] != nil {
+			continue
+		}
+		if !e.IsFromNexus() {
+			fmt.Printf("Skipping %s, copy it to the mods folder and add it by hand\n", e.Name)
+			failed = append(failed, e.Name)
+			continue
+		}
+		if !e.CanDownload() {
+			fmt.Printf("Skipping %s, the modlist has no Nexus file ID for it, download mod %s by hand\n", e.Name, e.ModID)
+			failed = append(failed, e.Name)
+			continue
+		}
+
+		fmt.Printf("Downloading %s (mod %s, file %s)\n", e.Name, e.ModID, e.FileID)
+		info := &nexus.NxmInfo{Game: game.NexusName, ModID: e.ModID, FileID: e.FileID}
+		m, err := nexus.Install(info, game, apiKey, nil)
+		if err != nil {
+			fmt.Printf("Failed to download %s: %v\n", e.Name, err)
+			failed = append(failed, e.Name)
+			continue
+		}
+		manifest := &mod.Manifest{Files: e.Files}
+		drift, err := manifest.Verify(m.Path)
+		if err != nil || !drift.IsClean() {
+			fmt.Printf("The files of %s don't match the modlist, removing it\n", e.Name)
+			os.RemoveAll(m.Path)
+			failed = append(failed, e.Name)
+			continue
+		}
+		m.Name = e.Name
+		if _, err := mod.Add(game.ID, m); err != nil {
+			return err
+		}
+		matched[i] = m
+	}
+
+	mods, err := mod.UpdateMods(game.ID, func(mods []*mod.Mod) ([]*mod.Mod, error) {
+		byID := make(map[string]*mod.Mod, len(mods))
+		for _, m := range mods {
+			byID[m.ID] = m
+		}
+		var ordered []*mod.Mod
+		for i, m := range matched {
+			if m == nil || byID[m.ID] == nil {
+				continue
+			}
+			m = byID[m.ID]
+			m.Active = list.Mods[i].Active
+			ordered = append(ordered, m)
+			delete(byID, m.ID)
+		}
+		for _, m := range mods {
+			if byID[m.ID] != nil {
+				m.Active = false
+				ordered = append(ordered, m)
+			}
+		}
+		return ordered, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(list.Plugins) > 0 {
+		prefixPath, err := config.FindGamePrefix(game)
+		if err != nil {
+			return fmt.Errorf("failed to find the prefix to write the plugin order to: %w", err)
+		}
+		if err := config.WriteGamePlugins(prefixPath, game, list.Plugins); err != nil {
+			return err
+		}
+	}
+	if err := vfs.UpdateLoadOrder(mods, game); err != nil {
+		return err
+	}
+	if err := vfs.SyncLinks(); err != nil {
+		return err
+	}
+
+	if len(differing) > 0 {
+		fmt.Printf("The installed files of %d mods differ from the modlist: %s\n", len(differing), strings.Join(differing, ", "))
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d mods of the modlist are missing: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// CheckInstalled checks the files of the installed mods matched to the
+// entries of the modlist against its hashes. The differences are printed
+// and the names of the mods that differ are returned.
+func (list *Modlist) CheckInstalled(matched []*mod.Mod) ([]string, error) {
+	var differing []string
+	for i, e := range list.Mods {
+		if matched[i] == nil || len(e.Files) == 0 {
+			continue
+		}
+		manifest := &mod.Manifest{Files: e.Files}
+		drift, err := manifest.Verify(matched[i].Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check the files of %s: %w", e.Name, err)
+		}
+		if !drift.IsClean() {
+			drift.Print(e.Name + " differs from the modlist")
+			differing = append(differing, e.Name)
+		}
+	}
+	return differing, nil
+}
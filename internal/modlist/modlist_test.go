@@ -0,0 +1,128 @@
+package modlist
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazsalanszky/fusioncore/internal/games"
+	"github.com/bazsalanszky/fusioncore/internal/mod"
+)
+
+func TestExportAndMatch(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-modlist")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	t.Setenv("HOME", tmpDir)
+
+	game, err := games.GetGameByID("fallout76")
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+
+	var installed []*mod.Mod
+	for _, m := range []struct{ name, modID, fileID string }{
+		{"Perk Loadout Manager", "1000", "2000"},
+		{"My Tweaks", "local", "local"},
+	} {
+		dir := filepath.Join(tmpDir, "mods", m.name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create mod dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, m.name+".ba2"), []byte(m.name), 0644); err != nil {
+			t.Fatalf("Failed to write archive: %v", err)
+		}
+		newMod := mod.New(m.name, dir, m.modID, m.fileID, game.ID)
+		newMod.Active = true
+		newMod.Metadata = &mod.Metadata{FileVersion: "1.2"}
+		if installed, err = mod.Add(game.ID, newMod); err != nil {
+			t.Fatalf("Failed to add mod: %v", err)
+		}
+	}
+
+	list, err := Export(game)
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	path := filepath.Join(tmpDir, "team.json")
+	if err := Save(path, list); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	list, err = Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	if list.Game != "fallout76" || len(list.Mods) != 2 {
+		t.Fatalf("unexpected modlist %+v", list)
+	}
+	plm := list.Mods[0]
+	if !plm.IsFromNexus() || plm.Version != "1.2" || !plm.Active || len(plm.Files) != 1 || plm.Files[0].SHA256 == "" {
+		t.Errorf("unexpected entry %+v", plm)
+	}
+	if list.Mods[1].IsFromNexus() {
+		t.Errorf("expected a local mod, got %+v", list.Mods[1])
+	}
+
+	// Another machine with only the local mod, under a different ID
+	other := []*mod.Mod{mod.New("my tweaks", "/elsewhere", "local", "local", game.ID)}
+	matched := list.Match(other)
+	if matched[0] != nil || matched[1] != other[0] {
+		t.Errorf("expected only the local mod to match, got %v", matched)
+	}
+	matched = list.Match(installed)
+	if matched[0] != installed[0] || matched[1] != installed[1] {
+		t.Errorf("expected both mods to match, got %v", matched)
+	}
+
+	// Installed mods are checked against the hashes of the modlist
+	if differing, err := list.CheckInstalled(matched); err != nil || len(differing) != 0 {
+		t.Errorf("expected the installed mods to match the modlist, got %v, %v", differing, err)
+	}
+	if err := os.WriteFile(filepath.Join(installed[1].Path, "My Tweaks.ba2"), []byte("changed"), 0644); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+	differing, err := list.CheckInstalled(matched)
+	if err != nil || len(differing) != 1 || differing[0] != "My Tweaks" {
+		t.Errorf("expected My Tweaks to differ, got %v, %v", differing, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version": 99}`), 0644); err != nil {
+		t.Fatalf("Failed to write modlist: %v", err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Errorf("expected an error for a newer modlist")
+	}
+}
+
+func TestMatchWithoutFileID(t *testing.T) {
+	// Mods imported from Vortex without an API key or from MO2 without
+	// installedFiles have a Nexus mod ID but no file ID
+	list := &Modlist{Mods: []Entry{
+		{Name: "Perk Loadout Manager", ModID: "1000", FileID: "2000"},
+		{Name: "Better Inventory", ModID: "3000"},
+		{Name: "Quick Loot", ModID: "4000", FileID: "4001"},
+	}}
+	installed := []*mod.Mod{
+		mod.New("Quick Loot (imported)", "/mods/ql", "4000", "", "fallout76"),
+		mod.New("Better Inventory", "/mods/bi", "3000", "", "fallout76"),
+		mod.New("Perk Loadout Manager", "/mods/plm", "1000", "2000", "fallout76"),
+	}
+
+	if !list.Mods[1].IsFromNexus() || list.Mods[1].CanDownload() {
+		t.Errorf("expected a Nexus mod that can't be downloaded, got %+v", list.Mods[1])
+	}
+	matched := list.Match(installed)
+	if matched[0] != installed[2] || matched[1] != installed[1] || matched[2] != installed[0] {
+		t.Errorf("expected the mods without file IDs to match by mod ID, got %v", matched)
+	}
+
+	// A different file of the same mod doesn't match
+	other := []*mod.Mod{mod.New("Perk Loadout Manager", "/mods/plm", "1000", "2001", "fallout76")}
+	if matched := list.Match(other); matched[0] != nil {
+		t.Errorf("expected another file of the mod not to match, got %v", matched)
+	}
+}
@@ -54,9 +54,11 @@ func GetDownloadURL(info *NxmInfo, apiKey string) (string, error) {
 		return "", fmt.Errorf("NxmInfo is nil")
 	}
 
-	apiURL := fmt.Sprintf("https://api.nexusmods.com/v1/games/%s/mods/%s/files/%s/download_link.json?key=%s&expires=%s", info.Game, info.ModID, info.FileID, info.Key, info.Expires)
-
-	fmt.Println("Api key:", apiURL)
+	apiURL := fmt.Sprintf("%s/v1/games/%s/mods/%s/files/%s/download_link.json", apiBaseURL, info.Game, info.ModID, info.FileID)
+	// Links from the website carry a key, premium accounts can download without one
+	if info.Key != "" {
+		apiURL += fmt.Sprintf("?key=%s&expires=%s", info.Key, info.Expires)
+	}
 
 	req, err := http.NewRequest("GET", apiURL, nil)
 	if err != nil {
@@ -0,0 +1,406 @@
+package nexus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bazsalanszky/fusioncore/internal/extractor"
+	"github.com/bazsalanszky/fusioncore/internal/fomod"
+	"github.com/bazsalanszky/fusioncore/internal/games"
+	"github.com/bazsalanszky/fusioncore/internal/mod"
+)
+
+// graphqlURL is the GraphQL API of Nexus Mods, replaced in tests.
+var graphqlURL = "https://api.nexusmods.com/v2/graphql"
+
+// Collection is a revision of a Nexus collection.
+type Collection struct {
+	Slug     string
+	Revision int
+	Name     string
+	Game     string             // Nexus name of the game
+	Mods     []CollectionMod    // In install order, mods win file conflicts against the ones before them
+	Plugins  []CollectionPlugin // In load order, empty if the collection doesn't set one
+}
+
+// CollectionMod is a mod file of a collection.
+type CollectionMod struct {
+	Name     string
+	Version  string
+	Optional bool
+	Source   string // "nexus" for mods on Nexus Mods, others have to be installed by hand
+	URL      string // Where to get mods that aren't on Nexus Mods
+	ModID    string
+	FileID   string
+	MD5      string
+	Choices  []fomod.Choice // Options of its FOMOD installer, nil if it has none
+	phase    int
+	fileName string
+}
+
+// CollectionPlugin is a plugin in the load order of a collection.
+type CollectionPlugin struct {
+	Name    string
+	Enabled bool
+}
+
+// manifest is the collection.json in the archive of a collection revision,
+// as written by Vortex.
+type manifest struct {
+	Info struct {
+		Name       string `json:"name"`
+		DomainName string `json:"domainName"`
+	} `json:"info"`
+	Mods []struct {
+		Name     string `json:"name"`
+		Version  string `json:"version"`
+		Optional bool   `json:"optional"`
+		Phase    int    `json:"phase"`
+		Source   struct {
+			Type            string `json:"type"`
+			ModID           int    `json:"modId"`
+			FileID          int    `json:"fileId"`
+			MD5             string `json:"md5"`
+			LogicalFilename string `json:"logicalFilename"`
+			URL             string `json:"url"`
+		} `json:"source"`
+		Choices *struct {
+			Type    string `json:"type"`
+			Options []struct {
+				Name   string `json:"name"`
+				Groups []struct {
+					Name    string `json:"name"`
+					Choices []struct {
+						Name string `json:"name"`
+					} `json:"choices"`
+				} `json:"groups"`
+			} `json:"options"`
+		} `json:"choices"`
+	} `json:"mods"`
+	ModRules []struct {
+		Type      string      `json:"type"` // "before" or "after", others don't affect the order
+		Source    manifestRef `json:"source"`
+		Reference manifestRef `json:"reference"`
+	} `json:"modRules"`
+	Plugins []struct {
+		Name    string `json:"name"`
+		Enabled bool   `json:"enabled"`
+	} `json:"plugins"`
+	PluginRules struct {
+		Plugins []struct {
+			Name  string   `json:"name"`
+			After []string `json:"after"`
+		} `json:"plugins"`
+	} `json:"pluginRules"`
+}
+
+// manifestRef identifies a mod of the manifest in a rule.
+type manifestRef struct {
+	FileMD5         string `json:"fileMD5"`
+	LogicalFileName string `json:"logicalFileName"`
+}
+
+const revisionQuery = `query CollectionRevision($slug: String!, $revision: Int, $domainName: String) {
+  collectionRevision(slug: $slug, revision: $revision, domainName: $domainName, viewAdultContent: true) {
+    revisionNumber
+    downloadLink
+    collection { name }
+  }
+}`
+
+// GetCollection fetches a revision of a collection: its details through the
+// GraphQL API, then its manifest from the collection archive.
+func GetCollection(info *NxmInfo, apiKey string) (*Collection, error) {
+	revision, err := strconv.Atoi(info.Revision)
+	if err != nil {
+		return nil, fmt.Errorf("invalid collection revision %q", info.Revision)
+	}
+
+	var result struct {
+		CollectionRevision *struct {
+			RevisionNumber int    `json:"revisionNumber"`
+			DownloadLink   string `json:"downloadLink"`
+			Collection     struct {
+				Name string `json:"name"`
+			} `json:"collection"`
+		} `json:"collectionRevision"`
+	}
+	variables := map[string]interface{}{"slug": info.Collection, "revision": revision, "domainName": info.Game}
+	if err := graphql(revisionQuery, variables, apiKey, &result); err != nil {
+		return nil, fmt.Errorf("failed to get collection %s: %w", info.Collection, err)
+	}
+	if result.CollectionRevision == nil {
+		return nil, fmt.Errorf("collection %s has no revision %d", info.Collection, revision)
+	}
+
+	// The download link is an API path that returns the download URLs
+	var links struct {
+		DownloadLinks []struct {
+			URI string `json:"URI"`
+		} `json:"download_links"`
+	}
+	linkPath := result.CollectionRevision.DownloadLink
+	if u, err := url.Parse(linkPath); err == nil && u.IsAbs() {
+		linkPath = u.RequestURI()
+	}
+	if info.Key != "" {
+		linkPath += "?key=" + url.QueryEscape(info.Key) + "&expires=" + url.QueryEscape(info.Expires)
+	}
+	if err := getJSON(linkPath, apiKey, &links); err != nil {
+		return nil, fmt.Errorf("failed to get the download link of collection %s: %w", info.Collection, err)
+	}
+	if len(links.DownloadLinks) == 0 {
+		return nil, fmt.Errorf("no download links for collection %s", info.Collection)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "fusion-core-collection")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	archivePath, err := DownloadFile(links.DownloadLinks[0].URI, tmpDir, nil)
+	if err != nil {
+		return nil, err
+	}
+	extractDir := filepath.Join(tmpDir, "collection")
+	if err := extractor.Extract(archivePath, extractDir); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(extractDir, "collection.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the collection manifest: %w", err)
+	}
+
+	collection, err := parseCollection(data)
+	if err != nil {
+		return nil, err
+	}
+	collection.Slug = info.Collection
+	collection.Revision = result.CollectionRevision.RevisionNumber
+	collection.Name = result.CollectionRevision.Collection.Name
+	if collection.Game == "" {
+		collection.Game = info.Game
+	}
+	return collection, nil
+}
+
+// graphql runs a query against the GraphQL API and decodes its data.
+func graphql(query string, variables map[string]interface{}, apiKey string, v interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", graphqlURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("apikey", apiKey)
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set("accept", "application/json")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to perform request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API request failed with status: %s", resp.Status)
+	}
+
+	var response struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(response.Errors) > 0 {
+		return fmt.Errorf("API error: %s", response.Errors[0].Message)
+	}
+	if err := json.Unmarshal(response.Data, v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// parseCollection reads a collection manifest. Mods are sorted by install
+// phase, then by the rules of the collection, and plugins by its plugin
+// rules.
+func parseCollection(data []byte) (*Collection, error) {
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to decode the collection manifest: %w", err)
+	}
+
+	collection := &Collection{Name: m.Info.Name, Game: m.Info.DomainName}
+	for _, entry := range m.Mods {
+		cm := CollectionMod{
+			Name:     entry.Name,
+			Version:  entry.Version,
+			Optional: entry.Optional,
+			Source:   entry.Source.Type,
+			URL:      entry.Source.URL,
+			MD5:      entry.Source.MD5,
+			phase:    entry.Phase,
+			fileName: entry.Source.LogicalFilename,
+		}
+		if entry.Source.Type == "nexus" {
+			cm.ModID = strconv.Itoa(entry.Source.ModID)
+			cm.FileID = strconv.Itoa(entry.Source.FileID)
+		}
+		if entry.Choices != nil && entry.Choices.Type == "fomod" {
+			cm.Choices = []fomod.Choice{}
+			for _, step := range entry.Choices.Options {
+				for _, group := range step.Groups {
+					for _, choice := range group.Choices {
+						cm.Choices = append(cm.Choices, fomod.Choice{Step: step.Name, Group: group.Name, Plugin: choice.Name})
+					}
+				}
+			}
+		}
+		collection.Mods = append(collection.Mods, cm)
+	}
+
+	// Mods must come after the mods they win against
+	after := make([][]int, len(collection.Mods))
+	find := func(ref manifestRef) int {
+		for i, cm := range collection.Mods {
+			if ref.FileMD5 != "" && strings.EqualFold(ref.FileMD5, cm.MD5) ||
+				ref.FileMD5 == "" && ref.LogicalFileName != "" && ref.LogicalFileName == cm.fileName {
+				return i
+			}
+		}
+		return -1
+	}
+	for _, rule := range m.ModRules {
+		source, reference := find(rule.Source), find(rule.Reference)
+		if source < 0 || reference < 0 || source == reference {
+			continue
+		}
+		switch rule.Type {
+		case "after":
+			after[source] = append(after[source], reference)
+		case "before":
+			after[reference] = append(after[reference], source)
+		}
+	}
+	order := make([]int, len(collection.Mods))
+	for i := range order {
+		order[i] = i
+	}
+	// Phases are installed one after the other, so rules only order the
+	// mods within a phase
+	sort.SliceStable(order, func(a, b int) bool { return collection.Mods[order[a]].phase < collection.Mods[order[b]].phase })
+	var sorted []CollectionMod
+	for start := 0; start < len(order); {
+		phase := collection.Mods[order[start]].phase
+		end := start + 1
+		for end < len(order) && collection.Mods[order[end]].phase == phase {
+			end++
+		}
+		for _, i := range topoSort(order[start:end], after) {
+			sorted = append(sorted, collection.Mods[i])
+		}
+		start = end
+	}
+	collection.Mods = sorted
+
+	byName := make(map[string]int)
+	for i, p := range m.Plugins {
+		collection.Plugins = append(collection.Plugins, CollectionPlugin{Name: p.Name, Enabled: p.Enabled})
+		byName[strings.ToLower(p.Name)] = i
+	}
+	pluginsAfter := make([][]int, len(collection.Plugins))
+	for _, rule := range m.PluginRules.Plugins {
+		i, ok := byName[strings.ToLower(rule.Name)]
+		if !ok {
+			continue
+		}
+		for _, name := range rule.After {
+			if j, ok := byName[strings.ToLower(name)]; ok && j != i {
+				pluginsAfter[i] = append(pluginsAfter[i], j)
+			}
+		}
+	}
+	pluginOrder := make([]int, len(collection.Plugins))
+	for i := range pluginOrder {
+		pluginOrder[i] = i
+	}
+	var plugins []CollectionPlugin
+	for _, i := range topoSort(pluginOrder, pluginsAfter) {
+		plugins = append(plugins, collection.Plugins[i])
+	}
+	collection.Plugins = plugins
+	return collection, nil
+}
+
+// topoSort orders items so that each one comes after the items listed in
+// after, keeping the given order otherwise. Items of after that aren't in
+// order are ignored. Cycles are broken by placing the first remaining item.
+func topoSort(order []int, after [][]int) []int {
+	var sorted []int
+	placed := make(map[int]bool)
+	inOrder := make(map[int]bool, len(order))
+	for _, i := range order {
+		inOrder[i] = true
+	}
+	for len(sorted) < len(order) {
+		progress := false
+		for _, i := range order {
+			if placed[i] {
+				continue
+			}
+			ready := true
+			for _, j := range after[i] {
+				if inOrder[j] && !placed[j] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				sorted = append(sorted, i)
+				placed[i] = true
+				progress = true
+				break // Start over so earlier items get placed first
+			}
+		}
+		if !progress {
+			for _, i := range order {
+				if !placed[i] {
+					sorted = append(sorted, i)
+					placed[i] = true
+					break
+				}
+			}
+		}
+	}
+	return sorted
+}
+
+// InstallMod downloads a Nexus mod of the collection, checks it against
+// the MD5 of the collection and runs its installer with the choices of the
+// collection. The returned mod isn't added to the mod list yet.
+func (c *Collection) InstallMod(cm *CollectionMod, game *games.Game, apiKey string, progress func(float64)) (*mod.Mod, error) {
+	if cm.Source != "nexus" {
+		return nil, fmt.Errorf("%s isn't on Nexus Mods, get it from %s", cm.Name, cm.URL)
+	}
+	info := &NxmInfo{Game: c.Game, ModID: cm.ModID, FileID: cm.FileID}
+	m, err := install(info, game, apiKey, progress, installOptions{md5: cm.MD5, choices: cm.Choices})
+	if err != nil {
+		return nil, err
+	}
+	m.Name = cm.Name
+	return m, nil
+}
@@ -0,0 +1,133 @@
+package nexus
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+const testCollectionManifest = `{
+	"info": {"name": "Known Good", "domainName": "skyrimspecialedition"},
+	"mods": [
+		{"name": "Textures Overhaul", "version": "2.0", "phase": 0,
+			"source": {"type": "nexus", "modId": 300, "fileId": 3000, "md5": "ccc", "logicalFilename": "Textures"}},
+		{"name": "SKSE Plugin", "version": "1.0", "phase": 0,
+			"source": {"type": "nexus", "modId": 100, "fileId": 1000, "md5": "aaa", "logicalFilename": "Plugin"},
+			"choices": {"type": "fomod", "options": [{"name": "Main", "groups": [
+				{"name": "Version", "choices": [{"name": "SE", "idx": 0}]}]}]}},
+		{"name": "Patch", "version": "1.1", "phase": 1,
+			"source": {"type": "nexus", "modId": 400, "fileId": 4000, "md5": "ddd"}},
+		{"name": "Optional Retexture", "version": "1.0", "optional": true, "phase": 0,
+			"source": {"type": "nexus", "modId": 200, "fileId": 2000, "md5": "bbb", "logicalFilename": "Retexture"}},
+		{"name": "Personal Tweaks", "phase": 0,
+			"source": {"type": "browse", "url": "https://example.com/tweaks"}}
+	],
+	"modRules": [
+		{"type": "after", "source": {"fileMD5": "bbb"}, "reference": {"logicalFileName": "Textures"}},
+		{"type": "before", "source": {"fileMD5": "ccc"}, "reference": {"fileMD5": "aaa"}},
+		{"type": "before", "source": {"fileMD5": "ddd"}, "reference": {"fileMD5": "ccc"}},
+		{"type": "requires", "source": {"fileMD5": "aaa"}, "reference": {"fileMD5": "ddd"}}
+	],
+	"plugins": [
+		{"name": "Patch.esp", "enabled": true},
+		{"name": "Plugin.esp", "enabled": true},
+		{"name": "Retexture.esp", "enabled": false}
+	],
+	"pluginRules": {"plugins": [{"name": "Patch.esp", "after": ["Plugin.esp"]}]}
+}`
+
+func TestParseCollection(t *testing.T) {
+	collection, err := parseCollection([]byte(testCollectionManifest))
+	if err != nil {
+		t.Fatalf("parseCollection failed: %v", err)
+	}
+
+	var names []string
+	for _, m := range collection.Mods {
+		names = append(names, m.Name)
+	}
+	// Phase 1 goes last even with a rule placing it before phase 0, the
+	// plugin and the retexture win against the textures
+	expected := []string{"Textures Overhaul", "SKSE Plugin", "Optional Retexture", "Personal Tweaks", "Patch"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected mods %v, got %v", expected, names)
+	}
+
+	plugin := collection.Mods[1]
+	if plugin.ModID != "100" || plugin.FileID != "1000" || plugin.MD5 != "aaa" || len(plugin.Choices) != 1 ||
+		plugin.Choices[0].Step != "Main" || plugin.Choices[0].Group != "Version" || plugin.Choices[0].Plugin != "SE" {
+		t.Errorf("unexpected mod %+v", plugin)
+	}
+	if collection.Mods[0].Choices != nil {
+		t.Errorf("expected no installer choices, got %+v", collection.Mods[0].Choices)
+	}
+	if !collection.Mods[2].Optional || collection.Mods[3].Source != "browse" || collection.Mods[3].ModID != "" {
+		t.Errorf("unexpected mods %+v", collection.Mods)
+	}
+
+	expectedPlugins := []CollectionPlugin{{"Plugin.esp", true}, {"Patch.esp", true}, {"Retexture.esp", false}}
+	if !reflect.DeepEqual(collection.Plugins, expectedPlugins) {
+		t.Errorf("expected plugins %v, got %v", expectedPlugins, collection.Plugins)
+	}
+}
+
+func TestGetCollection(t *testing.T) {
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	f, err := zw.Create("collection.json")
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	f.Write([]byte(testCollectionManifest))
+	zw.Close()
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/graphql":
+			var request struct {
+				Variables map[string]interface{} `json:"variables"`
+			}
+			json.NewDecoder(r.Body).Decode(&request)
+			if r.Header.Get("apikey") != "test-key" || request.Variables["slug"] != "qdurkx" || request.Variables["revision"] != float64(7) {
+				w.Write([]byte(`{"errors": [{"message": "Collection not found"}]}`))
+				return
+			}
+			w.Write([]byte(`{"data": {"collectionRevision": {"revisionNumber": 7,
+				"downloadLink": "/v1/collections/qdurkx/revisions/7/download_link",
+				"collection": {"name": "Known Good SE"}}}}`))
+		case "/v1/collections/qdurkx/revisions/7/download_link":
+			w.Write([]byte(`{"download_links": [{"name": "CDN", "URI": "` + server.URL + `/files/collection.zip"}]}`))
+		case "/files/collection.zip":
+			w.Write(archive.Bytes())
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	oldBaseURL, oldGraphqlURL := apiBaseURL, graphqlURL
+	apiBaseURL, graphqlURL = server.URL, server.URL+"/v2/graphql"
+	defer func() { apiBaseURL, graphqlURL = oldBaseURL, oldGraphqlURL }()
+
+	info, err := ParseNxmURL("nxm://skyrimspecialedition/collections/qdurkx/revisions/7")
+	if err != nil {
+		t.Fatalf("ParseNxmURL failed: %v", err)
+	}
+	collection, err := GetCollection(info, "test-key")
+	if err != nil {
+		t.Fatalf("GetCollection failed: %v", err)
+	}
+	if collection.Name != "Known Good SE" || collection.Revision != 7 || collection.Game != "skyrimspecialedition" || len(collection.Mods) != 5 {
+		t.Errorf("unexpected collection %+v", collection)
+	}
+
+	info.Revision = "8"
+	if _, err := GetCollection(info, "test-key"); err == nil {
+		t.Errorf("expected an error for a missing revision")
+	}
+}
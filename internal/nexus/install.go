@@ -0,0 +1,111 @@
+package nexus
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bazsalanszky/fusioncore/internal/extractor"
+	"github.com/bazsalanszky/fusioncore/internal/fomod"
+	"github.com/bazsalanszky/fusioncore/internal/games"
+	"github.com/bazsalanszky/fusioncore/internal/mod"
+)
+
+// Install downloads a mod file and extracts it into the game's mods
+// directory. The returned mod has its metadata but isn't added to the mod
+// list yet. Without the key of an nxm link only premium accounts can
+// download.
+func Install(info *NxmInfo, game *games.Game, apiKey string, progress func(float64)) (*mod.Mod, error) {
+	return install(info, game, apiKey, progress, installOptions{})
+}
+
+// installOptions are the extra steps of installing a mod of a collection.
+type installOptions struct {
+	md5     string         // Expected MD5 of the archive, not checked if empty
+	choices []fomod.Choice // Choices for the FOMOD installer, the archive is extracted as is if nil
+}
+
+func install(info *NxmInfo, game *games.Game, apiKey string, progress func(float64), opts installOptions) (*mod.Mod, error) {
+	downloadURL, err := GetDownloadURL(info, apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	destDir, err := game.GetModsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	filePath, err := DownloadFile(downloadURL, destDir, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.md5 != "" {
+		if sum, err := fileMD5(filePath); err != nil || !strings.EqualFold(sum, opts.md5) {
+			os.Remove(filePath)
+			return nil, fmt.Errorf("the download of mod %s, file %s doesn't match the expected MD5", info.ModID, info.FileID)
+		}
+	}
+
+	// Keep mods apart even if their downloads have the same file name
+	extractDir := mod.UniqueDir(strings.TrimSuffix(filePath, filepath.Ext(filePath)))
+	if opts.choices != nil {
+		err = installFomod(filePath, extractDir, opts.choices)
+	} else {
+		err = extractor.Extract(filePath, extractDir)
+	}
+	if err != nil {
+		os.RemoveAll(extractDir)
+		return nil, err
+	}
+
+	// Clean up the downloaded archive
+	if err := os.Remove(filePath); err != nil {
+		fmt.Printf("Failed to remove %s: %v\n", filePath, err)
+	}
+
+	newMod := mod.New(filepath.Base(extractDir), extractDir, info.ModID, info.FileID, game.ID)
+	if metadata, err := FetchMetadata(info.Game, info.ModID, info.FileID, apiKey); err == nil {
+		newMod.Metadata = metadata
+	} else {
+		fmt.Printf("Failed to get metadata of %s: %v\n", newMod.Name, err)
+	}
+	return newMod, nil
+}
+
+// installFomod extracts an archive next to it and runs its FOMOD installer
+// into destDir with the given choices.
+func installFomod(archivePath, destDir string, choices []fomod.Choice) error {
+	tmpDir, err := os.MkdirTemp(filepath.Dir(archivePath), ".fomod-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	if err := extractor.Extract(archivePath, tmpDir); err != nil {
+		return err
+	}
+	srcDir := fomod.FindConfig(tmpDir)
+	if srcDir == "" {
+		fmt.Printf("%s has no FOMOD installer, extracting it as is\n", filepath.Base(archivePath))
+		return mod.MoveDir(tmpDir, destDir)
+	}
+	return fomod.Install(srcDir, destDir, choices)
+}
+
+func fileMD5(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
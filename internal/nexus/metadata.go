@@ -0,0 +1,155 @@
+package nexus
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bazsalanszky/fusioncore/internal/mod"
+)
+
+// apiBaseURL is the root of the Nexus Mods API, replaced in tests.
+var apiBaseURL = "https://api.nexusmods.com"
+
+// ModInfo holds the fields of /v1/games/{game}/mods/{id}.json we use.
+type ModInfo struct {
+	ModID       int    `json:"mod_id"`
+	Name        string `json:"name"`
+	Summary     string `json:"summary"`
+	Version     string `json:"version"`
+	Author      string `json:"author"`
+	UploadedBy  string `json:"uploaded_by"`
+	CategoryID  int    `json:"category_id"`
+	PictureURL  string `json:"picture_url"`
+	CreatedTime int64  `json:"created_timestamp"`
+	UpdatedTime int64  `json:"updated_timestamp"`
+}
+
+// FileInfo holds the fields of /v1/games/{game}/mods/{id}/files/{file_id}.json we use.
+type FileInfo struct {
+	FileID       int    `json:"file_id"`
+	Name         string `json:"name"`
+	FileName     string `json:"file_name"`
+	Version      string `json:"version"`
+	CategoryName string `json:"category_name"`
+	UploadedTime int64  `json:"uploaded_timestamp"`
+	SizeKB       int64  `json:"size_kb"`
+}
+
+// Category is a mod category of a game.
+type Category struct {
+	ID   int    `json:"category_id"`
+	Name string `json:"name"`
+}
+
+// getJSON performs an authenticated GET request against the API and decodes the response.
+func getJSON(path, apiKey string, v interface{}) error {
+	req, err := http.NewRequest("GET", apiBaseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("apikey", apiKey)
+	req.Header.Set("accept", "application/json")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to perform request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API request failed with status: %s", resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// GetModInfo gets the details of a mod.
+func GetModInfo(game, modID, apiKey string) (*ModInfo, error) {
+	var info ModInfo
+	if err := getJSON(fmt.Sprintf("/v1/games/%s/mods/%s.json", game, modID), apiKey, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// GetFileInfo gets the details of a file of a mod.
+func GetFileInfo(game, modID, fileID, apiKey string) (*FileInfo, error) {
+	var info FileInfo
+	if err := getJSON(fmt.Sprintf("/v1/games/%s/mods/%s/files/%s.json", game, modID, fileID), apiKey, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// GetModFiles gets the files of a mod.
+func GetModFiles(game, modID, apiKey string) ([]FileInfo, error) {
+	var info struct {
+		Files []FileInfo `json:"files"`
+	}
+	if err := getJSON(fmt.Sprintf("/v1/games/%s/mods/%s/files.json", game, modID), apiKey, &info); err != nil {
+		return nil, err
+	}
+	return info.Files, nil
+}
+
+// GetCategories gets the mod categories of a game.
+func GetCategories(game, apiKey string) ([]Category, error) {
+	var info struct {
+		Categories []Category `json:"categories"`
+	}
+	if err := getJSON(fmt.Sprintf("/v1/games/%s.json", game), apiKey, &info); err != nil {
+		return nil, err
+	}
+	return info.Categories, nil
+}
+
+// FetchMetadata gets the metadata of an installed mod file. The category
+// name is left empty if the categories of the game can't be fetched, only
+// the category ID is set then.
+func FetchMetadata(game, modID, fileID, apiKey string) (*mod.Metadata, error) {
+	modInfo, err := GetModInfo(game, modID, apiKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mod info: %w", err)
+	}
+
+	metadata := &mod.Metadata{
+		Version:    modInfo.Version,
+		Author:     modInfo.Author,
+		Summary:    modInfo.Summary,
+		CategoryID: modInfo.CategoryID,
+		PictureURL: modInfo.PictureURL,
+		Fetched:    time.Now().UTC(),
+	}
+	if metadata.Author == "" {
+		metadata.Author = modInfo.UploadedBy
+	}
+
+	if fileID != "" {
+		fileInfo, err := GetFileInfo(game, modID, fileID, apiKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get file info: %w", err)
+		}
+		metadata.FileName = fileInfo.FileName
+		metadata.FileVersion = fileInfo.Version
+		if fileInfo.UploadedTime > 0 {
+			metadata.FileUploaded = time.Unix(fileInfo.UploadedTime, 0).UTC()
+		}
+	}
+
+	if categories, err := GetCategories(game, apiKey); err == nil {
+		for _, c := range categories {
+			if c.ID == modInfo.CategoryID {
+				metadata.Category = c.Name
+			}
+		}
+	}
+
+	return metadata, nil
+}
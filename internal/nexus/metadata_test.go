@@ -0,0 +1,64 @@
+package nexus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFetchMetadata(t *testing.T) {
+	responses := map[string]string{
+		"/v1/games/fallout4/mods/12345.json": `{
+			"mod_id": 12345, "name": "Better Guns", "summary": "Adds new guns",
+			"version": "1.2", "author": "Someone", "uploaded_by": "someone_else",
+			"category_id": 27, "picture_url": "https://example.com/guns.png"
+		}`,
+		"/v1/games/fallout4/mods/12345/files/67890.json": `{
+			"file_id": 67890, "name": "Main file", "file_name": "Better Guns-12345-1-1.zip",
+			"version": "1.1", "uploaded_timestamp": 1700000000
+		}`,
+		"/v1/games/fallout4.json": `{"categories": [
+			{"category_id": 1, "name": "Fallout 4", "parent_category": false},
+			{"category_id": 27, "name": "Weapons", "parent_category": 1}
+		]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("apikey") != "test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	oldBaseURL := apiBaseURL
+	apiBaseURL = server.URL
+	defer func() { apiBaseURL = oldBaseURL }()
+
+	metadata, err := FetchMetadata("fallout4", "12345", "67890", "test-key")
+	if err != nil {
+		t.Fatalf("FetchMetadata failed: %v", err)
+	}
+	if metadata.Version != "1.2" || metadata.FileVersion != "1.1" || metadata.Author != "Someone" {
+		t.Errorf("unexpected versions or author: %+v", metadata)
+	}
+	if metadata.Category != "Weapons" || metadata.Summary != "Adds new guns" {
+		t.Errorf("unexpected category or summary: %+v", metadata)
+	}
+	if !metadata.FileUploaded.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("unexpected upload time %v", metadata.FileUploaded)
+	}
+
+	if _, err := FetchMetadata("fallout4", "99999", "1", "test-key"); err == nil {
+		t.Errorf("expected an error for a missing mod")
+	}
+	if _, err := FetchMetadata("fallout4", "12345", "67890", "wrong-key"); err == nil {
+		t.Errorf("expected an error for an invalid API key")
+	}
+}
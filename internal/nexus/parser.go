@@ -13,6 +13,15 @@ type NxmInfo struct {
 	FileID string
 	Key    string
 	Expires string
+	// Slug and revision of a collection, set instead of the mod and file
+	// IDs for collection links
+	Collection string
+	Revision   string
+}
+
+// IsCollection reports whether the link is for a collection.
+func (info *NxmInfo) IsCollection() bool {
+	return info.Collection != ""
 }
 
 // ParseNxmURL parses an nxm URL and returns the extracted information.
@@ -27,12 +36,23 @@ func ParseNxmURL(nxmURL string) (*NxmInfo, error) {
 	}
 
 	pathParts := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
+	query := parsedURL.Query()
+
+	// nxm://<game>/collections/<slug>/revisions/<revision>
+	if len(pathParts) >= 4 && pathParts[0] == "collections" && pathParts[2] == "revisions" {
+		return &NxmInfo{
+			Game:       parsedURL.Host,
+			Collection: pathParts[1],
+			Revision:   pathParts[3],
+			Key:        query.Get("key"),
+			Expires:    query.Get("expires"),
+		}, nil
+	}
+
 	if len(pathParts) < 4 || pathParts[0] != "mods" || pathParts[2] != "files" {
 		return nil, fmt.Errorf("invalid nxm URL path: %s", parsedURL.Path)
 	}
 
-	query := parsedURL.Query()
-
 	info := &NxmInfo{
 		Game:    parsedURL.Host,
 		ModID:   pathParts[1],
@@ -35,3 +35,17 @@ func TestParseNxmURL(t *testing.T) {
 		t.Errorf("expected expires %q, got %q", expected.Expires, actual.Expires)
 	}
 }
+
+func TestParseNxmCollectionURL(t *testing.T) {
+	actual, err := ParseNxmURL("nxm://skyrimspecialedition/collections/qdurkx/revisions/42")
+	if err != nil {
+		t.Fatalf("ParseNxmURL failed: %v", err)
+	}
+	if !actual.IsCollection() || actual.Game != "skyrimspecialedition" || actual.Collection != "qdurkx" || actual.Revision != "42" {
+		t.Errorf("unexpected collection link %+v", actual)
+	}
+
+	if _, err := ParseNxmURL("nxm://skyrimspecialedition/collections/qdurkx"); err == nil {
+		t.Errorf("expected an error for a link without a revision")
+	}
+}
@@ -1,80 +1,134 @@
 package prefix
 
 import (
-	"errors"
+	"fmt"
 	"os"
+	"os/user"
 	"path/filepath"
+	"strings"
 )
 
-// FindSteamRoot finds the root directory of the Steam installation.
-// It checks common locations for the Steam installation on Linux.
-func FindSteamRoot() (string, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
-	}
+// steamUser is the Windows user name Proton creates in every prefix.
+const steamUser = "steamuser"
 
-	possiblePaths := []string{
-		filepath.Join(homeDir, ".steam", "steam"),
-		filepath.Join(homeDir, ".local", "share", "Steam"),
+// Root returns the Wine prefix directory (the one containing drive_c).
+// prefixPath may be a Proton compatdata directory, where the prefix lives in
+// the pfx subdirectory, or a plain Wine prefix as used by Heroic, Lutris and Bottles.
+func Root(prefixPath string) string {
+	if IsPlainWinePrefix(prefixPath) {
+		return prefixPath
 	}
+	return filepath.Join(prefixPath, "pfx")
+}
 
-	for _, path := range possiblePaths {
-		if _, err := os.Stat(path); err == nil {
-			return path, nil
-		}
+// IsPlainWinePrefix reports whether prefixPath is a Wine prefix without the
+// Proton compatdata pfx subdirectory.
+func IsPlainWinePrefix(prefixPath string) bool {
+	if _, err := os.Stat(filepath.Join(prefixPath, "pfx")); err == nil {
+		return false
 	}
+	_, err := os.Stat(filepath.Join(prefixPath, "drive_c"))
+	return err == nil
+}
 
-	return "", errors.New("Steam root directory not found")
+// DriveC returns the directory Wine maps to C:.
+func DriveC(prefixPath string) string {
+	return filepath.Join(Root(prefixPath), "drive_c")
 }
 
-// FindCompatdata finds the compatdata directory for a given AppID.
-func FindCompatdata(steamRoot, appID string) (string, error) {
-	compatdataPath := filepath.Join(steamRoot, "steamapps", "compatdata", appID)
-	if _, err := os.Stat(compatdataPath); err == nil {
-		return compatdataPath, nil
+// UserName returns the Windows user name used inside the prefix.
+// It is read from user.reg when possible. Otherwise Proton prefixes use
+// steamuser and plain Wine prefixes use the only profile in drive_c/users.
+func UserName(prefixPath string) string {
+	if name, ok := readUserRegValue(prefixPath, `Volatile Environment`, "USERNAME"); ok && name != "" {
+		return name
 	}
 
-	return "", errors.New("compatdata directory not found for AppID: " + appID)
+	usersDir := filepath.Join(DriveC(prefixPath), "users")
+	if entries, err := os.ReadDir(usersDir); err == nil {
+		var candidates []string
+		for _, entry := range entries {
+			if !entry.IsDir() || strings.EqualFold(entry.Name(), "Public") {
+				continue
+			}
+			if entry.Name() == steamUser {
+				return steamUser
+			}
+			candidates = append(candidates, entry.Name())
+		}
+		if len(candidates) == 1 {
+			return candidates[0]
+		}
+	}
+
+	if !IsPlainWinePrefix(prefixPath) {
+		return steamUser
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return steamUser
 }
 
-// FindFallout76Prefix finds the Proton prefix for Fallout 76.
-func FindFallout76Prefix() (string, error) {
-	steamRoot, err := FindSteamRoot()
-	if err != nil {
-		return "", err
+// UserDir returns the user profile directory inside the prefix.
+func UserDir(prefixPath string) string {
+	if profile, ok := readUserRegValue(prefixPath, `Volatile Environment`, "USERPROFILE"); ok {
+		if dir, err := ToUnixPath(prefixPath, profile); err == nil {
+			return dir
+		}
 	}
+	return filepath.Join(DriveC(prefixPath), "users", UserName(prefixPath))
+}
 
-	return FindCompatdata(steamRoot, "1151340")
+// DocumentsDir returns the user's Documents folder inside the prefix.
+// Older prefixes use "My Documents", which user.reg records.
+func DocumentsDir(prefixPath string) string {
+	return shellFolder(prefixPath, "Personal", "Documents")
 }
 
-// FindFallout76GameDir finds the game directory for Fallout 76.
-// This function currently only checks the default Steam library.
-func FindFallout76GameDir() (string, error) {
-	steamRoot, err := FindSteamRoot()
-	if err != nil {
-		return "", err
-	}
+// LocalAppDataDir returns the user's AppData/Local folder inside the prefix.
+func LocalAppDataDir(prefixPath string) string {
+	return shellFolder(prefixPath, "Local AppData", filepath.Join("AppData", "Local"))
+}
 
-	gameDir := filepath.Join(steamRoot, "steamapps", "common", "Fallout76")
-	if _, err := os.Stat(gameDir); err == nil {
-		return gameDir, nil
+// shellFolder resolves a folder from the Explorer "Shell Folders" key of
+// user.reg, falling back to fallback inside the user profile.
+func shellFolder(prefixPath, name, fallback string) string {
+	if winPath, ok := readUserRegValue(prefixPath, `Software\Microsoft\Windows\CurrentVersion\Explorer\Shell Folders`, name); ok {
+		if dir, err := ToUnixPath(prefixPath, winPath); err == nil {
+			return dir
+		}
 	}
-
-	return "", errors.New("Fallout 76 game directory not found in default library")
+	return filepath.Join(UserDir(prefixPath), fallback)
 }
 
-// FindFallout76DataDir finds the Data directory for Fallout 76.
-func FindFallout76DataDir() (string, error) {
-	gameDir, err := FindFallout76GameDir()
-	if err != nil {
-		return "", err
+// ToUnixPath converts a Windows path inside the prefix, like
+// C:\users\steamuser\Documents, to the matching Linux path using the drive
+// mappings in dosdevices.
+func ToUnixPath(prefixPath, winPath string) (string, error) {
+	if len(winPath) < 2 || winPath[1] != ':' {
+		return "", fmt.Errorf("not an absolute Windows path: %s", winPath)
 	}
+	drive := strings.ToLower(winPath[:2])
+	rest := strings.Trim(strings.ReplaceAll(winPath[2:], `\`, "/"), "/")
 
-	dataDir := filepath.Join(gameDir, "Data")
-	if _, err := os.Stat(dataDir); err == nil {
-		return dataDir, nil
+	driveRoot := ""
+	dosdevices := filepath.Join(Root(prefixPath), "dosdevices")
+	if target, err := os.Readlink(filepath.Join(dosdevices, drive)); err == nil {
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(dosdevices, target)
+		}
+		driveRoot = target
+	} else {
+		switch drive {
+		case "c:":
+			driveRoot = DriveC(prefixPath)
+		case "z:":
+			driveRoot = "/"
+		default:
+			return "", fmt.Errorf("drive %s is not mapped in the prefix", drive)
+		}
 	}
 
-	return "", errors.New("Fallout 76 Data directory not found")
+	return filepath.Join(driveRoot, filepath.FromSlash(rest)), nil
 }
@@ -0,0 +1,154 @@
+package prefix
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrefixLayouts(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-prefix")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Proton compatdata layout
+	compatdata := filepath.Join(tmpDir, "compatdata")
+	if err := os.MkdirAll(filepath.Join(compatdata, "pfx", "drive_c", "users", "steamuser"), 0755); err != nil {
+		t.Fatalf("Failed to create compatdata: %v", err)
+	}
+	if expected := filepath.Join(compatdata, "pfx", "drive_c", "users", "steamuser"); UserDir(compatdata) != expected {
+		t.Errorf("expected user dir %q, got %q", expected, UserDir(compatdata))
+	}
+
+	// Plain Wine prefix with a custom user name
+	winePrefix := filepath.Join(tmpDir, "wine")
+	for _, dir := range []string{"Public", "gamer"} {
+		if err := os.MkdirAll(filepath.Join(winePrefix, "drive_c", "users", dir), 0755); err != nil {
+			t.Fatalf("Failed to create wine prefix: %v", err)
+		}
+	}
+	if !IsPlainWinePrefix(winePrefix) {
+		t.Errorf("expected %s to be a plain Wine prefix", winePrefix)
+	}
+	if expected := filepath.Join(winePrefix, "drive_c", "users", "gamer"); UserDir(winePrefix) != expected {
+		t.Errorf("expected user dir %q, got %q", expected, UserDir(winePrefix))
+	}
+
+	// Missing prefixes keep the Proton layout
+	missing := filepath.Join(tmpDir, "missing")
+	if expected := filepath.Join(missing, "pfx", "drive_c", "users", "steamuser"); UserDir(missing) != expected {
+		t.Errorf("expected user dir %q, got %q", expected, UserDir(missing))
+	}
+}
+
+func TestUserRegFolders(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-prefix-reg")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// An older prefix that still uses "My Documents" and Local Settings
+	pfx := filepath.Join(tmpDir, "pfx")
+	if err := os.MkdirAll(filepath.Join(pfx, "drive_c", "users", "steamuser"), 0755); err != nil {
+		t.Fatalf("Failed to create prefix: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(pfx, "dosdevices"), 0755); err != nil {
+		t.Fatalf("Failed to create dosdevices: %v", err)
+	}
+	if err := os.Symlink("../drive_c", filepath.Join(pfx, "dosdevices", "c:")); err != nil {
+		t.Fatalf("Failed to create drive link: %v", err)
+	}
+
+	userReg := `WINE REGISTRY Version 2
+;; All keys relative to \\User\\S-1-5-21-0-0-0-1000
+
+[Software\\Microsoft\\Windows\\CurrentVersion\\Explorer\\Shell Folders] 1700000000
+#time=1da0000000000000
+"Local AppData"="C:\\users\\gamer\\Local Settings\\Application Data"
+"Personal"="C:\\users\\gamer\\My Documents"
+
+[Volatile Environment] 1700000000
+#time=1da0000000000000
+"USERNAME"="gamer"
+"USERPROFILE"="C:\\users\\gamer"
+`
+	if err := os.WriteFile(filepath.Join(pfx, "user.reg"), []byte(userReg), 0644); err != nil {
+		t.Fatalf("Failed to write user.reg: %v", err)
+	}
+
+	driveC := filepath.Join(pfx, "drive_c")
+	if name := UserName(tmpDir); name != "gamer" {
+		t.Errorf("expected user name gamer, got %q", name)
+	}
+	if expected := filepath.Join(driveC, "users", "gamer"); UserDir(tmpDir) != expected {
+		t.Errorf("expected user dir %q, got %q", expected, UserDir(tmpDir))
+	}
+	if expected := filepath.Join(driveC, "users", "gamer", "My Documents"); DocumentsDir(tmpDir) != expected {
+		t.Errorf("expected documents dir %q, got %q", expected, DocumentsDir(tmpDir))
+	}
+	if expected := filepath.Join(driveC, "users", "gamer", "Local Settings", "Application Data"); LocalAppDataDir(tmpDir) != expected {
+		t.Errorf("expected local appdata dir %q, got %q", expected, LocalAppDataDir(tmpDir))
+	}
+
+	// Unmapped drives are an error, Z: falls back to the filesystem root
+	if _, err := ToUnixPath(tmpDir, `D:\Games`); err == nil {
+		t.Errorf("expected an error for an unmapped drive")
+	}
+	if dir, err := ToUnixPath(tmpDir, `Z:\home\gamer`); err != nil || dir != "/home/gamer" {
+		t.Errorf("expected /home/gamer, got %q (%v)", dir, err)
+	}
+}
+
+func TestEnsureBethesdaKeys(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-prefix-registry")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	pfx := filepath.Join(tmpDir, "pfx")
+	if err := os.MkdirAll(filepath.Join(pfx, "drive_c"), 0755); err != nil {
+		t.Fatalf("Failed to create prefix: %v", err)
+	}
+	systemReg := "WINE REGISTRY Version 2\n;; All keys relative to \\\\Machine\n\n#arch=win64\n\n[Software\\\\Wine] 1700000000\n\"Version\"=\"win10\"\n"
+	if err := os.WriteFile(filepath.Join(pfx, "system.reg"), []byte(systemReg), 0644); err != nil {
+		t.Fatalf("Failed to write system.reg: %v", err)
+	}
+
+	gameDir := filepath.Join(tmpDir, "Games", "Fallout 4")
+	changed, err := EnsureBethesdaKeys(tmpDir, "Fallout4", gameDir)
+	if err != nil {
+		t.Fatalf("Failed to ensure keys: %v", err)
+	}
+	if !changed {
+		t.Errorf("expected the registry to change")
+	}
+
+	expected := "Z:" + strings.ReplaceAll(gameDir, "/", `\`) + `\`
+	if path, ok := BethesdaInstalledPath(tmpDir, "Fallout4"); !ok || path != expected {
+		t.Errorf("expected Installed Path %q, got %q (%v)", expected, path, ok)
+	}
+
+	data, err := os.ReadFile(filepath.Join(pfx, "system.reg"))
+	if err != nil {
+		t.Fatalf("Failed to read system.reg: %v", err)
+	}
+	if !strings.Contains(string(data), `[Software\\Wow6432Node\\Bethesda Softworks\\Fallout4]`) {
+		t.Errorf("expected the 32-bit key to be written:\n%s", data)
+	}
+	if !strings.HasPrefix(string(data), systemReg) {
+		t.Errorf("expected existing keys to be preserved:\n%s", data)
+	}
+	if _, err := os.Stat(filepath.Join(pfx, "system.reg.bak")); err != nil {
+		t.Errorf("expected a backup of system.reg: %v", err)
+	}
+
+	// Running it again is a no-op
+	if changed, err := EnsureBethesdaKeys(tmpDir, "Fallout4", gameDir); err != nil || changed {
+		t.Errorf("expected no change on the second run, got %v (%v)", changed, err)
+	}
+}
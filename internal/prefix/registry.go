@@ -0,0 +1,218 @@
+package prefix
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/bazsalanszky/fusioncore/internal/winereg"
+)
+
+// InstalledProgram is an entry of the Uninstall key, e.g. a Visual C++ runtime.
+type InstalledProgram struct {
+	Key     string // Name of the Uninstall subkey
+	Name    string
+	Version string
+}
+
+// SystemRegPath returns the path of the HKEY_LOCAL_MACHINE hive of the prefix.
+func SystemRegPath(prefixPath string) string {
+	return filepath.Join(Root(prefixPath), "system.reg")
+}
+
+// UserRegPath returns the path of the HKEY_CURRENT_USER hive of the prefix.
+func UserRegPath(prefixPath string) string {
+	return filepath.Join(Root(prefixPath), "user.reg")
+}
+
+// readUserRegValue reads a string value from the prefix's user.reg.
+func readUserRegValue(prefixPath, key, name string) (string, bool) {
+	hive, err := winereg.ParseFile(UserRegPath(prefixPath))
+	if err != nil {
+		return "", false
+	}
+	return hive.Key(key).GetString(name)
+}
+
+// bethesdaKeys returns the keys Bethesda games register their install location
+// under. 64-bit prefixes also get the 32-bit view, which older tools read.
+func bethesdaKeys(hive *winereg.Hive, registryName string) []string {
+	keys := []string{`Software\Bethesda Softworks\` + registryName}
+	for _, line := range hive.Header {
+		if strings.TrimSpace(line) == "#arch=win32" {
+			return keys
+		}
+	}
+	return append(keys, `Software\Wow6432Node\Bethesda Softworks\`+registryName)
+}
+
+// BethesdaInstalledPath returns the "Installed Path" the prefix has registered for a game.
+func BethesdaInstalledPath(prefixPath, registryName string) (string, bool) {
+	hive, err := winereg.ParseFile(SystemRegPath(prefixPath))
+	if err != nil {
+		return "", false
+	}
+	for _, name := range bethesdaKeys(hive, registryName) {
+		if path, ok := hive.Key(name).GetString("Installed Path"); ok {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// EnsureBethesdaKeys writes the "Installed Path" of a game to the prefix's
+// system.reg, so tools like xEdit, LOOT and script extenders can find it.
+// It reports whether the hive had to be changed.
+func EnsureBethesdaKeys(prefixPath, registryName, gameDir string) (bool, error) {
+	if registryName == "" {
+		return false, errors.New("the game has no registry name")
+	}
+	if WineserverRunning(prefixPath) {
+		return false, errors.New("Wine is running in the prefix. Please close the game first, Wine overwrites the registry when it exits")
+	}
+
+	winPath, err := ToWindowsPath(prefixPath, gameDir)
+	if err != nil {
+		return false, err
+	}
+	// Bethesda's launchers store the path with a trailing backslash
+	winPath = strings.TrimSuffix(winPath, `\`) + `\`
+
+	path := SystemRegPath(prefixPath)
+	hive, err := winereg.ParseFile(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read system.reg: %w", err)
+	}
+
+	changed := false
+	for _, name := range bethesdaKeys(hive, registryName) {
+		key := hive.CreateKey(name)
+		if current, ok := key.GetString("Installed Path"); ok && current == winPath {
+			continue
+		}
+		key.SetString("Installed Path", winPath)
+		changed = true
+	}
+	if !changed {
+		return false, nil
+	}
+
+	if err := hive.WriteFile(path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DllOverrides returns the DLL overrides of the prefix, e.g. "dxgi" -> "native,builtin".
+func DllOverrides(prefixPath string) (map[string]string, error) {
+	hive, err := winereg.ParseFile(UserRegPath(prefixPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user.reg: %w", err)
+	}
+
+	overrides := make(map[string]string)
+	key := hive.Key(`Software\Wine\DllOverrides`)
+	if key == nil {
+		return overrides, nil
+	}
+	for _, v := range key.Values {
+		if mode, ok := v.String(); ok && v.Name != "" {
+			overrides[v.Name] = mode
+		}
+	}
+	return overrides, nil
+}
+
+// InstalledPrograms returns the programs registered in the Uninstall keys of
+// the prefix, which includes the runtimes installed by Proton and winetricks.
+func InstalledPrograms(prefixPath string) ([]InstalledProgram, error) {
+	hive, err := winereg.ParseFile(SystemRegPath(prefixPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read system.reg: %w", err)
+	}
+
+	var programs []InstalledProgram
+	for _, parent := range []string{
+		`Software\Microsoft\Windows\CurrentVersion\Uninstall`,
+		`Software\Wow6432Node\Microsoft\Windows\CurrentVersion\Uninstall`,
+	} {
+		for _, key := range hive.Subkeys(parent) {
+			name, ok := key.GetString("DisplayName")
+			if !ok {
+				continue
+			}
+			version, _ := key.GetString("DisplayVersion")
+			programs = append(programs, InstalledProgram{Key: key.BaseName(), Name: name, Version: version})
+		}
+	}
+
+	sort.Slice(programs, func(i, j int) bool {
+		return strings.ToLower(programs[i].Name) < strings.ToLower(programs[j].Name)
+	})
+	return programs, nil
+}
+
+// ToWindowsPath converts a Linux path to the Windows path Wine sees it under,
+// using the drive mapping with the longest matching target.
+func ToWindowsPath(prefixPath, unixPath string) (string, error) {
+	unixPath, err := filepath.Abs(unixPath)
+	if err != nil {
+		return "", err
+	}
+
+	drives := map[string]string{"c:": DriveC(prefixPath), "z:": "/"}
+	dosdevices := filepath.Join(Root(prefixPath), "dosdevices")
+	if entries, err := os.ReadDir(dosdevices); err == nil {
+		for _, entry := range entries {
+			name := strings.ToLower(entry.Name())
+			if len(name) != 2 || name[1] != ':' {
+				continue // Skip devices like c:: and com1
+			}
+			target, err := os.Readlink(filepath.Join(dosdevices, entry.Name()))
+			if err != nil {
+				continue
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(dosdevices, target)
+			}
+			drives[name] = filepath.Clean(target)
+		}
+	}
+
+	bestDrive, bestRoot := "", ""
+	for drive, root := range drives {
+		if unixPath != root && !strings.HasPrefix(unixPath, strings.TrimSuffix(root, "/")+"/") {
+			continue
+		}
+		if len(root) > len(bestRoot) || (len(root) == len(bestRoot) && drive < bestDrive) {
+			bestDrive, bestRoot = drive, root
+		}
+	}
+	if bestDrive == "" {
+		return "", fmt.Errorf("%s is not reachable from any drive of the prefix", unixPath)
+	}
+
+	rest := strings.TrimPrefix(strings.TrimPrefix(unixPath, bestRoot), "/")
+	return strings.ToUpper(bestDrive) + `\` + strings.ReplaceAll(rest, "/", `\`), nil
+}
+
+// WineserverRunning reports whether a wineserver is serving the prefix.
+// Wine names its socket directory after the device and inode of the prefix.
+func WineserverRunning(prefixPath string) bool {
+	info, err := os.Stat(Root(prefixPath))
+	if err != nil {
+		return false
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+	socket := filepath.Join("/tmp", fmt.Sprintf(".wine-%d", os.Getuid()),
+		fmt.Sprintf("server-%x-%x", stat.Dev, stat.Ino), "socket")
+	_, err = os.Stat(socket)
+	return err == nil
+}
@@ -0,0 +1,143 @@
+package steam
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// App state flags as stored in the StateFlags field of an app manifest.
+const (
+	StateUninstalled    = 1 << 0
+	StateUpdateRequired = 1 << 1
+	StateFullyInstalled = 1 << 2
+	StateFilesMissing   = 1 << 5
+	StateAppRunning     = 1 << 6
+	StateFilesCorrupt   = 1 << 7
+	StateUpdateRunning  = 1 << 8
+	StateUpdatePaused   = 1 << 9
+	StateUpdateStarted  = 1 << 10
+	StateUninstalling   = 1 << 11
+	StateValidating     = 1 << 17
+	StateDownloading    = 1 << 20
+	StateStaging        = 1 << 21
+	StateCommitting     = 1 << 22
+)
+
+// busyStates are the flags that mean Steam is currently changing the game files.
+const busyStates = StateFilesMissing | StateFilesCorrupt | StateUpdateRunning | StateUpdateStarted |
+	StateUninstalling | StateValidating | StateDownloading | StateStaging | StateCommitting
+
+// AppManifest holds the interesting parts of a steamapps/appmanifest_<AppID>.acf file.
+type AppManifest struct {
+	AppID       string
+	Name        string
+	InstallDir  string
+	BuildID     string
+	StateFlags  int
+	LastUpdated time.Time
+	SizeOnDisk  int64
+	Library     string // Path of the library the manifest was read from
+}
+
+// GetAppManifestPath returns the path of the app manifest for an AppID in a library.
+func GetAppManifestPath(libraryPath, appID string) string {
+	return filepath.Join(libraryPath, "steamapps", "appmanifest_"+appID+".acf")
+}
+
+// ReadAppManifest reads the app manifest for an AppID from a library.
+// The returned error satisfies os.IsNotExist if the app has no manifest there.
+func ReadAppManifest(libraryPath, appID string) (*AppManifest, error) {
+	root, err := ParseVDFFile(GetAppManifestPath(libraryPath, appID))
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := ParseAppManifest(root)
+	if err != nil {
+		return nil, err
+	}
+	manifest.Library = libraryPath
+	return manifest, nil
+}
+
+// ParseAppManifest extracts an AppManifest from a parsed .acf document.
+func ParseAppManifest(root *Node) (*AppManifest, error) {
+	state := root.Child("AppState")
+	if state == nil {
+		return nil, fmt.Errorf("app manifest has no AppState section")
+	}
+
+	manifest := &AppManifest{
+		AppID:      state.Get("appid"),
+		Name:       state.Get("name"),
+		InstallDir: state.Get("installdir"),
+		BuildID:    state.Get("buildid"),
+	}
+	if manifest.InstallDir == "" {
+		return nil, fmt.Errorf("app manifest for %s has no installdir", manifest.AppID)
+	}
+
+	if flags := state.Get("StateFlags"); flags != "" {
+		n, err := strconv.Atoi(flags)
+		if err != nil {
+			return nil, fmt.Errorf("invalid StateFlags %q: %w", flags, err)
+		}
+		manifest.StateFlags = n
+	}
+	if updated, err := strconv.ParseInt(state.Get("LastUpdated"), 10, 64); err == nil && updated > 0 {
+		manifest.LastUpdated = time.Unix(updated, 0)
+	}
+	if size, err := strconv.ParseInt(state.Get("SizeOnDisk"), 10, 64); err == nil {
+		manifest.SizeOnDisk = size
+	}
+
+	return manifest, nil
+}
+
+// GameDir returns the directory the app is installed to.
+func (m *AppManifest) GameDir() string {
+	return filepath.Join(m.Library, "steamapps", "common", m.InstallDir)
+}
+
+// IsFullyInstalled reports whether Steam considers the app installed and is
+// not currently downloading, validating or otherwise touching its files.
+func (m *AppManifest) IsFullyInstalled() bool {
+	return m.StateFlags&StateFullyInstalled != 0 && m.StateFlags&busyStates == 0
+}
+
+// StateDescription returns a human readable summary of the state flags.
+func (m *AppManifest) StateDescription() string {
+	names := []struct {
+		flag int
+		name string
+	}{
+		{StateUninstalled, "uninstalled"},
+		{StateUpdateRequired, "update required"},
+		{StateFullyInstalled, "fully installed"},
+		{StateFilesMissing, "files missing"},
+		{StateAppRunning, "running"},
+		{StateFilesCorrupt, "files corrupt"},
+		{StateUpdateRunning, "update running"},
+		{StateUpdatePaused, "update paused"},
+		{StateUpdateStarted, "update started"},
+		{StateUninstalling, "uninstalling"},
+		{StateValidating, "validating"},
+		{StateDownloading, "downloading"},
+		{StateStaging, "staging"},
+		{StateCommitting, "committing"},
+	}
+
+	var parts []string
+	for _, n := range names {
+		if m.StateFlags&n.flag != 0 {
+			parts = append(parts, n.name)
+		}
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("unknown (%d)", m.StateFlags)
+	}
+	return strings.Join(parts, ", ")
+}
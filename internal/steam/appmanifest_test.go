@@ -0,0 +1,62 @@
+package steam
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadAppManifest(t *testing.T) {
+	libDir, err := os.MkdirTemp("", "test-library")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(libDir)
+
+	if err := os.MkdirAll(filepath.Join(libDir, "steamapps"), 0755); err != nil {
+		t.Fatalf("Failed to create steamapps directory: %v", err)
+	}
+
+	content := `"AppState"
+{
+	"appid"		"377160"
+	"name"		"Fallout 4"
+	"StateFlags"		"4"
+	"installdir"		"Fallout 4 (localized)"
+	"LastUpdated"		"1700000000"
+	"buildid"		"13154210"
+}
+`
+	if err := os.WriteFile(GetAppManifestPath(libDir, "377160"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write app manifest: %v", err)
+	}
+
+	manifest, err := ReadAppManifest(libDir, "377160")
+	if err != nil {
+		t.Fatalf("ReadAppManifest failed: %v", err)
+	}
+	if manifest.InstallDir != "Fallout 4 (localized)" {
+		t.Errorf("expected installdir %q, got %q", "Fallout 4 (localized)", manifest.InstallDir)
+	}
+	if manifest.BuildID != "13154210" {
+		t.Errorf("expected buildid 13154210, got %q", manifest.BuildID)
+	}
+	if manifest.LastUpdated.Unix() != 1700000000 {
+		t.Errorf("unexpected LastUpdated: %v", manifest.LastUpdated)
+	}
+	if want := filepath.Join(libDir, "steamapps", "common", "Fallout 4 (localized)"); manifest.GameDir() != want {
+		t.Errorf("expected game dir %q, got %q", want, manifest.GameDir())
+	}
+	if !manifest.IsFullyInstalled() {
+		t.Errorf("expected StateFlags 4 to be fully installed")
+	}
+
+	manifest.StateFlags = StateFullyInstalled | StateUpdateRequired | StateUpdateRunning
+	if manifest.IsFullyInstalled() {
+		t.Errorf("expected a running update not to count as fully installed")
+	}
+
+	if _, err := ReadAppManifest(libDir, "22300"); !os.IsNotExist(err) {
+		t.Errorf("expected not-exist error for missing manifest, got %v", err)
+	}
+}
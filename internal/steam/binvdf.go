@@ -0,0 +1,188 @@
+package steam
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+)
+
+// Type markers of the binary KeyValues format used by shortcuts.vdf.
+const (
+	binaryMap     = 0x00
+	binaryString  = 0x01
+	binaryInt32   = 0x02
+	binaryFloat32 = 0x03
+	binaryUint64  = 0x07
+	binaryEnd     = 0x08
+	binaryInt64   = 0x0A
+)
+
+// ReadBinaryVDF parses a binary KeyValues document. Like ParseVDF, the
+// returned root node has no key and holds the top level entries.
+func ReadBinaryVDF(r io.Reader) (*Node, error) {
+	br := bufio.NewReader(r)
+	root := &Node{Children: []*Node{}}
+	if err := readBinaryChildren(br, root, false); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// ReadBinaryVDFFile parses the binary KeyValues document at path.
+func ReadBinaryVDFFile(path string) (*Node, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	root, err := ReadBinaryVDF(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return root, nil
+}
+
+func readBinaryChildren(r *bufio.Reader, parent *Node, nested bool) error {
+	for {
+		kind, err := r.ReadByte()
+		if err == io.EOF && !nested {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unexpected end of data in %q: %w", parent.Key, err)
+		}
+		if kind == binaryEnd {
+			return nil
+		}
+
+		key, err := readCString(r)
+		if err != nil {
+			return err
+		}
+		node := &Node{Key: key}
+
+		switch kind {
+		case binaryMap:
+			node.Children = []*Node{}
+			if err := readBinaryChildren(r, node, true); err != nil {
+				return err
+			}
+		case binaryString:
+			node.Type = TypeString
+			if node.Value, err = readCString(r); err != nil {
+				return err
+			}
+		case binaryInt32:
+			var v int32
+			if err := binary.Read(r, binary.LittleEndian, &v); err != nil {
+				return err
+			}
+			node.Type = TypeInt32
+			node.Value = strconv.FormatInt(int64(v), 10)
+		case binaryFloat32:
+			var v uint32
+			if err := binary.Read(r, binary.LittleEndian, &v); err != nil {
+				return err
+			}
+			node.Type = TypeFloat32
+			node.Value = strconv.FormatFloat(float64(math.Float32frombits(v)), 'g', -1, 32)
+		case binaryUint64:
+			var v uint64
+			if err := binary.Read(r, binary.LittleEndian, &v); err != nil {
+				return err
+			}
+			node.Type = TypeUint64
+			node.Value = strconv.FormatUint(v, 10)
+		case binaryInt64:
+			var v int64
+			if err := binary.Read(r, binary.LittleEndian, &v); err != nil {
+				return err
+			}
+			node.Type = TypeInt64
+			node.Value = strconv.FormatInt(v, 10)
+		default:
+			return fmt.Errorf("unsupported value type 0x%02x for key %q", kind, key)
+		}
+
+		parent.Children = append(parent.Children, node)
+	}
+}
+
+func readCString(r *bufio.Reader) (string, error) {
+	s, err := r.ReadString(0)
+	if err != nil {
+		return "", fmt.Errorf("unterminated string: %w", err)
+	}
+	return s[:len(s)-1], nil
+}
+
+// WriteBinaryVDF writes the children of root as a binary KeyValues document.
+func WriteBinaryVDF(w io.Writer, root *Node) error {
+	bw := bufio.NewWriter(w)
+	for _, child := range root.Children {
+		if err := writeBinaryNode(bw, child); err != nil {
+			return err
+		}
+	}
+	bw.WriteByte(binaryEnd)
+	return bw.Flush()
+}
+
+func writeBinaryNode(w *bufio.Writer, n *Node) error {
+	writeKey := func(kind byte) {
+		w.WriteByte(kind)
+		w.WriteString(n.Key)
+		w.WriteByte(0)
+	}
+
+	if n.IsSection() {
+		writeKey(binaryMap)
+		for _, child := range n.Children {
+			if err := writeBinaryNode(w, child); err != nil {
+				return err
+			}
+		}
+		return w.WriteByte(binaryEnd)
+	}
+
+	switch n.Type {
+	case TypeString:
+		writeKey(binaryString)
+		w.WriteString(n.Value)
+		return w.WriteByte(0)
+	case TypeInt32:
+		v, err := strconv.ParseInt(n.Value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid int32 value %q for key %q", n.Value, n.Key)
+		}
+		writeKey(binaryInt32)
+		return binary.Write(w, binary.LittleEndian, int32(v))
+	case TypeFloat32:
+		v, err := strconv.ParseFloat(n.Value, 32)
+		if err != nil {
+			return fmt.Errorf("invalid float value %q for key %q", n.Value, n.Key)
+		}
+		writeKey(binaryFloat32)
+		return binary.Write(w, binary.LittleEndian, math.Float32bits(float32(v)))
+	case TypeUint64:
+		v, err := strconv.ParseUint(n.Value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid uint64 value %q for key %q", n.Value, n.Key)
+		}
+		writeKey(binaryUint64)
+		return binary.Write(w, binary.LittleEndian, v)
+	case TypeInt64:
+		v, err := strconv.ParseInt(n.Value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid int64 value %q for key %q", n.Value, n.Key)
+		}
+		writeKey(binaryInt64)
+		return binary.Write(w, binary.LittleEndian, v)
+	}
+	return fmt.Errorf("unsupported value type %d for key %q", n.Type, n.Key)
+}
@@ -0,0 +1,42 @@
+package steam
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// DefaultCompatTool is the Proton version used when none is requested.
+const DefaultCompatTool = "proton_experimental"
+
+// GetSteamConfigPath returns the path to the client's config.vdf.
+func GetSteamConfigPath(steamRoot string) string {
+	return filepath.Join(steamRoot, "config", "config.vdf")
+}
+
+// SetCompatTool forces a compatibility tool (e.g. a Proton version) for an
+// app ID, the same as ticking "Force the use of a specific Steam Play
+// compatibility tool" in the game properties. Steam must not be running.
+func SetCompatTool(steamRoot, appID, toolName string) error {
+	path := GetSteamConfigPath(steamRoot)
+	root, err := ParseVDFFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read Steam config: %w", err)
+	}
+
+	steamSection := root.ChildOrCreate("InstallConfigStore").
+		ChildOrCreate("Software").
+		ChildOrCreate("Valve").
+		ChildOrCreate("Steam")
+	mapping := steamSection.ChildOrCreate("CompatToolMapping").ChildOrCreate(appID)
+	mapping.Set("name", toolName, TypeString)
+	mapping.Set("config", "", TypeString)
+	mapping.Set("priority", "250", TypeString)
+
+	if err := backupFile(path); err != nil {
+		return err
+	}
+	if err := WriteVDFFile(path, root); err != nil {
+		return fmt.Errorf("failed to write Steam config: %w", err)
+	}
+	return nil
+}
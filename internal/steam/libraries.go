@@ -0,0 +1,153 @@
+package steam
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Library is a Steam library folder, e.g. the default one inside the Steam
+// root or an additional one on a second drive.
+type Library struct {
+	Path  string
+	Label string
+	Apps  []string // AppIDs Steam reports as installed in this library
+}
+
+// SteamApps returns the steamapps directory of the library.
+func (l Library) SteamApps() string {
+	return filepath.Join(l.Path, "steamapps")
+}
+
+// HasApp reports whether Steam lists the given AppID in this library.
+func (l Library) HasApp(appID string) bool {
+	for _, id := range l.Apps {
+		if id == appID {
+			return true
+		}
+	}
+	return false
+}
+
+// GetLibraryFoldersPath returns the path to libraryfolders.vdf for a Steam root.
+func GetLibraryFoldersPath(steamRoot string) string {
+	return filepath.Join(steamRoot, "steamapps", "libraryfolders.vdf")
+}
+
+// FindLibraries returns every library listed in libraryfolders.vdf.
+// The Steam root itself is always included, even if the file is missing.
+func FindLibraries(steamRoot string) ([]Library, error) {
+	root, err := ParseVDFFile(GetLibraryFoldersPath(steamRoot))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Library{{Path: steamRoot}}, nil
+		}
+		return nil, err
+	}
+
+	libraries := ParseLibraryFolders(root)
+
+	hasRoot := false
+	for _, lib := range libraries {
+		if samePath(lib.Path, steamRoot) {
+			hasRoot = true
+			break
+		}
+	}
+	if !hasRoot {
+		libraries = append([]Library{{Path: steamRoot}}, libraries...)
+	}
+
+	return libraries, nil
+}
+
+// ParseLibraryFolders extracts the libraries from a parsed libraryfolders.vdf.
+// Both the current format (one section per library with an "apps" map) and the
+// legacy format (numbered keys holding plain paths) are understood.
+func ParseLibraryFolders(root *Node) []Library {
+	folders := root.Child("libraryfolders")
+	if folders == nil {
+		return nil
+	}
+
+	var libraries []Library
+	for _, entry := range folders.Children {
+		if !isNumeric(entry.Key) {
+			continue // TimeNextStatsReport, ContentStatsID, ...
+		}
+
+		if !entry.IsSection() {
+			if entry.Value != "" {
+				libraries = append(libraries, Library{Path: entry.Value})
+			}
+			continue
+		}
+
+		lib := Library{
+			Path:  entry.Get("path"),
+			Label: entry.Get("label"),
+		}
+		if lib.Path == "" {
+			continue
+		}
+		if apps := entry.Child("apps"); apps != nil {
+			for _, app := range apps.Children {
+				lib.Apps = append(lib.Apps, app.Key)
+			}
+		}
+		libraries = append(libraries, lib)
+	}
+
+	return libraries
+}
+
+// MapAppsToLibraries maps every AppID listed in libraryfolders.vdf to the
+// library it is installed in.
+func MapAppsToLibraries(libraries []Library) map[string]Library {
+	apps := make(map[string]Library)
+	for _, lib := range libraries {
+		for _, appID := range lib.Apps {
+			apps[appID] = lib
+		}
+	}
+	return apps
+}
+
+// OrderLibrariesForApp returns the libraries with the one Steam reports the
+// app installed in moved to the front, so callers can search them in order.
+func OrderLibrariesForApp(libraries []Library, appID string) []Library {
+	ordered := make([]Library, 0, len(libraries))
+	for _, lib := range libraries {
+		if lib.HasApp(appID) {
+			ordered = append(ordered, lib)
+		}
+	}
+	for _, lib := range libraries {
+		if !lib.HasApp(appID) {
+			ordered = append(ordered, lib)
+		}
+	}
+	return ordered
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// samePath reports whether two paths point at the same directory,
+// resolving symlinks such as ~/.steam/steam.
+func samePath(a, b string) bool {
+	if filepath.Clean(a) == filepath.Clean(b) {
+		return true
+	}
+	ra, errA := filepath.EvalSymlinks(a)
+	rb, errB := filepath.EvalSymlinks(b)
+	return errA == nil && errB == nil && ra == rb
+}
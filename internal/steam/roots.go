@@ -0,0 +1,135 @@
+package steam
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// Environment variables that override Steam root discovery.
+const (
+	// EnvSteamRoot lets the user point Fusion Core at a Steam root explicitly.
+	EnvSteamRoot = "FUSION_CORE_STEAM_ROOT"
+	// EnvCompatClientInstallPath is set by Steam for games launched through Proton.
+	EnvCompatClientInstallPath = "STEAM_COMPAT_CLIENT_INSTALL_PATH"
+)
+
+// RootCandidate is a directory that was considered as a Steam root.
+type RootCandidate struct {
+	Path     string
+	Source   string // Where the candidate came from, e.g. "flatpak"
+	Found    bool   // Whether the path is a usable Steam root
+	Selected bool   // Whether the candidate is used for discovery
+	Reason   string // Why the candidate was selected or skipped
+}
+
+// FindRootCandidates checks every known Steam location in priority order and
+// reports what was found at each of them. Environment overrides come first,
+// followed by native Steam (also used on the Steam Deck), Flatpak and snap.
+func FindRootCandidates() []RootCandidate {
+	var candidates []RootCandidate
+
+	for _, env := range []string{EnvSteamRoot, EnvCompatClientInstallPath} {
+		if path := os.Getenv(env); path != "" {
+			candidates = append(candidates, RootCandidate{Path: path, Source: "$" + env})
+		}
+	}
+
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		flatpakDir := filepath.Join(homeDir, ".var", "app", "com.valvesoftware.Steam")
+		snapDir := filepath.Join(homeDir, "snap", "steam", "common")
+
+		candidates = append(candidates,
+			RootCandidate{Path: filepath.Join(homeDir, ".steam", "steam"), Source: "native"},
+			RootCandidate{Path: filepath.Join(homeDir, ".local", "share", "Steam"), Source: "native"},
+			RootCandidate{Path: filepath.Join(homeDir, ".steam", "root"), Source: "native"},
+			RootCandidate{Path: filepath.Join(flatpakDir, ".local", "share", "Steam"), Source: "flatpak"},
+			RootCandidate{Path: filepath.Join(flatpakDir, "data", "Steam"), Source: "flatpak"},
+			RootCandidate{Path: filepath.Join(snapDir, ".local", "share", "Steam"), Source: "snap"},
+			RootCandidate{Path: filepath.Join(snapDir, ".steam", "steam"), Source: "snap"},
+		)
+	}
+
+	selected := make(map[string]string)
+	for i := range candidates {
+		c := &candidates[i]
+
+		resolved, err := filepath.EvalSymlinks(c.Path)
+		if err != nil {
+			c.Reason = "does not exist"
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(resolved, "steamapps")); err != nil {
+			c.Reason = "exists but has no steamapps directory"
+			continue
+		}
+		c.Found = true
+
+		if first, ok := selected[resolved]; ok {
+			c.Reason = "same installation as " + first
+			continue
+		}
+		selected[resolved] = c.Path
+		c.Selected = true
+		if resolved != filepath.Clean(c.Path) {
+			c.Reason = "found (links to " + resolved + ")"
+		} else {
+			c.Reason = "found"
+		}
+	}
+
+	return candidates
+}
+
+// FindRoots returns every distinct Steam root on the system, in priority order.
+func FindRoots() ([]string, error) {
+	var roots []string
+	for _, c := range FindRootCandidates() {
+		if c.Selected {
+			roots = append(roots, c.Path)
+		}
+	}
+	if len(roots) == 0 {
+		return nil, errors.New("Steam root directory not found")
+	}
+	return roots, nil
+}
+
+// FindRoot returns the highest priority Steam root.
+func FindRoot() (string, error) {
+	roots, err := FindRoots()
+	if err != nil {
+		return "", err
+	}
+	return roots[0], nil
+}
+
+// FindAllLibraries returns the libraries of every Steam root, without duplicates.
+func FindAllLibraries() ([]Library, error) {
+	roots, err := FindRoots()
+	if err != nil {
+		return nil, err
+	}
+
+	var libraries []Library
+	for _, root := range roots {
+		libs, err := FindLibraries(root)
+		if err != nil {
+			return nil, err
+		}
+		for _, lib := range libs {
+			duplicate := false
+			for _, existing := range libraries {
+				if samePath(existing.Path, lib.Path) {
+					duplicate = true
+					break
+				}
+			}
+			if !duplicate {
+				libraries = append(libraries, lib)
+			}
+		}
+	}
+
+	return libraries, nil
+}
@@ -0,0 +1,59 @@
+package steam
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindRootCandidates(t *testing.T) {
+	homeDir, err := os.MkdirTemp("", "test-home")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(homeDir)
+
+	t.Setenv("HOME", homeDir)
+	t.Setenv(EnvSteamRoot, "")
+	t.Setenv(EnvCompatClientInstallPath, "")
+
+	nativeRoot := filepath.Join(homeDir, ".local", "share", "Steam")
+	flatpakRoot := filepath.Join(homeDir, ".var", "app", "com.valvesoftware.Steam", ".local", "share", "Steam")
+	for _, dir := range []string{nativeRoot, flatpakRoot} {
+		if err := os.MkdirAll(filepath.Join(dir, "steamapps"), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	// ~/.steam/steam is normally a symlink to the real root
+	if err := os.MkdirAll(filepath.Join(homeDir, ".steam"), 0755); err != nil {
+		t.Fatalf("Failed to create .steam: %v", err)
+	}
+	if err := os.Symlink(nativeRoot, filepath.Join(homeDir, ".steam", "steam")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	roots, err := FindRoots()
+	if err != nil {
+		t.Fatalf("FindRoots failed: %v", err)
+	}
+	expected := []string{filepath.Join(homeDir, ".steam", "steam"), flatpakRoot}
+	if len(roots) != len(expected) || roots[0] != expected[0] || roots[1] != expected[1] {
+		t.Errorf("expected roots %v, got %v", expected, roots)
+	}
+
+	for _, c := range FindRootCandidates() {
+		if c.Path == nativeRoot && c.Selected {
+			t.Errorf("expected %s to be reported as a duplicate, got %q", nativeRoot, c.Reason)
+		}
+	}
+
+	// Environment overrides take priority
+	t.Setenv(EnvCompatClientInstallPath, flatpakRoot)
+	root, err := FindRoot()
+	if err != nil {
+		t.Fatalf("FindRoot failed: %v", err)
+	}
+	if root != flatpakRoot {
+		t.Errorf("expected override %s to win, got %s", flatpakRoot, root)
+	}
+}
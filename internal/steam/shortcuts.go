@@ -0,0 +1,234 @@
+package steam
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bazsalanszky/fusioncore/internal/store"
+)
+
+// Shortcut is a non-Steam game added to a Steam user's library.
+type Shortcut struct {
+	AppID         uint32
+	AppName       string
+	Exe           string // Executable path without the surrounding quotes
+	StartDir      string
+	LaunchOptions string
+	UserID        string // Steam user the shortcut belongs to
+	Root          string // Steam root the shortcut was read from
+}
+
+// ShortcutAppID computes the app ID Steam assigns to a non-Steam shortcut.
+// exe is the executable as stored in shortcuts.vdf, including any quotes.
+func ShortcutAppID(exe, appName string) uint32 {
+	return crc32.ChecksumIEEE([]byte(exe+appName)) | 0x80000000
+}
+
+// GameID returns the 64-bit game ID used in steam://rungameid/ URLs.
+func (s Shortcut) GameID() uint64 {
+	return uint64(s.AppID)<<32 | 0x02000000
+}
+
+// CompatdataID returns the name of the compatdata directory of the shortcut.
+func (s Shortcut) CompatdataID() string {
+	return strconv.FormatUint(uint64(s.AppID), 10)
+}
+
+// GetShortcutsPath returns the path of a Steam user's shortcuts.vdf.
+func GetShortcutsPath(steamRoot, userID string) string {
+	return filepath.Join(steamRoot, "userdata", userID, "config", "shortcuts.vdf")
+}
+
+// FindUserIDs returns the Steam user IDs that have a userdata directory in the Steam root.
+func FindUserIDs(steamRoot string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(steamRoot, "userdata"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var userIDs []string
+	for _, entry := range entries {
+		// userdata/0 and ac/ are not real users
+		if entry.IsDir() && isNumeric(entry.Name()) && entry.Name() != "0" {
+			userIDs = append(userIDs, entry.Name())
+		}
+	}
+	return userIDs, nil
+}
+
+// ReadShortcuts reads the shortcuts of a Steam user. A missing file means no shortcuts.
+func ReadShortcuts(steamRoot, userID string) ([]Shortcut, error) {
+	root, err := ReadBinaryVDFFile(GetShortcutsPath(steamRoot, userID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	shortcuts := ParseShortcuts(root)
+	for i := range shortcuts {
+		shortcuts[i].UserID = userID
+		shortcuts[i].Root = steamRoot
+	}
+	return shortcuts, nil
+}
+
+// ParseShortcuts extracts the shortcuts from a parsed shortcuts.vdf.
+func ParseShortcuts(root *Node) []Shortcut {
+	list := root.Child("shortcuts")
+	if list == nil {
+		return nil
+	}
+
+	var shortcuts []Shortcut
+	for _, entry := range list.Children {
+		if !entry.IsSection() {
+			continue
+		}
+
+		rawExe := entry.Get("Exe")
+		s := Shortcut{
+			AppName:       entry.Get("AppName"),
+			Exe:           strings.Trim(rawExe, `"`),
+			StartDir:      strings.Trim(entry.Get("StartDir"), `"`),
+			LaunchOptions: entry.Get("LaunchOptions"),
+		}
+
+		// Older clients don't store the app ID, it is derived from the exe and name
+		if id, err := strconv.ParseInt(entry.Get("appid"), 10, 64); err == nil && id != 0 {
+			s.AppID = uint32(id)
+		} else {
+			s.AppID = ShortcutAppID(rawExe, s.AppName)
+		}
+
+		shortcuts = append(shortcuts, s)
+	}
+	return shortcuts
+}
+
+// FindAllShortcuts returns the shortcuts of every user in every Steam root.
+func FindAllShortcuts() ([]Shortcut, error) {
+	roots, err := FindRoots()
+	if err != nil {
+		return nil, err
+	}
+
+	var shortcuts []Shortcut
+	for _, root := range roots {
+		userIDs, err := FindUserIDs(root)
+		if err != nil {
+			return nil, err
+		}
+		for _, userID := range userIDs {
+			found, err := ReadShortcuts(root, userID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read shortcuts of user %s: %w", userID, err)
+			}
+			shortcuts = append(shortcuts, found...)
+		}
+	}
+	return shortcuts, nil
+}
+
+// FindCompatdata returns the compatdata directory of an app ID in the given libraries.
+func FindCompatdata(libraries []Library, appID string) (string, bool) {
+	for _, lib := range libraries {
+		compatdataPath := filepath.Join(lib.SteamApps(), "compatdata", appID)
+		if _, err := os.Stat(compatdataPath); err == nil {
+			return compatdataPath, true
+		}
+	}
+	return "", false
+}
+
+// AddShortcut adds a non-Steam shortcut to a Steam user's shortcuts.vdf, or
+// updates the existing one with the same name. Unknown fields of existing
+// shortcuts are preserved. Steam must not be running, or it will overwrite
+// the file on exit.
+func AddShortcut(steamRoot, userID string, s Shortcut) (Shortcut, error) {
+	path := GetShortcutsPath(steamRoot, userID)
+
+	root, err := ReadBinaryVDFFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return s, err
+		}
+		root = &Node{Children: []*Node{}}
+	}
+	list := root.ChildOrCreate("shortcuts")
+
+	quotedExe := `"` + s.Exe + `"`
+	s.AppID = ShortcutAppID(quotedExe, s.AppName)
+
+	var entry *Node
+	for _, existing := range list.Children {
+		if existing.IsSection() && existing.Get("AppName") == s.AppName {
+			entry = existing
+			break
+		}
+	}
+	if entry == nil {
+		entry = &Node{Key: strconv.Itoa(len(list.Children)), Children: []*Node{}}
+		list.Children = append(list.Children, entry)
+		entry.Set("icon", "", TypeString)
+		entry.Set("ShortcutPath", "", TypeString)
+		entry.Set("IsHidden", "0", TypeInt32)
+		entry.Set("AllowDesktopConfig", "1", TypeInt32)
+		entry.Set("AllowOverlay", "1", TypeInt32)
+		entry.Set("OpenVR", "0", TypeInt32)
+		entry.Set("Devkit", "0", TypeInt32)
+		entry.Set("DevkitGameID", "", TypeString)
+		entry.Set("DevkitOverrideAppID", "0", TypeInt32)
+		entry.Set("LastPlayTime", "0", TypeInt32)
+		entry.Set("FlatpakAppID", "", TypeString)
+		entry.ChildOrCreate("tags")
+	}
+
+	entry.Set("appid", strconv.FormatInt(int64(int32(s.AppID)), 10), TypeInt32)
+	entry.Set("AppName", s.AppName, TypeString)
+	entry.Set("Exe", quotedExe, TypeString)
+	entry.Set("StartDir", `"`+s.StartDir+`"`, TypeString)
+	entry.Set("LaunchOptions", s.LaunchOptions, TypeString)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return s, fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := backupFile(path); err != nil {
+		return s, err
+	}
+	var buf bytes.Buffer
+	if err := WriteBinaryVDF(&buf, root); err != nil {
+		return s, fmt.Errorf("failed to encode shortcuts.vdf: %w", err)
+	}
+	if err := store.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return s, fmt.Errorf("failed to write shortcuts.vdf: %w", err)
+	}
+
+	s.UserID = userID
+	s.Root = steamRoot
+	return s, nil
+}
+
+// backupFile copies path to path.bak before it is rewritten, if it exists.
+func backupFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err := os.WriteFile(path+".bak", data, 0644); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package steam
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// binaryEntry appends a binary KeyValues entry to buf.
+func binaryEntry(buf *bytes.Buffer, kind byte, key string, value interface{}) {
+	buf.WriteByte(kind)
+	buf.WriteString(key)
+	buf.WriteByte(0)
+	switch v := value.(type) {
+	case string:
+		buf.WriteString(v)
+		buf.WriteByte(0)
+	case int32:
+		binary.Write(buf, binary.LittleEndian, v)
+	}
+}
+
+func TestReadShortcuts(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteByte(binaryMap)
+	buf.WriteString("shortcuts\x00")
+
+	// New clients store the app ID as a signed int32
+	buf.WriteByte(binaryMap)
+	buf.WriteString("0\x00")
+	binaryEntry(&buf, binaryInt32, "appid", int32(-1000000000))
+	binaryEntry(&buf, binaryString, "AppName", "Fallout New Vegas GOG")
+	binaryEntry(&buf, binaryString, "Exe", `"/games/FNV/FalloutNV.exe"`)
+	binaryEntry(&buf, binaryString, "StartDir", `"/games/FNV/"`)
+	buf.WriteByte(binaryMap)
+	buf.WriteString("tags\x00")
+	buf.WriteByte(binaryEnd)
+	buf.WriteByte(binaryEnd)
+
+	// Old clients don't store it at all
+	buf.WriteByte(binaryMap)
+	buf.WriteString("1\x00")
+	binaryEntry(&buf, binaryString, "AppName", "Fallout 3 GOG")
+	binaryEntry(&buf, binaryString, "Exe", `"/games/Fallout 3/Fallout3.exe"`)
+	buf.WriteByte(binaryEnd)
+
+	buf.WriteByte(binaryEnd)
+	buf.WriteByte(binaryEnd)
+
+	root, err := ReadBinaryVDF(&buf)
+	if err != nil {
+		t.Fatalf("ReadBinaryVDF failed: %v", err)
+	}
+
+	if tags := root.Lookup("shortcuts", "0", "tags"); tags == nil || !tags.IsSection() {
+		t.Errorf("expected empty tags section, got %+v", tags)
+	}
+
+	shortcuts := ParseShortcuts(root)
+	if len(shortcuts) != 2 {
+		t.Fatalf("expected 2 shortcuts, got %d", len(shortcuts))
+	}
+
+	if shortcuts[0].CompatdataID() != "3294967296" {
+		t.Errorf("expected app ID 3294967296, got %s", shortcuts[0].CompatdataID())
+	}
+	if shortcuts[0].Exe != "/games/FNV/FalloutNV.exe" || shortcuts[0].StartDir != "/games/FNV/" {
+		t.Errorf("expected quotes to be stripped, got %q and %q", shortcuts[0].Exe, shortcuts[0].StartDir)
+	}
+	if shortcuts[1].AppID != 3305958291 {
+		t.Errorf("expected computed app ID 3305958291, got %d", shortcuts[1].AppID)
+	}
+	if gameID := shortcuts[1].GameID(); gameID != uint64(3305958291)<<32|0x02000000 {
+		t.Errorf("unexpected game ID %d", gameID)
+	}
+}
+
+func TestAddShortcut(t *testing.T) {
+	steamRoot, err := os.MkdirTemp("", "test-steam-root")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(steamRoot)
+
+	shortcut := Shortcut{
+		AppName:       "Fallout 4 (Fusion Core)",
+		Exe:           "/games/Fallout 4/f4se_loader.exe",
+		StartDir:      "/games/Fallout 4",
+		LaunchOptions: "%command%",
+	}
+	added, err := AddShortcut(steamRoot, "12345", shortcut)
+	if err != nil {
+		t.Fatalf("AddShortcut failed: %v", err)
+	}
+	if added.AppID != ShortcutAppID(`"/games/Fallout 4/f4se_loader.exe"`, shortcut.AppName) {
+		t.Errorf("unexpected app ID %d", added.AppID)
+	}
+
+	// Adding it again updates the existing entry instead of duplicating it
+	shortcut.LaunchOptions = "-foo %command%"
+	if _, err := AddShortcut(steamRoot, "12345", shortcut); err != nil {
+		t.Fatalf("AddShortcut failed on update: %v", err)
+	}
+
+	shortcuts, err := ReadShortcuts(steamRoot, "12345")
+	if err != nil {
+		t.Fatalf("ReadShortcuts failed: %v", err)
+	}
+	if len(shortcuts) != 1 {
+		t.Fatalf("expected 1 shortcut, got %d", len(shortcuts))
+	}
+	if shortcuts[0].AppID != added.AppID || shortcuts[0].LaunchOptions != "-foo %command%" || shortcuts[0].Exe != shortcut.Exe {
+		t.Errorf("shortcut did not round-trip: %+v", shortcuts[0])
+	}
+}
+
+func TestSetCompatTool(t *testing.T) {
+	steamRoot, err := os.MkdirTemp("", "test-steam-root")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(steamRoot)
+
+	configPath := GetSteamConfigPath(steamRoot)
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+	content := "\"InstallConfigStore\"\n{\n\t\"Software\"\n\t{\n\t\t\"valve\"\n\t\t{\n\t\t\t\"Steam\"\n\t\t\t{\n\t\t\t\t\"AutoUpdateWindowEnabled\"\t\t\"0\"\n\t\t\t}\n\t\t}\n\t}\n}\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config.vdf: %v", err)
+	}
+
+	if err := SetCompatTool(steamRoot, "3294967296", "proton_9"); err != nil {
+		t.Fatalf("SetCompatTool failed: %v", err)
+	}
+
+	root, err := ParseVDFFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to parse written config.vdf: %v", err)
+	}
+	steamSection := root.Lookup("InstallConfigStore", "Software", "Valve", "Steam")
+	if steamSection.Get("AutoUpdateWindowEnabled") != "0" {
+		t.Errorf("expected existing settings to be preserved")
+	}
+	if got := steamSection.Lookup("CompatToolMapping", "3294967296").Get("name"); got != "proton_9" {
+		t.Errorf("expected compat tool proton_9, got %q", got)
+	}
+}
@@ -0,0 +1,78 @@
+package steam
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// steamID64Base is the offset between 64-bit Steam IDs and the 32-bit account
+// IDs used as userdata directory names.
+const steamID64Base = 76561197960265728
+
+// FindActiveUserID returns the userdata ID of the user that logged in most
+// recently, falling back to the only user if there is just one.
+func FindActiveUserID(steamRoot string) (string, error) {
+	userIDs, err := FindUserIDs(steamRoot)
+	if err != nil {
+		return "", err
+	}
+	if len(userIDs) == 0 {
+		return "", errors.New("no Steam users found. Log in to Steam at least once")
+	}
+	if len(userIDs) == 1 {
+		return userIDs[0], nil
+	}
+
+	root, err := ParseVDFFile(filepath.Join(steamRoot, "config", "loginusers.vdf"))
+	if err == nil {
+		if users := root.Lookup("users"); users != nil {
+			for _, user := range users.Children {
+				if user.Get("MostRecent") != "1" {
+					continue
+				}
+				steamID, err := strconv.ParseUint(user.Key, 10, 64)
+				if err != nil || steamID < steamID64Base {
+					continue
+				}
+				// The user may have logged in without a userdata directory
+				userID := strconv.FormatUint(steamID-steamID64Base, 10)
+				if slices.Contains(userIDs, userID) {
+					return userID, nil
+				}
+			}
+		}
+	}
+
+	return "", fmt.Errorf("multiple Steam users found (%s), please choose one", strings.Join(userIDs, ", "))
+}
+
+// IsRunning reports whether the Steam client is running, based on the pid
+// file Steam writes on start.
+func IsRunning() bool {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return false
+	}
+	for _, pidFile := range []string{
+		filepath.Join(homeDir, ".steam", "steam.pid"),
+		filepath.Join(homeDir, ".var", "app", "com.valvesoftware.Steam", ".steam", "steam.pid"),
+	} {
+		data, err := os.ReadFile(pidFile)
+		if err != nil {
+			continue
+		}
+		pid := strings.TrimSpace(string(data))
+		if _, err := strconv.Atoi(pid); err != nil {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join("/proc", pid)); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package steam
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindActiveUserID(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-steam-users")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for _, userID := range []string{"0", "111", "222"} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, "userdata", userID), 0755); err != nil {
+			t.Fatalf("Failed to create userdata: %v", err)
+		}
+	}
+	loginUsers := filepath.Join(tmpDir, "config", "loginusers.vdf")
+	if err := os.MkdirAll(filepath.Dir(loginUsers), 0755); err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"empty", "", ""},
+		{"no users", `"users" {}`, ""},
+		// 76561197960265839 is account 111
+		{"most recent", `"users" { "76561197960265839" { "MostRecent" "1" } }`, "111"},
+		// 76561197960266061 is account 333, which has no userdata
+		{"most recent without userdata", `"users" { "76561197960266061" { "MostRecent" "1" } }`, ""},
+	}
+	for _, test := range tests {
+		if err := os.WriteFile(loginUsers, []byte(test.content), 0644); err != nil {
+			t.Fatalf("Failed to write loginusers.vdf: %v", err)
+		}
+		userID, err := FindActiveUserID(tmpDir)
+		if test.expected == "" {
+			if err == nil {
+				t.Errorf("%s: expected an error, got user %s", test.name, userID)
+			}
+			continue
+		}
+		if err != nil || userID != test.expected {
+			t.Errorf("%s: expected user %s, got %q (%v)", test.name, test.expected, userID, err)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package steam
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bazsalanszky/fusioncore/internal/store"
+)
+
+// NodeType is the type of a value in a binary KeyValues document.
+// Text documents only contain strings.
+type NodeType int
+
+const (
+	TypeString NodeType = iota
+	TypeInt32
+	TypeFloat32
+	TypeUint64
+	TypeInt64
+)
+
+// Node is a single key in a Valve KeyValues (VDF) document.
+// Sections have a non-nil Children slice, plain keys have a Value.
+// Numeric values from binary documents are stored in decimal.
+type Node struct {
+	Key       string
+	Value     string
+	Type      NodeType
+	Children  []*Node
+	Condition string // Platform conditional of text documents, like [$WIN32]
+}
+
+// IsSection reports whether the node holds child keys rather than a value.
+func (n *Node) IsSection() bool {
+	return n.Children != nil
+}
+
+// Child returns the first direct child with the given key.
+// Keys are compared case-insensitively, like Steam does.
+func (n *Node) Child(key string) *Node {
+	if n == nil {
+		return nil
+	}
+	for _, c := range n.Children {
+		if strings.EqualFold(c.Key, key) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Lookup follows a path of keys from n and returns the node at the end of it.
+func (n *Node) Lookup(keys ...string) *Node {
+	current := n
+	for _, key := range keys {
+		current = current.Child(key)
+		if current == nil {
+			return nil
+		}
+	}
+	return current
+}
+
+// Get returns the value of the direct child with the given key, or an empty string.
+func (n *Node) Get(key string) string {
+	if c := n.Child(key); c != nil {
+		return c.Value
+	}
+	return ""
+}
+
+// ParseVDF parses a text KeyValues document. The returned root node has no key;
+// the top level entries of the document are its children.
+func ParseVDF(r io.Reader) (*Node, error) {
+	p := &vdfParser{r: bufio.NewReader(r), line: 1}
+	root := &Node{Children: []*Node{}}
+	if err := p.parseChildren(root, false); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// ParseVDFFile parses the text KeyValues document at path.
+func ParseVDFFile(path string) (*Node, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	root, err := ParseVDF(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return root, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenString
+	tokenOpen
+	tokenClose
+)
+
+type vdfParser struct {
+	r    *bufio.Reader
+	line int
+}
+
+// parseChildren reads key/value pairs into parent until a closing brace
+// (when nested) or the end of the input.
+func (p *vdfParser) parseChildren(parent *Node, nested bool) error {
+	for {
+		kind, key, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch kind {
+		case tokenEOF:
+			if nested {
+				return fmt.Errorf("line %d: unexpected end of file, missing '}'", p.line)
+			}
+			return nil
+		case tokenClose:
+			if !nested {
+				return fmt.Errorf("line %d: unexpected '}'", p.line)
+			}
+			return nil
+		case tokenOpen:
+			return fmt.Errorf("line %d: unexpected '{', expected a key", p.line)
+		}
+
+		// A conditional may come between the key and a section
+		node := &Node{Key: key, Condition: p.readConditional()}
+		kind, value, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch kind {
+		case tokenString:
+			node.Value = value
+		case tokenOpen:
+			node.Children = []*Node{}
+			if err := p.parseChildren(node, true); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("line %d: missing value for key %q", p.line, key)
+		}
+		if condition := p.readConditional(); condition != "" {
+			node.Condition = condition
+		}
+		parent.Children = append(parent.Children, node)
+	}
+}
+
+// next returns the next token, skipping whitespace and comments.
+func (p *vdfParser) next() (tokenKind, string, error) {
+	for {
+		c, err := p.r.ReadByte()
+		if err == io.EOF {
+			return tokenEOF, "", nil
+		}
+		if err != nil {
+			return tokenEOF, "", err
+		}
+
+		switch {
+		case c == '\n':
+			p.line++
+		case c == ' ' || c == '\t' || c == '\r':
+		case c == '{':
+			return tokenOpen, "", nil
+		case c == '}':
+			return tokenClose, "", nil
+		case c == '/':
+			if next, _ := p.r.Peek(1); len(next) == 1 && next[0] == '/' {
+				p.r.ReadString('\n')
+				p.line++
+				continue
+			}
+			return p.readUnquoted(c)
+		case c == '"':
+			return p.readQuoted()
+		default:
+			return p.readUnquoted(c)
+		}
+	}
+}
+
+func (p *vdfParser) readQuoted() (tokenKind, string, error) {
+	var sb strings.Builder
+	for {
+		c, err := p.r.ReadByte()
+		if err == io.EOF {
+			return tokenEOF, "", fmt.Errorf("line %d: unterminated string", p.line)
+		}
+		if err != nil {
+			return tokenEOF, "", err
+		}
+		switch c {
+		case '"':
+			return tokenString, sb.String(), nil
+		case '\n':
+			p.line++
+			sb.WriteByte(c)
+		case '\\':
+			escaped, err := p.r.ReadByte()
+			if err != nil {
+				return tokenEOF, "", fmt.Errorf("line %d: unterminated string", p.line)
+			}
+			switch escaped {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case '\\', '"':
+				sb.WriteByte(escaped)
+			default:
+				sb.WriteByte('\\')
+				sb.WriteByte(escaped)
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+}
+
+func (p *vdfParser) readUnquoted(first byte) (tokenKind, string, error) {
+	var sb strings.Builder
+	sb.WriteByte(first)
+	for {
+		next, err := p.r.Peek(1)
+		if err != nil || len(next) == 0 {
+			break
+		}
+		c := next[0]
+		if c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '{' || c == '}' || c == '"' {
+			break
+		}
+		p.r.ReadByte()
+		sb.WriteByte(c)
+	}
+	return tokenString, sb.String(), nil
+}
+
+// readConditional reads a platform conditional such as [$WIN32] that may
+// follow a key or a key/value pair on the same line, so it can be written
+// back. It returns an empty string if there is none.
+func (p *vdfParser) readConditional() string {
+	for {
+		next, err := p.r.Peek(1)
+		if err != nil || len(next) == 0 {
+			return ""
+		}
+		switch next[0] {
+		case ' ', '\t':
+			p.r.ReadByte()
+		case '[':
+			condition, _ := p.r.ReadString(']')
+			return condition
+		default:
+			return ""
+		}
+	}
+}
+
+// WriteVDF writes the children of root as a text KeyValues document,
+// formatted the way Steam writes its own files.
+func WriteVDF(w io.Writer, root *Node) error {
+	bw := bufio.NewWriter(w)
+	for _, child := range root.Children {
+		writeVDFNode(bw, child, 0)
+	}
+	return bw.Flush()
+}
+
+// WriteVDFFile writes root as a text KeyValues document to path. The file
+// is replaced atomically, so a failed write leaves the old one in place.
+func WriteVDFFile(path string, root *Node) error {
+	var buf bytes.Buffer
+	if err := WriteVDF(&buf, root); err != nil {
+		return err
+	}
+	return store.WriteFile(path, buf.Bytes(), 0644)
+}
+
+func writeVDFNode(w *bufio.Writer, n *Node, depth int) {
+	indent := strings.Repeat("\t", depth)
+	condition := ""
+	if n.Condition != "" {
+		condition = " " + n.Condition
+	}
+	if !n.IsSection() {
+		fmt.Fprintf(w, "%s\"%s\"\t\t\"%s\"%s\n", indent, escapeVDF(n.Key), escapeVDF(n.Value), condition)
+		return
+	}
+	fmt.Fprintf(w, "%s\"%s\"%s\n%s{\n", indent, escapeVDF(n.Key), condition, indent)
+	for _, child := range n.Children {
+		writeVDFNode(w, child, depth+1)
+	}
+	fmt.Fprintf(w, "%s}\n", indent)
+}
+
+// escapeVDF escapes the characters ParseVDF unescapes.
+func escapeVDF(s string) string {
+	return vdfEscaper.Replace(s)
+}
+
+var vdfEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+
+// ChildOrCreate returns the direct child section with the given key,
+// appending an empty section if there is none.
+func (n *Node) ChildOrCreate(key string) *Node {
+	if c := n.Child(key); c != nil {
+		if c.Children == nil {
+			c.Children = []*Node{}
+		}
+		return c
+	}
+	c := &Node{Key: key, Children: []*Node{}}
+	n.Children = append(n.Children, c)
+	return c
+}
+
+// Set sets the value of the direct child with the given key, adding it if
+// needed. A section with the key is replaced by the value.
+func (n *Node) Set(key, value string, typ NodeType) {
+	if c := n.Child(key); c != nil {
+		c.Value = value
+		c.Type = typ
+		c.Children = nil
+		return
+	}
+	n.Children = append(n.Children, &Node{Key: key, Value: value, Type: typ})
+}
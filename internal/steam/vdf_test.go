@@ -0,0 +1,223 @@
+package steam
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testLibraryFolders = `"libraryfolders"
+{
+	"0"
+	{
+		"path"		"/home/user/.local/share/Steam"
+		"label"		""
+		"apps"
+		{
+			"1151340"		"80123456789"
+		}
+	}
+	// Second drive
+	"1"
+	{
+		"path"		"/mnt/ssd2/SteamLibrary"
+		"label"		"Games \"SSD\""
+		"apps"
+		{
+			"377160"		"33000000000"
+			"489830"		"12000000000"
+		}
+	}
+}
+`
+
+func TestParseVDF(t *testing.T) {
+	root, err := ParseVDF(strings.NewReader(testLibraryFolders))
+	if err != nil {
+		t.Fatalf("ParseVDF failed: %v", err)
+	}
+
+	if got := root.Lookup("libraryfolders", "1", "path"); got == nil || got.Value != "/mnt/ssd2/SteamLibrary" {
+		t.Errorf("expected path of library 1 to be /mnt/ssd2/SteamLibrary, got %+v", got)
+	}
+	if got := root.Lookup("LibraryFolders", "1").Get("label"); got != `Games "SSD"` {
+		t.Errorf("expected escaped label, got %q", got)
+	}
+	if got := root.Lookup("libraryfolders", "2"); got != nil {
+		t.Errorf("expected missing library to be nil, got %+v", got)
+	}
+
+	if _, err := ParseVDF(strings.NewReader(`"a" { "b" "c"`)); err == nil {
+		t.Errorf("expected error for unterminated section")
+	}
+}
+
+func TestParseLibraryFolders(t *testing.T) {
+	root, err := ParseVDF(strings.NewReader(testLibraryFolders))
+	if err != nil {
+		t.Fatalf("ParseVDF failed: %v", err)
+	}
+
+	libraries := ParseLibraryFolders(root)
+	if len(libraries) != 2 {
+		t.Fatalf("expected 2 libraries, got %d", len(libraries))
+	}
+	if !reflect.DeepEqual(libraries[1].Apps, []string{"377160", "489830"}) {
+		t.Errorf("unexpected apps for library 1: %v", libraries[1].Apps)
+	}
+
+	apps := MapAppsToLibraries(libraries)
+	if apps["377160"].Path != "/mnt/ssd2/SteamLibrary" {
+		t.Errorf("expected Fallout 4 in /mnt/ssd2/SteamLibrary, got %q", apps["377160"].Path)
+	}
+	if apps["1151340"].Path != "/home/user/.local/share/Steam" {
+		t.Errorf("expected Fallout 76 in the Steam root, got %q", apps["1151340"].Path)
+	}
+
+	ordered := OrderLibrariesForApp(libraries, "489830")
+	if ordered[0].Path != "/mnt/ssd2/SteamLibrary" {
+		t.Errorf("expected library containing the app first, got %q", ordered[0].Path)
+	}
+
+	// Legacy format with plain numbered paths
+	legacy, err := ParseVDF(strings.NewReader(`"LibraryFolders" { "TimeNextStatsReport" "1" "1" "/mnt/games" }`))
+	if err != nil {
+		t.Fatalf("ParseVDF failed on legacy format: %v", err)
+	}
+	libraries = ParseLibraryFolders(legacy)
+	if len(libraries) != 1 || libraries[0].Path != "/mnt/games" {
+		t.Errorf("unexpected legacy libraries: %+v", libraries)
+	}
+}
+
+const testConfigVDF = `"InstallConfigStore"
+{
+	"Software"
+	{
+		"Valve"
+		{
+			"Steam"
+			{
+				"SentryFile"		"C:\\Program Files\\Steam\\ssfn"
+				"Motd"		"line one\nline two\tindented"
+				"Overlay"		"1"	[$WIN32]
+				"Linux" [$LINUX]
+				{
+					"Scale"		"2"
+				}
+				"CompatToolMapping"
+				{
+					"377160"
+					{
+						"name"		"proton_8"
+					}
+				}
+			}
+		}
+	}
+}
+`
+
+func TestWriteVDFRoundTrip(t *testing.T) {
+	root, err := ParseVDF(strings.NewReader(testConfigVDF))
+	if err != nil {
+		t.Fatalf("ParseVDF failed: %v", err)
+	}
+	steamSection := root.Lookup("InstallConfigStore", "Software", "Valve", "Steam")
+	if got := steamSection.Get("SentryFile"); got != `C:\Program Files\Steam\ssfn` {
+		t.Errorf("expected unescaped backslashes, got %q", got)
+	}
+	if got := steamSection.Get("Motd"); got != "line one\nline two\tindented" {
+		t.Errorf("expected unescaped newline and tab, got %q", got)
+	}
+	if got := steamSection.Child("Overlay"); got == nil || got.Value != "1" || got.Condition != "[$WIN32]" {
+		t.Errorf("expected the conditional of Overlay to be kept, got %+v", got)
+	}
+	if got := steamSection.Child("Linux"); got == nil || got.Condition != "[$LINUX]" || got.Get("Scale") != "2" {
+		t.Errorf("expected the conditional of the Linux section to be kept, got %+v", got)
+	}
+
+	var first bytes.Buffer
+	if err := WriteVDF(&first, root); err != nil {
+		t.Fatalf("WriteVDF failed: %v", err)
+	}
+	reparsed, err := ParseVDF(bytes.NewReader(first.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to parse the written document: %v\n%s", err, first.String())
+	}
+	if !reflect.DeepEqual(reparsed, root) {
+		t.Errorf("expected the document to survive a round trip, got\n%s", first.String())
+	}
+	var second bytes.Buffer
+	if err := WriteVDF(&second, reparsed); err != nil {
+		t.Fatalf("WriteVDF failed: %v", err)
+	}
+	if first.String() != second.String() {
+		t.Errorf("expected writing to be stable, got\n%s\nthen\n%s", first.String(), second.String())
+	}
+}
+
+func TestNodeSet(t *testing.T) {
+	root, err := ParseVDF(strings.NewReader(testConfigVDF))
+	if err != nil {
+		t.Fatalf("ParseVDF failed: %v", err)
+	}
+	steamSection := root.Lookup("InstallConfigStore", "Software", "Valve", "Steam")
+	count := len(steamSection.Children)
+
+	steamSection.Set("overlay", "0", TypeString)
+	steamSection.Set("CompatToolMapping", "", TypeString)
+	steamSection.Set("NewKey", "value", TypeString)
+	if len(steamSection.Children) != count+1 {
+		t.Fatalf("expected existing keys to be replaced, got %d children", len(steamSection.Children))
+	}
+	if got := steamSection.Child("CompatToolMapping"); got.IsSection() || got.Value != "" {
+		t.Errorf("expected the section to be replaced by a value, got %+v", got)
+	}
+	if got := steamSection.Get("Overlay"); got != "0" {
+		t.Errorf("expected Overlay to be 0, got %q", got)
+	}
+}
+
+func TestSetCompatToolRoundTrip(t *testing.T) {
+	steamRoot, err := os.MkdirTemp("", "test-steam-root")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(steamRoot)
+
+	path := GetSteamConfigPath(steamRoot)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(testConfigVDF), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	if err := SetCompatTool(steamRoot, "377160", "proton_experimental"); err != nil {
+		t.Fatalf("SetCompatTool failed: %v", err)
+	}
+	root, err := ParseVDFFile(path)
+	if err != nil {
+		t.Fatalf("failed to parse the written config: %v", err)
+	}
+	steamSection := root.Lookup("InstallConfigStore", "Software", "Valve", "Steam")
+	mapping := steamSection.Lookup("CompatToolMapping", "377160")
+	if len(mapping.Children) != 3 || mapping.Get("name") != "proton_experimental" || mapping.Get("priority") != "250" {
+		t.Errorf("unexpected mapping %+v", mapping.Children)
+	}
+	if got := steamSection.Get("Motd"); got != "line one\nline two\tindented" {
+		t.Errorf("expected the other keys to be kept, got %q", got)
+	}
+	if got := steamSection.Child("Overlay"); got == nil || got.Condition != "[$WIN32]" {
+		t.Errorf("expected the conditionals to be kept, got %+v", got)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil || len(entries) != 2 {
+		t.Errorf("expected only the config and its backup, got %v, %v", entries, err)
+	}
+}
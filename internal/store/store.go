@@ -0,0 +1,232 @@
+// Package store persists the JSON files of Fusion Core (config.json and the
+// mod lists) so that a crash or a full disk never leaves a truncated file.
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// KeepBackups is the number of backups kept of each file.
+const KeepBackups = 10
+
+// LockTimeout is how long Lock waits for another process to release a lock.
+var LockTimeout = 10 * time.Second
+
+// Lock takes an exclusive advisory lock on the file at path, so the CLI and
+// the GUI don't overwrite each other's changes. The lock is held on a
+// separate path.lock file, because saving replaces the file itself. Call
+// the returned function to release it.
+func Lock(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	file, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(LockTimeout)
+	for {
+		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if err != syscall.EWOULDBLOCK && err != syscall.EINTR {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", filepath.Base(path), err)
+		}
+		if time.Now().After(deadline) {
+			file.Close()
+			return nil, fmt.Errorf("%s is locked by another Fusion Core process", filepath.Base(path))
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	return func() {
+		syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+	}, nil
+}
+
+// WriteFile replaces the file at path with data. The data is written and
+// synced to a temporary file in the same directory, which is then renamed
+// over path, so readers see either the old or the new file, never a partial one.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", filepath.Base(path), err)
+	}
+
+	// Sync the directory so the rename itself survives a crash. Not every
+	// file system supports this, so failures are ignored.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// BackupDir returns the directory backups of the file at path are kept in.
+func BackupDir(path string) string {
+	return filepath.Join(filepath.Dir(path), "backups")
+}
+
+// Backup copies the file at path to its backup directory, named after the
+// current time, and removes all but the newest keep backups of it. Nothing
+// is copied if the file doesn't exist or matches the newest backup.
+func Backup(path string, keep int) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+
+	backups, err := ListBackups(path)
+	if err != nil {
+		return err
+	}
+	if len(backups) > 0 {
+		if latest, err := os.ReadFile(backups[len(backups)-1]); err == nil && bytes.Equal(latest, data) {
+			return nil
+		}
+	}
+
+	name := filepath.Base(path) + "." + time.Now().UTC().Format("20060102-150405.000000000")
+	if err := WriteFile(filepath.Join(BackupDir(path), name), data, 0600); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", filepath.Base(path), err)
+	}
+
+	backups, err = ListBackups(path)
+	if err != nil {
+		return err
+	}
+	for len(backups) > keep {
+		if err := os.Remove(backups[0]); err != nil {
+			return fmt.Errorf("failed to remove old backup: %w", err)
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// ListBackups returns the backups of the file at path, oldest first.
+func ListBackups(path string) ([]string, error) {
+	entries, err := os.ReadDir(BackupDir(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backups: %w", err)
+	}
+
+	prefix := filepath.Base(path) + "."
+	var backups []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), prefix) && !strings.HasSuffix(entry.Name(), ".tmp") {
+			backups = append(backups, filepath.Join(BackupDir(path), entry.Name()))
+		}
+	}
+	// The timestamps sort in the order the backups were made
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// SaveJSON backs up the file at path and replaces it with v as indented
+// JSON. Nothing is written if the file already holds the same data.
+func SaveJSON(path string, v interface{}, perm os.FileMode) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+	data = append(data, '\n')
+	if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, data) {
+		return nil
+	}
+	if err := Backup(path, KeepBackups); err != nil {
+		return err
+	}
+	return WriteFile(path, data, perm)
+}
+
+// Migration upgrades a JSON document by one schema version.
+type Migration func(data []byte) ([]byte, error)
+
+// Migrate upgrades a JSON document to the version after the last migration.
+// migrations[i] upgrades a document of version i, documents without a
+// "version" field are version 0. The upgraded document gets its version set.
+// Documents newer than the migrations know are rejected, so files written by
+// a newer release aren't silently overwritten with missing data.
+func Migrate(data []byte, migrations []Migration) ([]byte, error) {
+	version, err := documentVersion(data)
+	if err != nil {
+		return nil, err
+	}
+	if version > len(migrations) {
+		return nil, fmt.Errorf("file version %d is newer than the supported version %d, please update Fusion Core", version, len(migrations))
+	}
+	if version == len(migrations) {
+		return data, nil
+	}
+
+	for ; version < len(migrations); version++ {
+		if data, err = migrations[version](data); err != nil {
+			return nil, fmt.Errorf("failed to migrate from version %d: %w", version, err)
+		}
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("migrated document is not an object: %w", err)
+	}
+	doc["version"] = json.RawMessage(fmt.Sprint(version))
+	return json.Marshal(doc)
+}
+
+// documentVersion returns the "version" field of a document, 0 if it has none.
+func documentVersion(data []byte) (int, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' {
+		return 0, nil
+	}
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return 0, err
+	}
+	return header.Version, nil
+}
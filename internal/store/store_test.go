@@ -0,0 +1,134 @@
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveJSONBackups(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-store")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "mods.json")
+	for i := 0; i < KeepBackups+3; i++ {
+		if err := SaveJSON(path, map[string]int{"save": i}, 0600); err != nil {
+			t.Fatalf("Failed to save: %v", err)
+		}
+	}
+	// Saving the same content again doesn't touch the file or add a backup
+	if err := SaveJSON(path, map[string]int{"save": KeepBackups + 2}, 0600); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	var saved map[string]int
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if err := json.Unmarshal(data, &saved); err != nil || saved["save"] != KeepBackups+2 {
+		t.Errorf("unexpected content %s", data)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %v", info.Mode())
+	}
+
+	backups, err := ListBackups(path)
+	if err != nil {
+		t.Fatalf("Failed to list backups: %v", err)
+	}
+	if len(backups) != KeepBackups {
+		t.Fatalf("expected %d backups, got %d", KeepBackups, len(backups))
+	}
+	// The newest backup is the save before the last one
+	data, err = os.ReadFile(backups[len(backups)-1])
+	if err != nil {
+		t.Fatalf("Failed to read backup: %v", err)
+	}
+	if err := json.Unmarshal(data, &saved); err != nil || saved["save"] != KeepBackups+1 {
+		t.Errorf("unexpected newest backup %s", data)
+	}
+
+	// No temporary files are left behind
+	entries, _ := os.ReadDir(tmpDir)
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			t.Errorf("temporary file %s left behind", entry.Name())
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	migrations := []Migration{
+		func(data []byte) ([]byte, error) {
+			return json.Marshal(map[string]json.RawMessage{"items": data})
+		},
+		func(data []byte) ([]byte, error) {
+			var doc map[string]interface{}
+			if err := json.Unmarshal(data, &doc); err != nil {
+				return nil, err
+			}
+			doc["renamed"] = doc["items"]
+			delete(doc, "items")
+			return json.Marshal(doc)
+		},
+	}
+
+	data, err := Migrate([]byte(`["a", "b"]`), migrations)
+	if err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	var doc struct {
+		Version int
+		Renamed []string
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Failed to decode migrated document: %v", err)
+	}
+	if doc.Version != 2 || len(doc.Renamed) != 2 {
+		t.Errorf("unexpected migrated document %s", data)
+	}
+
+	current := []byte(`{"version": 2, "renamed": []}`)
+	if data, err := Migrate(current, migrations); err != nil || string(data) != string(current) {
+		t.Errorf("expected a current document to be unchanged, got %s, %v", data, err)
+	}
+
+	if _, err := Migrate([]byte(`{"version": 3}`), migrations); err == nil {
+		t.Errorf("expected an error for a newer document")
+	}
+}
+
+func TestLock(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-store-lock")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	oldTimeout := LockTimeout
+	LockTimeout = 100 * time.Millisecond
+	defer func() { LockTimeout = oldTimeout }()
+
+	path := filepath.Join(tmpDir, "config.json")
+	unlock, err := Lock(path)
+	if err != nil {
+		t.Fatalf("Failed to lock: %v", err)
+	}
+	if _, err := Lock(path); err == nil {
+		t.Errorf("expected a second lock to time out")
+	}
+
+	unlock()
+	unlock, err = Lock(path)
+	if err != nil {
+		t.Fatalf("Failed to lock after unlocking: %v", err)
+	}
+	unlock()
+}
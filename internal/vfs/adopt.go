@@ -0,0 +1,183 @@
+package vfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bazsalanszky/fusioncore/internal/games"
+	"github.com/bazsalanszky/fusioncore/internal/mod"
+)
+
+// LooseFilesMod is the name of the mod unmanaged loose files are adopted
+// into, as there is no telling which mod they came from.
+const LooseFilesMod = "Unmanaged loose files"
+
+// Unmanaged is a group of files in the data directory that neither ship
+// with the game nor are deployed by Fusion Core, proposed as a new mod.
+type Unmanaged struct {
+	Name  string
+	Files []string // Relative to the data directory, with forward slashes
+}
+
+// ScanUnmanaged finds the files in a game's data directory that were copied
+// there by hand. Symlinks are skipped, they are deployed by SyncLinks or
+// belong to another tool. Plugins are grouped with the archives named after
+// them, other archives by their name up to " - ", and the remaining files
+// are collected in LooseFilesMod. Games that only get archives deployed
+// can't have other files adopted, those are returned as kept instead.
+func ScanUnmanaged(game *games.Game, dataDir string) (found []Unmanaged, kept []string, err error) {
+	if len(game.VanillaFiles) == 0 {
+		return nil, nil, fmt.Errorf("%s has no vanilla_files in its definition, its own files can't be told apart from unmanaged ones", game.Name)
+	}
+
+	var plugins, archives, loose []string
+	err = filepath.WalkDir(dataDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dataDir || d.Type()&os.ModeSymlink != 0 {
+			return nil
+		}
+		rel, err := filepath.Rel(dataDir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if game.IsVanillaFile(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		switch {
+		case strings.Contains(rel, "/"):
+			loose = append(loose, rel)
+		case games.IsPlugin(rel):
+			plugins = append(plugins, rel)
+		case game.IsArchive(rel):
+			archives = append(archives, rel)
+		default:
+			loose = append(loose, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to scan %s: %w", dataDir, err)
+	}
+	if game.DeployMode == games.DeployModeArchives {
+		kept = append(append(kept, plugins...), loose...)
+		sort.Strings(kept)
+		plugins, loose = nil, nil
+	}
+
+	groups := make(map[string]*Unmanaged)
+	var order []string
+	addFile := func(name, file string) {
+		key := strings.ToLower(name)
+		group, ok := groups[key]
+		if !ok {
+			group = &Unmanaged{Name: name}
+			groups[key] = group
+			order = append(order, key)
+		}
+		group.Files = append(group.Files, file)
+	}
+
+	sort.Strings(plugins)
+	for _, plugin := range plugins {
+		addFile(strings.TrimSuffix(plugin, filepath.Ext(plugin)), plugin)
+	}
+	sort.Strings(archives)
+	for _, archive := range archives {
+		base := strings.TrimSuffix(archive, filepath.Ext(archive))
+		name := base
+		if before, _, ok := strings.Cut(base, " - "); ok {
+			name = before
+		}
+		// Archives like "MyMod - Textures.ba2" belong to MyMod.esp
+		for _, plugin := range plugins {
+			pluginBase := strings.TrimSuffix(plugin, filepath.Ext(plugin))
+			if strings.EqualFold(base, pluginBase) || strings.HasPrefix(strings.ToLower(base), strings.ToLower(pluginBase)+" - ") {
+				name = pluginBase
+				break
+			}
+		}
+		addFile(name, archive)
+	}
+	sort.Strings(loose)
+	for _, file := range loose {
+		addFile(LooseFilesMod, file)
+	}
+
+	found = make([]Unmanaged, 0, len(order))
+	for _, key := range order {
+		found = append(found, *groups[key])
+	}
+	return found, kept, nil
+}
+
+// AdoptUnmanaged moves the files of each group out of the data directory
+// into a new mod in the game's mods directory and adds the mods to the mod
+// list as active. If a file can't be moved, the files of that group are
+// moved back and the groups adopted so far are kept. Only archives are
+// adopted for games that only get archives deployed.
+func AdoptUnmanaged(game *games.Game, dataDir string, found []Unmanaged) ([]*mod.Mod, error) {
+	modsDir, err := game.GetModsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var adopted []*mod.Mod
+	for _, group := range found {
+		modDir := mod.UniqueDir(filepath.Join(modsDir, group.Name))
+		var moved []string
+		for _, file := range group.Files {
+			if game.DeployMode == games.DeployModeArchives && !game.IsArchive(file) {
+				fmt.Printf("Leaving %s in place, %s only gets archives deployed\n", file, game.Name)
+				continue
+			}
+			src := filepath.Join(dataDir, filepath.FromSlash(file))
+			dst := filepath.Join(modDir, filepath.FromSlash(file))
+			if err := mod.MoveFile(src, dst); err != nil {
+				for _, file := range moved {
+					if err := mod.MoveFile(filepath.Join(modDir, file), filepath.Join(dataDir, file)); err != nil {
+						fmt.Printf("Failed to move %s back: %v\n", file, err)
+					}
+				}
+				os.RemoveAll(modDir)
+				return adopted, fmt.Errorf("failed to move %s: %w", file, err)
+			}
+			moved = append(moved, filepath.FromSlash(file))
+			removeEmptyDirs(filepath.Dir(src), dataDir)
+		}
+
+		if len(moved) == 0 {
+			continue
+		}
+
+		m := mod.New(group.Name, modDir, "local", "local", game.ID)
+		m.Active = true
+		if _, err := mod.Add(game.ID, m); err != nil {
+			return adopted, fmt.Errorf("failed to add %s to the mod list, its files are in %s: %w", m.Name, modDir, err)
+		}
+		fmt.Printf("Adopted %d files as %s\n", len(moved), m.Name)
+		adopted = append(adopted, m)
+	}
+	return adopted, nil
+}
+
+// removeEmptyDirs removes dir and its parents below root while they are empty.
+func removeEmptyDirs(dir, root string) {
+	for dir != root && strings.HasPrefix(dir, root) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
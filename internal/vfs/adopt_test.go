@@ -0,0 +1,155 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bazsalanszky/fusioncore/internal/games"
+	"github.com/bazsalanszky/fusioncore/internal/mod"
+)
+
+func TestAdoptUnmanaged(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-adopt")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	t.Setenv("HOME", tmpDir)
+
+	game, err := games.GetGameByID("fallout4")
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+
+	dataDir := filepath.Join(tmpDir, "Data")
+	files := []string{
+		// Vanilla
+		"Fallout4.esm",
+		"Fallout4 - Textures1.ba2",
+		"DLCCoast.esm",
+		"ccBGSFO4001-PipBoy(Black).esl",
+		"Video/Intro.bk2",
+		// Copied by hand
+		"ArmorKeywords.esm",
+		"ArmorKeywords - Main.ba2",
+		"Sim Settlements - Textures.ba2",
+		"Sim Settlements - Main.ba2",
+		"meshes/armor/body.nif",
+		"F4SE/Plugins/mod.dll",
+	}
+	for _, name := range files {
+		path := filepath.Join(dataDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	// Deployed by SyncLinks
+	if err := os.Symlink(filepath.Join(tmpDir, "Managed.ba2"), filepath.Join(dataDir, "Managed.ba2")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	found, kept, err := ScanUnmanaged(game, dataDir)
+	if err != nil {
+		t.Fatalf("Failed to scan: %v", err)
+	}
+	if len(kept) != 0 {
+		t.Errorf("expected every file to be adopted, got %v kept", kept)
+	}
+	expected := []Unmanaged{
+		{Name: "ArmorKeywords", Files: []string{"ArmorKeywords.esm", "ArmorKeywords - Main.ba2"}},
+		{Name: "Sim Settlements", Files: []string{"Sim Settlements - Main.ba2", "Sim Settlements - Textures.ba2"}},
+		{Name: LooseFilesMod, Files: []string{"F4SE/Plugins/mod.dll", "meshes/armor/body.nif"}},
+	}
+	if !reflect.DeepEqual(found, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, found)
+	}
+
+	adopted, err := AdoptUnmanaged(game, dataDir, found)
+	if err != nil {
+		t.Fatalf("Failed to adopt: %v", err)
+	}
+	if len(adopted) != 3 {
+		t.Fatalf("expected 3 mods, got %d", len(adopted))
+	}
+	if _, err := os.Stat(filepath.Join(adopted[2].Path, "meshes", "armor", "body.nif")); err != nil {
+		t.Errorf("expected the loose file in the mod folder: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "meshes")); !os.IsNotExist(err) {
+		t.Errorf("expected the emptied meshes folder to be removed")
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "Fallout4.esm")); err != nil {
+		t.Errorf("expected vanilla files to stay: %v", err)
+	}
+
+	mods, err := mod.LoadMods("fallout4")
+	if err != nil {
+		t.Fatalf("Failed to load mods: %v", err)
+	}
+	if len(mods) != 3 || mods[0].Name != "ArmorKeywords" || !mods[0].Active {
+		t.Errorf("unexpected mod list %+v", mods)
+	}
+
+	// Nothing is left to adopt
+	if found, _, err := ScanUnmanaged(game, dataDir); err != nil || len(found) != 0 {
+		t.Errorf("expected nothing left, got %+v, %v", found, err)
+	}
+}
+
+func TestAdoptUnmanagedArchivesOnly(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-adopt")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	t.Setenv("HOME", tmpDir)
+
+	game, err := games.GetGameByID("fallout76")
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+
+	dataDir := filepath.Join(tmpDir, "Data")
+	writeTestFiles(t, dataDir, map[string]string{
+		"SeventySix - Textures01.ba2": "vanilla",
+		"BetterInventory.ba2":         "mod",
+		"Mod.esp":                     "mod",
+		"textures/loose.dds":          "mod",
+	})
+
+	found, kept, err := ScanUnmanaged(game, dataDir)
+	if err != nil {
+		t.Fatalf("Failed to scan: %v", err)
+	}
+	expected := []Unmanaged{{Name: "BetterInventory", Files: []string{"BetterInventory.ba2"}}}
+	if !reflect.DeepEqual(found, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, found)
+	}
+	if expectedKept := []string{"Mod.esp", "textures/loose.dds"}; !reflect.DeepEqual(kept, expectedKept) {
+		t.Errorf("expected %v to be kept, got %v", expectedKept, kept)
+	}
+
+	// Loose files passed in anyway stay where they are
+	found[0].Files = append(found[0].Files, "textures/loose.dds")
+	adopted, err := AdoptUnmanaged(game, dataDir, found)
+	if err != nil {
+		t.Fatalf("Failed to adopt: %v", err)
+	}
+	if len(adopted) != 1 {
+		t.Fatalf("expected 1 mod, got %d", len(adopted))
+	}
+	if _, err := os.Stat(filepath.Join(adopted[0].Path, "BetterInventory.ba2")); err != nil {
+		t.Errorf("expected the archive in the mod folder: %v", err)
+	}
+	for _, name := range []string{"Mod.esp", "textures/loose.dds"} {
+		if _, err := os.Stat(filepath.Join(dataDir, filepath.FromSlash(name))); err != nil {
+			t.Errorf("expected %s to stay in Data: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package vfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bazsalanszky/fusioncore/internal/games"
+	"github.com/bazsalanszky/fusioncore/internal/mod"
+)
+
+// Conflict is a file of the data directory that more than one active mod
+// provides, or that an active mod provides while the file is already in the
+// data directory. The last of the mods in the mod order wins, files already
+// in the data directory always lose and are backed up while it is deployed.
+type Conflict struct {
+	Path     string // Relative to the data directory
+	Winner   *mod.Mod
+	Losers   []*mod.Mod // In mod order
+	DataFile bool       // The file is in the data directory without a mod
+}
+
+// FindConflicts returns the files that several active mods provide, matched
+// ignoring case like on Windows, sorted by path. Files of dataDir that mods
+// replace are conflicts too, dataDir may be empty to leave them out.
+func FindConflicts(mods []*mod.Mod, game *games.Game, dataDir string) ([]Conflict, error) {
+	dataFiles := make(map[string]bool)
+	if dataDir != "" {
+		var err error
+		if dataFiles, err = unmanagedFiles(dataDir); err != nil {
+			return nil, err
+		}
+	}
+
+	providers := make(map[string][]*mod.Mod)
+	paths := make(map[string]string) // As the first mod has it, like the deployed link
+	for _, m := range mods {
+		if !m.Active {
+			continue
+		}
+		files, err := filesToDeploy(m, game)
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool) // Mods may have the same file in several cases
+		for _, f := range files {
+			key := strings.ToLower(f.rel)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if _, ok := paths[key]; !ok {
+				paths[key] = f.rel
+			}
+			providers[key] = append(providers[key], m)
+		}
+	}
+
+	var conflicts []Conflict
+	for key, mods := range providers {
+		if len(mods) < 2 && !dataFiles[key] {
+			continue
+		}
+		conflicts = append(conflicts, Conflict{Path: paths[key], Winner: mods[len(mods)-1], Losers: mods[:len(mods)-1], DataFile: dataFiles[key]})
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return strings.ToLower(conflicts[i].Path) < strings.ToLower(conflicts[j].Path)
+	})
+	return conflicts, nil
+}
+
+// Involves reports whether a mod provides the file of the conflict.
+func (c *Conflict) Involves(m *mod.Mod) bool {
+	if c.Winner.ID == m.ID {
+		return true
+	}
+	for _, loser := range c.Losers {
+		if loser.ID == m.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// LoserNames returns the names of the mods that lose the file, and the
+// file already in the data directory if there is one.
+func (c *Conflict) LoserNames() []string {
+	var names []string
+	if c.DataFile {
+		names = append(names, "the file in Data")
+	}
+	for _, loser := range c.Losers {
+		names = append(names, loser.Name)
+	}
+	return names
+}
+
+// unmanagedFiles returns the lowercase paths of the files in a data
+// directory that aren't deployed links, including those backed up while
+// mods replace them.
+func unmanagedFiles(dataDir string) (map[string]bool, error) {
+	files := make(map[string]bool)
+	for _, root := range []string{dataDir, backupDir(dataDir)} {
+		err := filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) && path == root {
+					return nil
+				}
+				return err
+			}
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			files[strings.ToLower(rel)] = true
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", root, err)
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,87 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazsalanszky/fusioncore/internal/games"
+	"github.com/bazsalanszky/fusioncore/internal/mod"
+)
+
+func TestFindConflicts(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-conflicts")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	game, err := games.GetGameByID("fallout4")
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"A/Textures/Armor.dds":        "a",
+		"A/meshes/armor.nif":          "a",
+		"B/Data/textures/ARMOR.dds":   "b",
+		"B/Data/meshes/armor.nif":     "b",
+		"B/Data/B.esp":                "b",
+		"C/meshes/armor.nif":          "c",
+		"Disabled/textures/armor.dds": "disabled",
+	})
+	mods := []*mod.Mod{
+		{ID: "a", Name: "A", Path: filepath.Join(tmpDir, "A"), Active: true},
+		{ID: "b", Name: "B", Path: filepath.Join(tmpDir, "B"), Active: true},
+		{ID: "c", Name: "C", Path: filepath.Join(tmpDir, "C"), Active: true},
+		{ID: "d", Name: "Disabled", Path: filepath.Join(tmpDir, "Disabled"), Active: false},
+	}
+
+	conflicts, err := FindConflicts(mods, game, "")
+	if err != nil {
+		t.Fatalf("FindConflicts failed: %v", err)
+	}
+	if len(conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts, got %+v", conflicts)
+	}
+
+	meshes := conflicts[0]
+	if meshes.Path != filepath.Join("meshes", "armor.nif") || meshes.Winner != mods[2] || len(meshes.Losers) != 2 ||
+		meshes.Losers[0] != mods[0] || meshes.Losers[1] != mods[1] {
+		t.Errorf("unexpected conflict %+v", meshes)
+	}
+	// Matched ignoring case, named like the first mod has it
+	textures := conflicts[1]
+	if textures.Path != filepath.Join("Textures", "Armor.dds") || textures.Winner != mods[1] || len(textures.Losers) != 1 {
+		t.Errorf("unexpected conflict %+v", textures)
+	}
+	if textures.Involves(mods[2]) || !textures.Involves(mods[0]) || textures.Involves(mods[3]) {
+		t.Errorf("unexpected mods involved in %+v", textures)
+	}
+
+	// Files already in Data lose against every mod, also while backed up
+	dataDir := filepath.Join(tmpDir, "Data")
+	writeTestFiles(t, dataDir, map[string]string{
+		"B.esp":              "unmanaged",
+		"Textures/other.dds": "unmanaged",
+	})
+	writeTestFiles(t, backupDir(dataDir), map[string]string{
+		"Meshes/Armor.nif": "vanilla",
+	})
+	conflicts, err = FindConflicts(mods, game, dataDir)
+	if err != nil {
+		t.Fatalf("FindConflicts failed: %v", err)
+	}
+	if len(conflicts) != 3 {
+		t.Fatalf("expected 3 conflicts, got %+v", conflicts)
+	}
+	if plugin := conflicts[0]; plugin.Path != "B.esp" || plugin.Winner != mods[1] || len(plugin.Losers) != 0 || !plugin.DataFile {
+		t.Errorf("unexpected conflict %+v", plugin)
+	}
+	if !conflicts[1].DataFile || conflicts[2].DataFile {
+		t.Errorf("expected only the backed up mesh to be in Data, got %+v", conflicts)
+	}
+	if names := conflicts[1].LoserNames(); len(names) != 3 || names[0] != "the file in Data" {
+		t.Errorf("unexpected losers %v", names)
+	}
+}
@@ -0,0 +1,353 @@
+package vfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bazsalanszky/fusioncore/internal/games"
+	"github.com/bazsalanszky/fusioncore/internal/mod"
+	"github.com/bazsalanszky/fusioncore/internal/store"
+)
+
+// deployer links mod files into a data directory. Folders and files are
+// matched case insensitively like on Windows, so "Textures" of the game and
+// "textures" of a mod end up as one folder. Files of the game or copied by
+// hand that a mod replaces are moved to a backup folder, and the folders the
+// deployer creates are recorded, so removeSymlinks can undo both.
+type deployer struct {
+	dataDir   string
+	names     map[string]map[string]string // Directory to the lowercase names in it and their real names
+	deployed  map[string]bool              // Links created by this deployment
+	created   []string                     // Folders created by this deployment, relative to dataDir
+	replaced  int                          // Links of earlier mods replaced by later ones
+	backedUp  int                          // Files moved to the backup folder
+	looseOnly bool                         // Skip plugins and archives, for the Data folder in My Games
+}
+
+func newDeployer(dataDir string) *deployer {
+	return &deployer{dataDir: dataDir, names: make(map[string]map[string]string), deployed: make(map[string]bool)}
+}
+
+// deployStateDir returns the folder the backups and the created folders of
+// a deployment to dataDir are kept in. It is next to the data directory, so
+// the game doesn't load it and files can be moved there without copying.
+func deployStateDir(dataDir string) string {
+	return filepath.Join(filepath.Dir(dataDir), ".fusion-core", filepath.Base(dataDir))
+}
+
+func backupDir(dataDir string) string {
+	return filepath.Join(deployStateDir(dataDir), "backup")
+}
+
+func createdDirsFile(dataDir string) string {
+	return filepath.Join(deployStateDir(dataDir), "created-dirs.json")
+}
+
+// entries returns the names in a directory, keyed by their lowercase form.
+func (d *deployer) entries(dir string) (map[string]string, error) {
+	if names, ok := d.names[dir]; ok {
+		return names, nil
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(files))
+	for _, file := range files {
+		names[strings.ToLower(file.Name())] = file.Name()
+	}
+	d.names[dir] = names
+	return names, nil
+}
+
+// link links target to rel in the data directory, creating the folders it
+// goes in. Links of earlier mods are replaced, other files are moved to the
+// backup folder.
+func (d *deployer) link(target, rel string) error {
+	dir := d.dataDir
+	parts := strings.Split(rel, string(filepath.Separator))
+	for _, part := range parts[:len(parts)-1] {
+		names, err := d.entries(dir)
+		if err != nil {
+			return err
+		}
+		if name, ok := names[strings.ToLower(part)]; ok {
+			dir = filepath.Join(dir, name)
+			continue
+		}
+		if err := os.Mkdir(filepath.Join(dir, part), 0755); err != nil {
+			return err
+		}
+		names[strings.ToLower(part)] = part
+		dir = filepath.Join(dir, part)
+		d.names[dir] = make(map[string]string)
+		created, err := filepath.Rel(d.dataDir, dir)
+		if err != nil {
+			return err
+		}
+		d.created = append(d.created, created)
+	}
+
+	names, err := d.entries(dir)
+	if err != nil {
+		return err
+	}
+	base := parts[len(parts)-1]
+	if name, ok := names[strings.ToLower(base)]; ok {
+		existing := filepath.Join(dir, name)
+		if d.deployed[existing] {
+			if err := os.Remove(existing); err != nil {
+				return err
+			}
+			d.replaced++
+		} else {
+			info, err := os.Lstat(existing)
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				fmt.Printf("Keeping the folder %s, a mod has a file with its name\n", existing)
+				return nil
+			}
+			if err := d.backup(existing); err != nil {
+				return err
+			}
+		}
+		// The link keeps the name of the file it replaces
+		base = name
+	}
+	path := filepath.Join(dir, base)
+	if err := os.Symlink(target, path); err != nil {
+		return fmt.Errorf("failed to create symlink for %s: %w", rel, err)
+	}
+	names[strings.ToLower(base)] = base
+	d.deployed[path] = true
+	return nil
+}
+
+// backup moves a file of the data directory that a mod replaces to the
+// backup folder, from where removeSymlinks puts it back.
+func (d *deployer) backup(path string) error {
+	rel, err := filepath.Rel(d.dataDir, path)
+	if err != nil {
+		return err
+	}
+	if err := mod.MoveFile(path, filepath.Join(backupDir(d.dataDir), rel)); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	d.backedUp++
+	return nil
+}
+
+// save records the folders created by the deployment for removeSymlinks.
+func (d *deployer) save() error {
+	if len(d.created) == 0 {
+		return nil
+	}
+	data, err := json.MarshalIndent(d.created, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := store.WriteFile(createdDirsFile(d.dataDir), data, 0644); err != nil {
+		return fmt.Errorf("failed to record the folders created in %s: %w", d.dataDir, err)
+	}
+	return nil
+}
+
+// deployFile is a file of a mod and where it goes in the data directory.
+type deployFile struct {
+	rel    string // Relative to the data directory
+	target string
+}
+
+// filesToDeploy returns the files of a mod to link into the data directory,
+// the whole Data tree or only its archives depending on the game.
+func filesToDeploy(m *mod.Mod, game *games.Game) ([]deployFile, error) {
+	var files []deployFile
+	if game.DeployMode == games.DeployModeArchives {
+		archives, err := findArchiveFiles(m.Path, game)
+		if err != nil {
+			return nil, err
+		}
+		// The game only loads archives from the root of its data directory
+		for _, archive := range archives {
+			files = append(files, deployFile{rel: filepath.Base(archive), target: filepath.Join(m.Path, archive)})
+		}
+		return files, nil
+	}
+
+	rels, err := modFiles(m.Path)
+	if err != nil {
+		return nil, err
+	}
+	root := modDataRoot(m.Path)
+	for _, rel := range rels {
+		files = append(files, deployFile{rel: rel, target: filepath.Join(root, rel)})
+	}
+	return files, nil
+}
+
+// deployMod links the files of a mod into the data directory.
+func (d *deployer) deployMod(m *mod.Mod, game *games.Game) (int, error) {
+	files, err := filesToDeploy(m, game)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, f := range files {
+		if d.looseOnly && (games.IsPlugin(f.rel) || game.IsArchive(f.rel)) {
+			continue
+		}
+		if err := d.link(f.target, f.rel); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// modDataRoot returns the folder of a mod that maps to the data directory:
+// its "Data" folder if the mod is packaged with one, the mod itself
+// otherwise.
+func modDataRoot(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return dir
+	}
+	root := dir
+	for _, entry := range entries {
+		switch {
+		case entry.IsDir() && strings.EqualFold(entry.Name(), "data"):
+			root = filepath.Join(dir, entry.Name())
+		case games.IsPlugin(entry.Name()):
+			return dir // Plugins next to the folder, it is a folder of the mod
+		}
+	}
+	return root
+}
+
+// modFiles returns the files of a mod relative to its data root. The folder
+// of FOMOD installers isn't deployed.
+func modFiles(dir string) ([]string, error) {
+	root := modDataRoot(dir)
+	var files []string
+	err := filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if strings.EqualFold(rel, "fomod") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		files = append(files, rel)
+		return nil
+	})
+	return files, err
+}
+
+// removeSymlinks removes the links into modsDir deployed to a data
+// directory, puts the files they replaced back and removes the folders the
+// deployment created once they are empty. Folders that were there before
+// are kept even if they are empty.
+func removeSymlinks(dataDir, modsDir string) error {
+	var links []string
+	err := filepath.WalkDir(dataDir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type()&os.ModeSymlink == 0 {
+			return nil
+		}
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		if rel, err := filepath.Rel(modsDir, target); err == nil && !strings.HasPrefix(rel, "..") {
+			links = append(links, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read data directory: %w", err)
+	}
+	for _, link := range links {
+		if err := os.Remove(link); err != nil {
+			return fmt.Errorf("failed to remove existing symlink at %s: %w", link, err)
+		}
+	}
+
+	if err := restoreBackups(dataDir); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(createdDirsFile(dataDir))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		var created []string
+		if err := json.Unmarshal(data, &created); err != nil {
+			return fmt.Errorf("failed to read the folders created in %s: %w", dataDir, err)
+		}
+		// Deepest first, removing fails for folders that still hold other files
+		sort.Sort(sort.Reverse(sort.StringSlice(created)))
+		for _, dir := range created {
+			os.Remove(filepath.Join(dataDir, dir))
+		}
+		if err := os.Remove(createdDirsFile(dataDir)); err != nil {
+			return err
+		}
+	}
+	os.Remove(deployStateDir(dataDir))
+	os.Remove(filepath.Dir(deployStateDir(dataDir)))
+	return nil
+}
+
+// restoreBackups moves the files in the backup folder of a data directory
+// back to where they were. Files that were put there again in the meantime
+// win, their backups are kept.
+func restoreBackups(dataDir string) error {
+	root := backupDir(dataDir)
+	var files []string
+	err := filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read the backups of %s: %w", dataDir, err)
+	}
+
+	kept := false
+	for _, file := range files {
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		if err := mod.MoveFile(file, filepath.Join(dataDir, rel)); err != nil {
+			fmt.Printf("Keeping the backup %s: %v\n", file, err)
+			kept = true
+		}
+	}
+	if !kept {
+		return os.RemoveAll(root)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bazsalanszky/fusioncore/internal/games"
+	"github.com/bazsalanszky/fusioncore/internal/mod"
+)
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestDeployLooseFiles(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-deploy")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	game, err := games.GetGameByID("skyrimse")
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+
+	dataDir := filepath.Join(tmpDir, "Data")
+	modsDir := filepath.Join(tmpDir, "mods")
+	writeTestFiles(t, dataDir, map[string]string{
+		"Skyrim.esm":           "vanilla",
+		"Textures/vanilla.dds": "vanilla",
+	})
+	// Folders of the game stay even if they are empty
+	if err := os.MkdirAll(filepath.Join(dataDir, "Meshes"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	writeTestFiles(t, modsDir, map[string]string{
+		"A/ModA.esp":                 "a",
+		"A/ModA.bsa":                 "a",
+		"A/textures/a.dds":           "a",
+		"A/textures/shared.dds":      "a",
+		"A/fomod/ModuleConfig.xml":   "installer",
+		"A/SKSE/Plugins/a.dll":       "a",
+		"B/Data/Textures/SHARED.dds": "b",
+		"B/Data/meshes/b.nif":        "b",
+		"B/Data/Skyrim.esm":          "replaced",
+		"B/Data/optional/extra.bsa":  "b",
+	})
+	mods := []*mod.Mod{
+		{Name: "A", Path: filepath.Join(modsDir, "A"), Active: true},
+		{Name: "B", Path: filepath.Join(modsDir, "B"), Active: true},
+	}
+
+	if err := deployMods(mods, game, dataDir, ""); err != nil {
+		t.Fatalf("Failed to deploy: %v", err)
+	}
+
+	expected := map[string]string{
+		"ModA.esp":             "a",
+		"ModA.bsa":             "a",
+		"Textures/a.dds":       "a",
+		"Textures/shared.dds":  "b", // B wins, matched ignoring case
+		"SKSE/Plugins/a.dll":   "a",
+		"Meshes/b.nif":         "b",
+		"Skyrim.esm":           "replaced", // The game's file is backed up
+		"Textures/vanilla.dds": "vanilla",
+	}
+	for name, content := range expected {
+		data, err := os.ReadFile(filepath.Join(dataDir, filepath.FromSlash(name)))
+		if err != nil || string(data) != content {
+			t.Errorf("expected %s to be %q, got %q, %v", name, content, data, err)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(backupDir(dataDir), "Skyrim.esm")); err != nil || string(data) != "vanilla" {
+		t.Errorf("expected the backup of Skyrim.esm, got %q, %v", data, err)
+	}
+	for _, missing := range []string{"textures", "fomod", "Data"} {
+		if _, err := os.Lstat(filepath.Join(dataDir, missing)); !os.IsNotExist(err) {
+			t.Errorf("expected no %s in Data", missing)
+		}
+	}
+
+	// Only archives in the root of Data are loaded
+	archives, err := archiveNames(mods[1].Path, game)
+	if err != nil || len(archives) != 0 {
+		t.Errorf("expected no archives of B, got %v, %v", archives, err)
+	}
+	archives, err = archiveNames(mods[0].Path, game)
+	if err != nil || !reflect.DeepEqual(archives, []string{"ModA.bsa"}) {
+		t.Errorf("expected the archive of A, got %v, %v", archives, err)
+	}
+
+	if err := removeSymlinks(dataDir, modsDir); err != nil {
+		t.Fatalf("Failed to remove symlinks: %v", err)
+	}
+	var left []string
+	filepath.WalkDir(dataDir, func(path string, entry os.DirEntry, err error) error {
+		if err == nil && path != dataDir {
+			rel, _ := filepath.Rel(dataDir, path)
+			left = append(left, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	expectedLeft := []string{"Meshes", "Skyrim.esm", "Textures", "Textures/vanilla.dds"}
+	if !reflect.DeepEqual(left, expectedLeft) {
+		t.Errorf("expected %v to be left, got %v", expectedLeft, left)
+	}
+	if data, err := os.ReadFile(filepath.Join(dataDir, "Skyrim.esm")); err != nil || string(data) != "vanilla" {
+		t.Errorf("expected Skyrim.esm to be restored, got %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, ".fusion-core")); !os.IsNotExist(err) {
+		t.Errorf("expected the backup folder to be removed")
+	}
+}
+
+func TestDeployArchivesOnly(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-deploy")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	game, err := games.GetGameByID("fallout76")
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+
+	dataDir := filepath.Join(tmpDir, "Data")
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	modDir := filepath.Join(tmpDir, "mods", "C")
+	writeTestFiles(t, modDir, map[string]string{
+		"Main/sub/C - Main.ba2": "c",
+		"textures/c.dds":        "c",
+	})
+
+	d := newDeployer(dataDir)
+	if _, err := d.deployMod(&mod.Mod{Name: "C", Path: modDir, Active: true}, game); err != nil {
+		t.Fatalf("Failed to deploy: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dataDir, "C - Main.ba2"))
+	if err != nil || string(data) != "c" {
+		t.Errorf("expected the archive in the root of Data, got %q, %v", data, err)
+	}
+	if _, err := os.Lstat(filepath.Join(dataDir, "textures")); !os.IsNotExist(err) {
+		t.Errorf("expected no loose files to be deployed")
+	}
+}
+
+func TestDeployMyGamesData(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-deploy")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	game, err := games.GetGameByID("starfield")
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+
+	dataDir := filepath.Join(tmpDir, "Data")
+	myGamesData := filepath.Join(tmpDir, "My Games", "Starfield", "Data")
+	for _, dir := range []string{dataDir, myGamesData} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+	modDir := filepath.Join(tmpDir, "mods", "D")
+	writeTestFiles(t, modDir, map[string]string{
+		"D.esm":            "d",
+		"D - Main.ba2":     "d",
+		"textures/d.dds":   "d",
+		"interface/d.swf":  "d",
+		"meshes/sub/d.nif": "d",
+	})
+
+	mods := []*mod.Mod{{Name: "D", Path: modDir, Active: true}}
+	if err := deployMods(mods, game, dataDir, myGamesData); err != nil {
+		t.Fatalf("Failed to deploy: %v", err)
+	}
+
+	for _, name := range []string{"D.esm", "D - Main.ba2", "textures/d.dds", "interface/d.swf", "meshes/sub/d.nif"} {
+		if data, err := os.ReadFile(filepath.Join(dataDir, filepath.FromSlash(name))); err != nil || string(data) != "d" {
+			t.Errorf("expected %s in the install Data, got %q, %v", name, data, err)
+		}
+	}
+	for _, name := range []string{"textures/d.dds", "interface/d.swf", "meshes/sub/d.nif"} {
+		if data, err := os.ReadFile(filepath.Join(myGamesData, filepath.FromSlash(name))); err != nil || string(data) != "d" {
+			t.Errorf("expected %s in My Games Data, got %q, %v", name, data, err)
+		}
+	}
+	// Plugins and archives are only loaded from the install
+	for _, name := range []string{"D.esm", "D - Main.ba2"} {
+		if _, err := os.Lstat(filepath.Join(myGamesData, name)); !os.IsNotExist(err) {
+			t.Errorf("expected no %s in My Games Data", name)
+		}
+	}
+
+	if err := removeSymlinks(myGamesData, filepath.Join(tmpDir, "mods")); err != nil {
+		t.Fatalf("Failed to remove symlinks: %v", err)
+	}
+	if entries, err := os.ReadDir(myGamesData); err != nil || len(entries) != 0 {
+		t.Errorf("expected My Games Data to be empty, got %v, %v", entries, err)
+	}
+}
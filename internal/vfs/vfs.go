@@ -33,69 +33,258 @@ func SyncLinks() error {
 		customPath = cfg.GamePaths[cfg.CurrentGame]
 	}
 
+	// Don't touch the game while Steam is still installing or updating it
+	inst, err := game.FindInstallation(customPath)
+	if err != nil {
+		return fmt.Errorf("failed to find %s installation: %w", game.Name, err)
+	}
+	if !inst.IsReady() {
+		return fmt.Errorf("%s is not fully installed (%s). Wait for Steam to finish before deploying mods", game.Name, inst.Manifest.StateDescription())
+	}
+
 	dataDir, err := game.FindDataDirWithCustomPath(customPath)
 	if err != nil {
 		return fmt.Errorf("failed to find %s data directory: %w", game.Name, err)
 	}
 
-	// First, remove all existing symlinks to avoid dangling links
-	files, err := os.ReadDir(dataDir)
+	// Games like Starfield also load loose files from Data in My Games
+	dataDirs := []string{dataDir}
+	prefixPath, myGamesData := "", ""
+	if game.MyGamesData || len(game.RequiredIni) > 0 || game.PluginsFormat == games.PluginsFormatTimestamp {
+		if prefixPath, err = config.FindGamePrefix(game); err != nil {
+			return fmt.Errorf("failed to find %s prefix: %w", game.Name, err)
+		}
+	}
+	if game.MyGamesData {
+		myGamesData = game.MyGamesDataDir(prefixPath)
+		if err := os.MkdirAll(myGamesData, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", myGamesData, err)
+		}
+		dataDirs = append(dataDirs, myGamesData)
+	}
+
+	modsDir, err := game.GetModsDir()
 	if err != nil {
-		return fmt.Errorf("failed to read data directory: %w", err)
+		return err
 	}
-	for _, file := range files {
-		if file.Type()&os.ModeSymlink != 0 {
-			symlinkPath := filepath.Join(dataDir, file.Name())
-			if err := os.Remove(symlinkPath); err != nil {
-				return fmt.Errorf("failed to remove existing symlink at %s: %w", symlinkPath, err)
-			}
+
+	// First, remove all existing symlinks to avoid dangling links
+	for _, dir := range dataDirs {
+		if err := removeSymlinks(dir, modsDir); err != nil {
+			return err
 		}
 	}
 
-	// Then, create symlinks for all active mods
-	for _, m := range mods {
-		if m.Active {
-			archiveFiles, err := findArchiveFiles(m.Path, game.ArchiveExt)
-			if err != nil {
-				return fmt.Errorf("failed to find %s files in mod %s: %w", game.ArchiveExt, m.Name, err)
-			}
-			for _, ba2File := range archiveFiles {
-				symlinkPath := filepath.Join(dataDir, ba2File)
-				targetPath := filepath.Join(m.Path, ba2File)
-				if err := os.Symlink(targetPath, symlinkPath); err != nil {
-					return fmt.Errorf("failed to create symlink for %s: %w", ba2File, err)
-				}
-				fmt.Printf("Created symlink for %s\n", ba2File)
-			}
+	// Then link the files of the active mods, later mods win conflicts
+	if err := deployMods(mods, game, dataDir, myGamesData); err != nil {
+		return err
+	}
 
-			/*symlinkPath := filepath.Join(dataDir, m.Name)
-			if err := os.Symlink(m.Path, symlinkPath); err != nil {
-				return fmt.Errorf("failed to create symlink for %s: %w", m.Name, err)
-			}
-			fmt.Printf("Created symlink for %s\n", m.Name)*/
+	if err := config.EnsureRequiredIni(prefixPath, game); err != nil {
+		return fmt.Errorf("failed to update %s: %w", game.ConfigFile, err)
+	}
+
+	// Games with timestamp load orders get the order of plugins.txt applied
+	if game.PluginsFormat == games.PluginsFormatTimestamp {
+		plugins, err := config.ReadGamePlugins(prefixPath, game)
+		if err != nil {
+			return err
+		}
+		if err := games.SetPluginTimestamps(dataDir, plugins); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deployMods links the files of the active mods into the data directory,
+// later mods win conflicts. Loose files also go to myGamesData if it is set,
+// as the game loads them from there before the install.
+func deployMods(mods []*mod.Mod, game *games.Game, dataDir, myGamesData string) error {
+	deployers := []*deployer{newDeployer(dataDir)}
+	if myGamesData != "" {
+		loose := newDeployer(myGamesData)
+		loose.looseOnly = true
+		deployers = append(deployers, loose)
+	}
+
+	// The created folders are recorded even if a mod failed, so they
+	// are removed with the rest of the deployment
+	err := deployActive(deployers, mods, game)
+	for _, d := range deployers {
+		if saveErr := d.save(); saveErr != nil && err == nil {
+			err = saveErr
+		}
+		if d.backedUp > 0 {
+			fmt.Printf("Moved %d files of %s that mods replace to %s, they are put back when the mods are removed\n", d.backedUp, d.dataDir, backupDir(d.dataDir))
 		}
 	}
+	if err != nil {
+		return err
+	}
+	if d := deployers[0]; d.replaced > 0 {
+		fmt.Printf("%d files are provided by more than one mod, the later mods win. Run 'conflicts' to list them\n", d.replaced)
+	}
+	return nil
+}
 
+// deployActive deploys the active mods with each of the deployers.
+func deployActive(deployers []*deployer, mods []*mod.Mod, game *games.Game) error {
+	for _, m := range mods {
+		if !m.Active {
+			continue
+		}
+		count, err := deployers[0].deployMod(m, game)
+		if err != nil {
+			return fmt.Errorf("failed to deploy mod %s: %w", m.Name, err)
+		}
+		for _, d := range deployers[1:] {
+			if _, err := d.deployMod(m, game); err != nil {
+				return fmt.Errorf("failed to deploy mod %s to %s: %w", m.Name, d.dataDir, err)
+			}
+		}
+		fmt.Printf("Deployed %d files of %s\n", count, m.Name)
+	}
 	return nil
 }
 
-// findArchiveFiles finds all archive files with the given extension in a directory.
-func findArchiveFiles(dir, ext string) ([]string, error) {
+// findArchiveFiles finds the archive files of a mod that end up in the root
+// of the data directory, relative to the mod directory. Archives anywhere in
+// the mod count for games that only get archives deployed.
+func findArchiveFiles(dir string, game *games.Game) ([]string, error) {
 	var archiveFiles []string
+	if game.DeployMode == games.DeployModeLoose {
+		root := modDataRoot(dir)
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && game.IsArchive(entry.Name()) {
+				rel, err := filepath.Rel(dir, filepath.Join(root, entry.Name()))
+				if err != nil {
+					return nil, err
+				}
+				archiveFiles = append(archiveFiles, rel)
+			}
+		}
+		return archiveFiles, nil
+	}
+
 	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() && filepath.Ext(path) == ext {
-			archiveFiles = append(archiveFiles, info.Name())
+		if !info.IsDir() && game.IsArchive(info.Name()) {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			archiveFiles = append(archiveFiles, rel)
 		}
 		return nil
 	})
 	return archiveFiles, err
 }
 
-// Activate activates a mod.
-func Activate(modName string) error {
+// archiveNames returns the names of the archives of a mod as the game's INI
+// lists them.
+func archiveNames(dir string, game *games.Game) ([]string, error) {
+	archiveFiles, err := findArchiveFiles(dir, game)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(archiveFiles))
+	for i, archive := range archiveFiles {
+		names[i] = filepath.Base(archive)
+	}
+	return names, nil
+}
+
+// ArchivesToRegister drops the archives the game loads by itself because
+// they are named after a plugin in its data directory or one of plugins,
+// the plugins of the mods being deployed, which aren't in Data yet.
+func ArchivesToRegister(game *games.Game, archives, plugins []string) []string {
+	if game.ArchiveStrategy != games.ArchiveStrategyPlugin {
+		return archives
+	}
+	if cfg, err := config.LoadConfig(); err == nil {
+		if dataDir, err := game.FindDataDirWithCustomPath(cfg.GamePaths[game.ID]); err == nil {
+			if dataPlugins, err := games.ListPlugins(dataDir); err == nil {
+				plugins = append(dataPlugins, plugins...)
+			}
+		}
+	}
+
+	var register []string
+	for _, archive := range archives {
+		if game.AutoLoadsArchive(archive, plugins) {
+			fmt.Printf("%s is loaded with its plugin, not adding it to %s\n", archive, game.ConfigFile)
+			continue
+		}
+		register = append(register, archive)
+	}
+	return register
+}
+
+// modPlugins returns the plugins of a mod, which are deployed to the root of
+// the data directory.
+func modPlugins(dir string) []string {
+	plugins, _ := games.ListPlugins(modDataRoot(dir))
+	return plugins
+}
+
+// pluginsToEnable returns the plugins of a mod that go into the plugins file.
+// The game loads its own plugins by itself, even when a mod replaces them.
+func pluginsToEnable(game *games.Game, plugins []string) []string {
+	var enable []string
+	for _, plugin := range plugins {
+		if !game.IsVanillaFile(plugin) {
+			enable = append(enable, plugin)
+		}
+	}
+	return enable
+}
+
+// UpdateLoadOrder sets the archive list in the game's INI to the archives of
+// the active mods, in mod order, keeping the vanilla archives the game needs.
+func UpdateLoadOrder(mods []*mod.Mod, game *games.Game) error {
+	var archives, plugins []string
+	for _, m := range mods {
+		if m.Active {
+			archiveFiles, err := archiveNames(m.Path, game)
+			if err != nil {
+				return err
+			}
+			archives = append(archives, archiveFiles...)
+			plugins = append(plugins, modPlugins(m.Path)...)
+		}
+	}
+	prefixPath, err := config.FindGamePrefix(game)
+	if err != nil {
+		return err
+	}
+	return config.SetGameArchiveList(prefixPath, game, ArchivesToRegister(game, archives, plugins))
+}
+
+// setActive marks a mod as active or inactive in the mod list of a game.
+func setActive(gameID, idOrName string, active bool) (*mod.Mod, error) {
+	var changed *mod.Mod
+	_, err := mod.UpdateMods(gameID, func(mods []*mod.Mod) ([]*mod.Mod, error) {
+		m, err := mod.Find(mods, idOrName)
+		if err != nil {
+			return nil, err
+		}
+		m.Active = active
+		changed = m
+		return mods, nil
+	})
+	return changed, err
+}
+
+// Activate activates a mod, given by its ID or name.
+func Activate(idOrName string) error {
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		return err
@@ -106,37 +295,71 @@ func Activate(modName string) error {
 		return err
 	}
 
-	mods, err := mod.LoadMods(cfg.CurrentGame)
+	m, err := setActive(cfg.CurrentGame, idOrName, true)
+	if err != nil {
+		return err
+	}
+
+	archiveFiles, err := archiveNames(m.Path, game)
+	if err != nil {
+		return err
+	}
+	plugins := modPlugins(m.Path)
+	archiveFiles = ArchivesToRegister(game, archiveFiles, plugins)
+	for _, archiveFile := range archiveFiles {
+		if err := config.AddArchiveToCustomIniWithPrefix(archiveFile); err != nil {
+			return err
+		}
+	}
+	for _, plugin := range pluginsToEnable(game, plugins) {
+		if err := config.AddPluginWithPrefix(plugin); err != nil {
+			return err
+		}
+	}
+
+	return SyncLinks()
+}
+
+// DeployImported registers the archives and plugins of the active mods among
+// mods in the current game's INI and plugins file, like Activate does for one
+// mod, and deploys all mods.
+func DeployImported(mods []*mod.Mod) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	game, err := games.GetGameByID(cfg.CurrentGame)
 	if err != nil {
 		return err
 	}
 
 	for _, m := range mods {
-		if m.Name == modName {
-			m.Active = true
-			if err := mod.SaveMods(mods, cfg.CurrentGame); err != nil {
+		if !m.Active {
+			continue
+		}
+		archiveFiles, err := archiveNames(m.Path, game)
+		if err != nil {
+			return err
+		}
+		plugins := modPlugins(m.Path)
+		for _, archiveFile := range ArchivesToRegister(game, archiveFiles, plugins) {
+			if err := config.AddArchiveToCustomIniWithPrefix(archiveFile); err != nil {
 				return err
 			}
-
-			archiveFiles, err := findArchiveFiles(m.Path, game.ArchiveExt)
-			if err != nil {
+		}
+		// Plugins already listed keep their place and state
+		for _, plugin := range pluginsToEnable(game, plugins) {
+			if err := config.AddPluginWithPrefix(plugin); err != nil {
 				return err
 			}
-			for _, archiveFile := range archiveFiles {
-				if err := config.AddArchiveToCustomIniWithPrefix(archiveFile); err != nil {
-					return err
-				}
-			}
-
-			return SyncLinks()
 		}
 	}
 
-	return fmt.Errorf("mod not found: %s", modName)
+	return SyncLinks()
 }
 
-// Deactivate deactivates a mod.
-func Deactivate(modName string) error {
+// Deactivate deactivates a mod, given by its ID or name.
+func Deactivate(idOrName string) error {
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		return err
@@ -147,31 +370,37 @@ func Deactivate(modName string) error {
 		return err
 	}
 
-	mods, err := mod.LoadMods(cfg.CurrentGame)
+	m, err := setActive(cfg.CurrentGame, idOrName, false)
 	if err != nil {
 		return err
 	}
 
-	for _, m := range mods {
-		if m.Name == modName {
-			m.Active = false
-			if err := mod.SaveMods(mods, cfg.CurrentGame); err != nil {
-				return err
-			}
+	archiveFiles, err := archiveNames(m.Path, game)
+	if err != nil {
+		return err
+	}
+	for _, archiveFile := range archiveFiles {
+		if err := config.RemoveArchiveFromCustomIniWithPrefix(archiveFile); err != nil {
+			return err
+		}
+	}
 
-			archiveFiles, err := findArchiveFiles(m.Path, game.ArchiveExt)
-			if err != nil {
-				return err
-			}
-			for _, archiveFile := range archiveFiles {
-				if err := config.RemoveArchiveFromCustomIniWithPrefix(archiveFile); err != nil {
-					return err
-				}
-			}
+	if err := SyncLinks(); err != nil {
+		return err
+	}
 
-			return SyncLinks()
+	// Plugins another mod still provides stay enabled
+	dataDir, err := game.FindDataDirWithCustomPath(cfg.GamePaths[game.ID])
+	if err != nil {
+		return err
+	}
+	for _, plugin := range pluginsToEnable(game, modPlugins(m.Path)) {
+		if _, err := os.Lstat(filepath.Join(dataDir, plugin)); err == nil {
+			continue
+		}
+		if err := config.RemovePluginWithPrefix(plugin); err != nil {
+			return err
 		}
 	}
-
-	return fmt.Errorf("mod not found: %s", modName)
+	return nil
 }
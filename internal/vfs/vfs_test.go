@@ -0,0 +1,140 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bazsalanszky/fusioncore/internal/config"
+	"github.com/bazsalanszky/fusioncore/internal/games"
+	"github.com/bazsalanszky/fusioncore/internal/mod"
+)
+
+// setupFallout4 configures a Fallout 4 install and prefix in dir and returns
+// the game, its install directory and its prefix.
+func setupFallout4(t *testing.T, dir string) (*games.Game, string, string) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+
+	game, err := games.GetGameByID("fallout4")
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+
+	gameDir := filepath.Join(dir, "Fallout 4")
+	gamePrefix := filepath.Join(dir, "compatdata")
+	writeTestFiles(t, gameDir, map[string]string{"Data/Fallout4.esm": "vanilla"})
+	if err := os.MkdirAll(filepath.Join(gamePrefix, "pfx"), 0755); err != nil {
+		t.Fatalf("Failed to create prefix: %v", err)
+	}
+	err = config.SaveConfig(&config.Config{
+		CurrentGame:     "fallout4",
+		GamePaths:       map[string]string{"fallout4": gameDir},
+		CompatdataPaths: map[string]string{"fallout4": gamePrefix},
+	})
+	if err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	return game, gameDir, gamePrefix
+}
+
+// addTestMod adds a mod with the given files to the mods of the game.
+func addTestMod(t *testing.T, game *games.Game, name string, files map[string]string) {
+	t.Helper()
+	modsDir, err := game.GetModsDir()
+	if err != nil {
+		t.Fatalf("Failed to get mods dir: %v", err)
+	}
+	modDir := filepath.Join(modsDir, name)
+	writeTestFiles(t, modDir, files)
+	if _, err := mod.Add(game.ID, mod.New(name, modDir, "", "", game.ID)); err != nil {
+		t.Fatalf("Failed to add mod: %v", err)
+	}
+}
+
+func TestActivateSkipsArchivesOfModPlugin(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-activate")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	game, gameDir, gamePrefix := setupFallout4(t, tmpDir)
+
+	iniPath, err := config.GetCustomIniPath(gamePrefix, game)
+	if err != nil {
+		t.Fatalf("Failed to get ini path: %v", err)
+	}
+	original := "[Archive]\nbInvalidateOlderFiles=1\n"
+	writeTestFiles(t, filepath.Dir(iniPath), map[string]string{filepath.Base(iniPath): original})
+
+	addTestMod(t, game, "Foo", map[string]string{
+		"Foo.esp":        "plugin",
+		"Foo - Main.ba2": "archive",
+	})
+
+	if err := Activate("Foo"); err != nil {
+		t.Fatalf("Failed to activate: %v", err)
+	}
+
+	// The game loads the archive with Foo.esp, which wasn't deployed yet when
+	// the archives were registered
+	data, err := os.ReadFile(iniPath)
+	if err != nil {
+		t.Fatalf("Failed to read ini: %v", err)
+	}
+	if string(data) != original {
+		t.Errorf("expected the ini to be unchanged, got:\n%s", data)
+	}
+	if _, err := os.Lstat(filepath.Join(gameDir, "Data", "Foo - Main.ba2")); err != nil {
+		t.Errorf("expected the archive to be deployed: %v", err)
+	}
+}
+
+func TestActivateEnablesPlugins(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-activate-plugins")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	game, _, gamePrefix := setupFallout4(t, tmpDir)
+
+	if err := config.WriteGamePlugins(gamePrefix, game, []string{"*Other.esp"}); err != nil {
+		t.Fatalf("Failed to write plugins: %v", err)
+	}
+	addTestMod(t, game, "Foo", map[string]string{
+		"Data/Foo.esp":      "plugin",
+		"Data/Foo.esl":      "light plugin",
+		"Data/Fallout4.esm": "replaced",
+	})
+	addTestMod(t, game, "Bar", map[string]string{"Bar.esp": "plugin"})
+	if err := Activate("Bar"); err != nil {
+		t.Fatalf("Failed to activate: %v", err)
+	}
+
+	if err := Activate("Foo"); err != nil {
+		t.Fatalf("Failed to activate: %v", err)
+	}
+	plugins, err := config.ReadGamePlugins(gamePrefix, game)
+	if err != nil {
+		t.Fatalf("Failed to read plugins: %v", err)
+	}
+	// The game loads Fallout4.esm by itself
+	expected := []string{"*Other.esp", "*Bar.esp", "*Foo.esl", "*Foo.esp"}
+	if !reflect.DeepEqual(plugins, expected) {
+		t.Errorf("expected plugins %v, got %v", expected, plugins)
+	}
+
+	if err := Deactivate("Foo"); err != nil {
+		t.Fatalf("Failed to deactivate: %v", err)
+	}
+	plugins, err = config.ReadGamePlugins(gamePrefix, game)
+	if err != nil {
+		t.Fatalf("Failed to read plugins: %v", err)
+	}
+	expected = []string{"*Other.esp", "*Bar.esp"}
+	if !reflect.DeepEqual(plugins, expected) {
+		t.Errorf("expected plugins %v, got %v", expected, plugins)
+	}
+}
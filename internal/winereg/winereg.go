@@ -0,0 +1,383 @@
+// Package winereg reads and writes the text registry hives Wine keeps in a
+// prefix (system.reg, user.reg and userdef.reg).
+package winereg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Hive is a parsed .reg file. Keys, values and comment lines are kept in the
+// order they were read, so writing an unmodified hive reproduces the file.
+type Hive struct {
+	Header []string // Lines before the first key, e.g. "WINE REGISTRY Version 2"
+	Keys   []*Key
+}
+
+// Key is a registry key with its values.
+type Key struct {
+	Name      string   // Unescaped path relative to the hive root, e.g. Software\Wine
+	Timestamp string   // Modification time written after the key name
+	Meta      []string // Lines starting with '#', like #time= and #class=
+	Values    []*Value
+}
+
+// Value is a single named value. Data holds the text after the '=' exactly
+// as it appears in the file, including continuation lines of hex data.
+type Value struct {
+	Name string // Empty for the default value, written as @
+	Data string
+}
+
+// Parse reads a .reg hive.
+func Parse(r io.Reader) (*Hive, error) {
+	hive := &Hive{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var key *Key
+	var pending *Value // value whose hex data continues on the next line
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+
+		if pending != nil {
+			pending.Data += "\n" + line
+			if !strings.HasSuffix(line, `\`) {
+				pending = nil
+			}
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "["):
+			end := closingBracket(trimmed)
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated key name", lineNo)
+			}
+			key = &Key{
+				Name:      unescape(trimmed[1:end]),
+				Timestamp: strings.TrimSpace(trimmed[end+1:]),
+			}
+			hive.Keys = append(hive.Keys, key)
+		case key == nil:
+			hive.Header = append(hive.Header, line)
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";"):
+			key.Meta = append(key.Meta, trimmed)
+		default:
+			value, err := parseValue(trimmed)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			key.Values = append(key.Values, value)
+			if strings.HasSuffix(value.Data, `\`) {
+				pending = value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Wine puts an empty line before every key, it is written back by Write
+	for len(hive.Header) > 0 && strings.TrimSpace(hive.Header[len(hive.Header)-1]) == "" {
+		hive.Header = hive.Header[:len(hive.Header)-1]
+	}
+	return hive, nil
+}
+
+// ParseFile reads the .reg hive at path. A missing file returns the os error
+// unwrapped, so os.IsNotExist can be used on it.
+func ParseFile(path string) (*Hive, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hive, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return hive, nil
+}
+
+// closingBracket returns the index of the ']' ending a key name, skipping escaped characters.
+func closingBracket(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ']':
+			return i
+		}
+	}
+	return -1
+}
+
+func parseValue(line string) (*Value, error) {
+	if strings.HasPrefix(line, "@=") {
+		return &Value{Data: line[2:]}, nil
+	}
+	if !strings.HasPrefix(line, `"`) {
+		return nil, fmt.Errorf("invalid value line %q", line)
+	}
+	end := closingQuote(line)
+	if end < 0 || end+1 >= len(line) || line[end+1] != '=' {
+		return nil, fmt.Errorf("invalid value line %q", line)
+	}
+	return &Value{Name: unescape(line[1:end]), Data: line[end+2:]}, nil
+}
+
+// closingQuote returns the index of the '"' ending a quoted string that starts at s[0].
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// Key returns the key with the given name, compared case-insensitively like Windows does.
+func (h *Hive) Key(name string) *Key {
+	for _, k := range h.Keys {
+		if strings.EqualFold(k.Name, name) {
+			return k
+		}
+	}
+	return nil
+}
+
+// CreateKey returns the key with the given name, adding it if it doesn't exist.
+// Wine creates missing parent keys itself when it loads the hive.
+func (h *Hive) CreateKey(name string) *Key {
+	if k := h.Key(name); k != nil {
+		return k
+	}
+	k := &Key{Name: name, Timestamp: strconv.FormatInt(time.Now().Unix(), 10)}
+	h.Keys = append(h.Keys, k)
+	return k
+}
+
+// Subkeys returns the keys directly below the key with the given name.
+func (h *Hive) Subkeys(name string) []*Key {
+	parent := strings.ToLower(name) + `\`
+	var subkeys []*Key
+	for _, k := range h.Keys {
+		rest := strings.TrimPrefix(strings.ToLower(k.Name), parent)
+		if rest != strings.ToLower(k.Name) && rest != "" && !strings.Contains(rest, `\`) {
+			subkeys = append(subkeys, k)
+		}
+	}
+	return subkeys
+}
+
+// BaseName returns the last component of the key's path.
+func (k *Key) BaseName() string {
+	return k.Name[strings.LastIndex(k.Name, `\`)+1:]
+}
+
+// Value returns the value with the given name, or nil. Use "" for the default value.
+func (k *Key) Value(name string) *Value {
+	if k == nil {
+		return nil
+	}
+	for _, v := range k.Values {
+		if strings.EqualFold(v.Name, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+// GetString returns a string value of the key.
+func (k *Key) GetString(name string) (string, bool) {
+	v := k.Value(name)
+	if v == nil {
+		return "", false
+	}
+	return v.String()
+}
+
+// SetString sets a REG_SZ value, keeping its position if it already exists.
+func (k *Key) SetString(name, value string) {
+	k.set(name, `"`+escape(value)+`"`)
+}
+
+// SetDword sets a REG_DWORD value, keeping its position if it already exists.
+func (k *Key) SetDword(name string, value uint32) {
+	k.set(name, fmt.Sprintf("dword:%08x", value))
+}
+
+func (k *Key) set(name, data string) {
+	if v := k.Value(name); v != nil {
+		v.Data = data
+		return
+	}
+	k.Values = append(k.Values, &Value{Name: name, Data: data})
+}
+
+// String returns the data of a REG_SZ or REG_EXPAND_SZ value.
+// ok is false for other value types.
+func (v *Value) String() (string, bool) {
+	data := strings.TrimPrefix(v.Data, "str(2):")
+	if len(data) < 2 || data[0] != '"' || data[len(data)-1] != '"' {
+		return "", false
+	}
+	return unescape(data[1 : len(data)-1]), true
+}
+
+// Dword returns the data of a REG_DWORD value.
+func (v *Value) Dword() (uint32, bool) {
+	if !strings.HasPrefix(v.Data, "dword:") {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(v.Data[len("dword:"):], 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint32(n), true
+}
+
+// Write writes the hive in the format Wine uses.
+func (h *Hive) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, line := range h.Header {
+		bw.WriteString(line + "\n")
+	}
+	for _, k := range h.Keys {
+		bw.WriteString("\n[" + escape(k.Name) + "]")
+		if k.Timestamp != "" {
+			bw.WriteString(" " + k.Timestamp)
+		}
+		bw.WriteString("\n")
+		for _, meta := range k.Meta {
+			bw.WriteString(meta + "\n")
+		}
+		for _, v := range k.Values {
+			if v.Name == "" {
+				bw.WriteString("@=" + v.Data + "\n")
+			} else {
+				bw.WriteString(`"` + escape(v.Name) + `"=` + v.Data + "\n")
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteFile replaces the hive at path. The old file is kept as path.bak and
+// the new one is written to a temporary file first, so a failed write never
+// leaves a truncated hive behind. Wine must not be running in the prefix,
+// or it will overwrite the file when it exits.
+func (h *Hive) WriteFile(path string) error {
+	if data, err := os.ReadFile(path); err == nil {
+		if err := os.WriteFile(path+".bak", data, 0644); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", filepath.Base(path), err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := h.Write(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// unescape decodes the escapes Wine uses in key names and strings:
+// \\, \", \n, \r, \t, \0 and \xNNNN for other characters.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case '0':
+			sb.WriteByte(0)
+		case 'x':
+			j := i + 1
+			for j < len(s) && j < i+5 && isHex(s[j]) {
+				j++
+			}
+			n, err := strconv.ParseUint(s[i+1:j], 16, 16)
+			if err != nil {
+				sb.WriteString(`\x`)
+				continue
+			}
+			sb.WriteRune(rune(n))
+			i = j - 1
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
+
+// escape is the inverse of unescape. Characters outside printable ASCII are
+// written as UTF-16 code units, like Wine does.
+func escape(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '"':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case r < 0x20 || r > 0x7e:
+			for _, unit := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&sb, `\x%04x`, unit)
+			}
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
@@ -0,0 +1,103 @@
+package winereg
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const testHive = `WINE REGISTRY Version 2
+;; All keys relative to \\Machine
+
+#arch=win64
+
+[Software\\Microsoft\\Windows\\CurrentVersion\\Uninstall\\{AAAA-BBBB}] 1700000000
+#time=1da0000000000000
+"DisplayName"="Microsoft Visual C++ 2019 Redistributable (x64)"
+"DisplayVersion"="14.29.30133"
+"EstimatedSize"=dword:00004e20
+
+[Software\\Wine\\Fonts] 1700000001
+#time=1da0000000000001
+@="default"
+"Bin"=hex:01,02,03,04,05,06,07,08,09,0a,0b,0c,0d,0e,0f,10,11,12,13,14,15,16,\
+  17,18,19
+"Caf\x00e9"="Quote \" and backslash \\"
+"Path"=str(2):"%SystemRoot%\\Fonts"
+`
+
+func TestParseRoundTrip(t *testing.T) {
+	hive, err := Parse(strings.NewReader(testHive))
+	if err != nil {
+		t.Fatalf("Failed to parse hive: %v", err)
+	}
+
+	if len(hive.Keys) != 2 {
+		t.Fatalf("expected 2 keys, got %d", len(hive.Keys))
+	}
+
+	fonts := hive.Key(`software\wine\fonts`)
+	if fonts == nil {
+		t.Fatalf("expected to find the Fonts key case-insensitively")
+	}
+	if v, ok := fonts.GetString("Café"); !ok || v != `Quote " and backslash \` {
+		t.Errorf("unexpected escaped value %q (%v)", v, ok)
+	}
+	if v, ok := fonts.GetString("Path"); !ok || v != `%SystemRoot%\Fonts` {
+		t.Errorf("unexpected expand string %q (%v)", v, ok)
+	}
+	if v, ok := fonts.GetString(""); !ok || v != "default" {
+		t.Errorf("unexpected default value %q (%v)", v, ok)
+	}
+	if _, ok := fonts.GetString("Bin"); ok {
+		t.Errorf("expected hex data not to be a string")
+	}
+
+	uninstall := hive.Subkeys(`Software\Microsoft\Windows\CurrentVersion\Uninstall`)
+	if len(uninstall) != 1 || uninstall[0].BaseName() != "{AAAA-BBBB}" {
+		t.Fatalf("unexpected Uninstall subkeys: %v", uninstall)
+	}
+	if size, ok := uninstall[0].Value("EstimatedSize").Dword(); !ok || size != 20000 {
+		t.Errorf("unexpected dword %d (%v)", size, ok)
+	}
+
+	var buf bytes.Buffer
+	if err := hive.Write(&buf); err != nil {
+		t.Fatalf("Failed to write hive: %v", err)
+	}
+	if buf.String() != testHive {
+		t.Errorf("hive changed on round trip:\n%s", buf.String())
+	}
+}
+
+func TestSetValues(t *testing.T) {
+	hive, err := Parse(strings.NewReader(testHive))
+	if err != nil {
+		t.Fatalf("Failed to parse hive: %v", err)
+	}
+
+	key := hive.CreateKey(`Software\Bethesda Softworks\Fallout4`)
+	key.SetString("Installed Path", `C:\Games\Fallout 4\`)
+	hive.Key(`Software\Wine\Fonts`).SetDword("Bin", 1)
+
+	var buf bytes.Buffer
+	if err := hive.Write(&buf); err != nil {
+		t.Fatalf("Failed to write hive: %v", err)
+	}
+	reparsed, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("Failed to parse written hive: %v", err)
+	}
+
+	if v, ok := reparsed.Key(`Software\Bethesda Softworks\Fallout4`).GetString("Installed Path"); !ok || v != `C:\Games\Fallout 4\` {
+		t.Errorf("unexpected Installed Path %q (%v)", v, ok)
+	}
+	fonts := reparsed.Key(`Software\Wine\Fonts`)
+	if n, ok := fonts.Value("Bin").Dword(); !ok || n != 1 {
+		t.Errorf("unexpected dword %d (%v)", n, ok)
+	}
+	// Replaced values keep their position
+	if fonts.Values[1].Name != "Bin" {
+		t.Errorf("expected Bin to stay the second value, got %q", fonts.Values[1].Name)
+	}
+}